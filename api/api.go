@@ -1,21 +1,49 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	db "github.com/danglnh07/URLShortener/db/sqlc"
 	"github.com/danglnh07/URLShortener/service"
+	"github.com/danglnh07/URLShortener/store"
 )
 
 // request struct for create shorten URL action
 type createShortenURLRequest struct {
-	URL string `json:"url" validate:"required"`
+	URL         string     `json:"url" validate:"required"`
+	Alias       string     `json:"alias,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxClicks   *int32     `json:"max_clicks,omitempty" validate:"omitempty,gt=0"`
+	FallbackURL string     `json:"fallback_url,omitempty"`
+
+	// HTTP status used to redirect the visitors, the default of the server if not set
+	RedirectType *int32 `json:"redirect_type,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+
+	// Password visitors must enter before being redirected, the URL is public if empty
+	Password string `json:"password,omitempty" validate:"max=72"`
+
+	// Title shown on the preview and interstitial pages
+	Title string `json:"title,omitempty" validate:"max=200"`
+
+	// Allow visitors to see the preview page (/{code}+) of the URL instead of being redirected
+	Preview bool `json:"preview,omitempty"`
+
+	// Seconds the interstitial page counts down before the redirect, the visitors are redirected directly if not set
+	InterstitialSeconds *int32 `json:"interstitial_seconds,omitempty" validate:"omitempty,gt=0,lte=30"`
+
+	// By default, the existing shorten URL with the same original URL and settings is returned.
+	// Set this to true to always create a new, independent shorten URL
+	AllowDuplicate bool `json:"allow_duplicate,omitempty"`
 }
 
 // response struct for create shorten URL action
@@ -27,12 +55,28 @@ type createShortenURLResponse struct {
 //
 // @Summary      Create a shortened URL
 // @Description  Takes an original URL, validates it, and stores it in the database.
+// @Description  An optional alias can be provided to use as the shorten code instead of the ID.
+// @Description  The URL can also expire at a given time (expires_at) or after a number of visits (max_clicks).
+// @Description  redirect_type is the status of the redirect: 301 or 308 are permanent and cached by browsers,
+// @Description  302 or 307 are temporary, so that a change of destination reaches every visitor.
+// @Description  With a password, visitors must enter it in a form before being redirected.
+// @Description  With preview, the page /{code}+ shows the destination instead of redirecting. With
+// @Description  interstitial_seconds, visitors see a page counting down before being redirected.
+// @Description  If the caller already shortened the same URL with the same settings, the existing shorten URL
+// @Description  is returned with status 200, unless allow_duplicate is true.
+// @Description  URLs are validated and stored in their canonical form (lowercase host, punycode, no default port).
+// @Description  URLs pointing to blocked domains, private addresses or this service itself are rejected.
 // @Tags         urls
 // @Accept       json
 // @Produce      json
+// @Security     ApiKeyAuth
 // @Param        request body createShortenURLRequest true "Original URL request"
+// @Success      200 {object} createShortenURLResponse "Shortened URL already exists"
 // @Success      201 {object} createShortenURLResponse "Shortened URL created successfully"
-// @Failure      400 {object} ErrorResp "Invalid input or URL already exists"
+// @Failure      400 {object} ErrorResp "Invalid input"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      409 {object} ErrorResp "Alias already taken"
+// @Failure      422 {object} ValidationErrorResp "URL rejected by the validation pipeline"
 // @Failure      500 {object} ErrorResp "Internal server error"
 // @Router       /api/urls [post]
 func (server *Server) HandleCreateShortenURL(w http.ResponseWriter, r *http.Request) {
@@ -43,17 +87,18 @@ func (server *Server) HandleCreateShortenURL(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := server.validate.Struct(req); err != nil {
-		server.WriteError(w, http.StatusBadRequest, ErrorResp{"url should not be empty"})
+	params, reqErr := server.PrepareCreateURL(r.Context(), req)
+	if reqErr != nil {
+		server.WriteRequestError(w, reqErr)
 		return
 	}
 
-	// Insert URL into database
-	res, err := server.queries.CreateURL(r.Context(), req.URL)
+	// Insert URL into database, or get the existing one
+	res, created, err := server.CreateURL(r.Context(), server.store, params, req.AllowDuplicate)
 	if err != nil {
-		// If URL already exists in database
-		if strings.Contains(err.Error(), "url_original_url_key") {
-			server.WriteError(w, http.StatusBadRequest, ErrorResp{"This URL has been registered"})
+		// If the alias has been taken by another URL
+		if errors.Is(err, errAliasTaken) {
+			server.WriteError(w, http.StatusConflict, ErrorResp{"This alias has been taken"})
 			return
 		}
 
@@ -63,88 +108,445 @@ func (server *Server) HandleCreateShortenURL(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Create response with shorten URL using the database ID
-	shortenURL := service.GenerateShortenURL(server.config, res.ID)
-	server.logger.Info("Create URL shorten successfully", "url", shortenURL)
+	// Create response with shorten URL using the alias or the database ID
+	shortenURL := server.GenerateShortenURL(res.ID, res.Alias, res.Code)
 	resp := createShortenURLResponse{
 		ShortenURL: shortenURL,
 	}
+	if !created {
+		server.WriteJSON(w, http.StatusOK, resp)
+		return
+	}
+	server.logger.Info("Create URL shorten successfully", "url", shortenURL)
 
 	// Write response back to client
 	server.WriteJSON(w, http.StatusCreated, resp)
 }
 
+// Helper method to validate a create shorten URL request and build the database parameters
+func (server *Server) PrepareCreateURL(
+	ctx context.Context, req createShortenURLRequest,
+) (db.CreateURLParams, *requestError) {
+	if err := server.validate.Struct(req); err != nil {
+		return db.CreateURLParams{}, &requestError{
+			Status:  http.StatusBadRequest,
+			Message: server.ValidationMessage(err),
+		}
+	}
+
+	// Validate and normalize the URLs, so that the same URL is always stored the same way
+	originalURL, reqErr := server.NormalizeURLField(ctx, "url", req.URL)
+	if reqErr != nil {
+		return db.CreateURLParams{}, reqErr
+	}
+
+	fallbackURL := ""
+	if req.FallbackURL != "" {
+		if fallbackURL, reqErr = server.NormalizeURLField(ctx, "fallback_url", req.FallbackURL); reqErr != nil {
+			return db.CreateURLParams{}, reqErr
+		}
+	}
+
+	// The expiration time, if provided, must be in the future
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return db.CreateURLParams{}, &requestError{
+			Status:  http.StatusBadRequest,
+			Message: "expires_at must be in the future",
+		}
+	}
+
+	// Validate the custom alias, if provided
+	alias := sql.NullString{String: req.Alias, Valid: req.Alias != ""}
+	if alias.Valid {
+		if reqErr := server.ValidateAlias(ctx, req.Alias); reqErr != nil {
+			return db.CreateURLParams{}, reqErr
+		}
+	}
+
+	// Only the hash of the password is stored
+	passwordHash := sql.NullString{}
+	if req.Password != "" {
+		hash, err := service.HashLinkPassword(req.Password)
+		if err != nil {
+			return db.CreateURLParams{}, &requestError{Status: http.StatusBadRequest, Message: err.Error()}
+		}
+		passwordHash = sql.NullString{String: hash, Valid: true}
+	}
+
+	return db.CreateURLParams{
+		OriginalUrl:         originalURL,
+		Alias:               alias,
+		ExpiresAt:           toNullTime(req.ExpiresAt),
+		MaxClicks:           toNullInt32(req.MaxClicks),
+		FallbackUrl:         sql.NullString{String: fallbackURL, Valid: fallbackURL != ""},
+		OwnerID:             GetPrincipal(ctx).OwnerID(),
+		RedirectType:        toNullInt32(req.RedirectType),
+		PasswordHash:        passwordHash,
+		Title:               sql.NullString{String: req.Title, Valid: req.Title != ""},
+		Preview:             req.Preview,
+		InterstitialSeconds: toNullInt32(req.InterstitialSeconds),
+	}, nil
+}
+
+// Error sentinel when the custom alias has been taken by another URL
+var errAliasTaken = errors.New("alias has been taken")
+
+// Helper method to insert the URL into database. Unless allowDuplicate is true, the existing URL with
+// the same original URL and settings is returned instead, so that re-running the request is idempotent.
+// Also return whether the URL was created
+func (server *Server) CreateURL(
+	ctx context.Context, queries store.Store, params db.CreateURLParams, allowDuplicate bool,
+) (db.Url, bool, error) {
+	if !allowDuplicate {
+		existing, found, err := server.FindDuplicateURL(ctx, queries, params)
+		if err != nil {
+			return db.Url{}, false, fmt.Errorf("failed to look up existing URL: %w", err)
+		}
+		if found {
+			return existing, false, nil
+		}
+	}
+
+	url, err := server.insertURL(ctx, queries, params)
+	if err != nil {
+		if strings.Contains(err.Error(), "url_alias_key") {
+			return db.Url{}, false, errAliasTaken
+		}
+		return db.Url{}, false, err
+	}
+
+	// The code or alias may have been cached as unknown
+	server.InvalidateURL(url)
+	return url, true, nil
+}
+
+// Number of random codes drawn for a new URL before giving up, collisions are very unlikely
+const maxCodeAttempts = 5
+
+// Helper method to insert the URL into database. If the code strategy draws random codes, the URL gets
+// one (even with an alias, so that it keeps an unguessable code if the alias is removed), and a new code
+// is drawn when it has been taken. Each attempt runs in its own nested transaction, since a failed
+// statement aborts the whole transaction in PostgreSQL
+func (server *Server) insertURL(ctx context.Context, queries store.Store, params db.CreateURLParams) (db.Url, error) {
+	code := server.codes.NewCode()
+	if code == "" {
+		return queries.CreateURL(ctx, params)
+	}
+
+	for attempt := 1; ; attempt++ {
+		params.Code = sql.NullString{String: code, Valid: true}
+
+		var url db.Url
+		err := queries.ExecTx(ctx, func(tx store.Store) error {
+			var err error
+			url, err = tx.CreateURL(ctx, params)
+			return err
+		})
+		if err == nil || !strings.Contains(err.Error(), "url_code_key") || attempt == maxCodeAttempts {
+			return url, err
+		}
+		code = server.codes.NewCode()
+	}
+}
+
+// Helper method to find an active URL of the same owner with the same original URL and settings.
+// Password-protected URLs are never duplicates, since their passwords cannot be compared
+func (server *Server) FindDuplicateURL(
+	ctx context.Context, queries db.Querier, params db.CreateURLParams,
+) (db.Url, bool, error) {
+	urls, err := queries.ListURLByOriginal(ctx, db.ListURLByOriginalParams{
+		OriginalUrl: params.OriginalUrl,
+		OwnerID:     params.OwnerID,
+	})
+	if err != nil {
+		return db.Url{}, false, err
+	}
+
+	// Database only stores time up to microsecond
+	expiresAt := params.ExpiresAt.Time.Truncate(time.Microsecond)
+	for _, url := range urls {
+		if url.Alias == params.Alias &&
+			url.MaxClicks == params.MaxClicks &&
+			url.FallbackUrl == params.FallbackUrl &&
+			url.RedirectType == params.RedirectType &&
+			url.Title == params.Title &&
+			url.Preview == params.Preview &&
+			url.InterstitialSeconds == params.InterstitialSeconds &&
+			!url.PasswordHash.Valid && !params.PasswordHash.Valid &&
+			url.ExpiresAt.Valid == params.ExpiresAt.Valid &&
+			url.ExpiresAt.Time.Truncate(time.Microsecond).Equal(expiresAt) {
+			return url, true, nil
+		}
+	}
+
+	return db.Url{}, false, nil
+}
+
+// Error sentinel when the URL cannot be visited anymore
+var errURLGone = errors.New("url is gone")
+
+// Response struct when the URL has expired
+type goneResp struct {
+	Message     string `json:"error"`
+	FallbackURL string `json:"fallback_url,omitempty"`
+}
+
 // HandleRedirect godoc
 // @Summary      Redirect to original URL
 // @Description  Redirects a visitor from the shortened URL code to the original URL and records the visit.
+// @Description  The code is resolved as a custom alias first, then as a random code, then as an ID encoded
+// @Description  by the code strategy of the server (sequential or obfuscated Base62).
+// @Description  The redirect status is the redirect type of the URL, or the default of the server. Permanent
+// @Description  redirects can be cached by browsers for a day, temporary redirects are never cached.
+// @Description  Password-protected URLs answer with a password form (see POST /{code}) until the visitor
+// @Description  has entered the password. URLs with an interstitial page answer with a page counting down
+// @Description  before redirecting.
 // @Tags         urls
 // @Accept       json
 // @Produce      json
-// @Param        code path string true "Shortened URL code"
-// @Success      301 {string} string "Redirected successfully"
+// @Param        code path string true "Shortened URL code or alias"
+// @Success      200 {string} string "HTML password form of a password-protected URL, or interstitial page"
+// @Success      301 {string} string "Redirected successfully, the status is the redirect type of the URL"
+// @Success      302 {string} string "Redirected successfully"
+// @Success      307 {string} string "Redirected successfully"
+// @Success      308 {string} string "Redirected successfully"
 // @Failure      400 {object} ErrorResp "Invalid code or URL not found"
+// @Failure      410 {object} goneResp "URL has been deleted, has expired or reached its maximum number of visits"
 // @Failure      500 {object} ErrorResp "Internal server error"
 // @Router       /{code} [get]
 func (server *Server) HandleRedirect(w http.ResponseWriter, r *http.Request) {
-	// Get and decode the ID
-	id := service.DecodeBase62(r.PathValue("code"))
+	// The QR codes /{code}.png and /{code}.svg and the preview page /{code}+ share this route, a wildcard
+	// must be a whole path segment
+	if isQRCodePath(r.PathValue("code")) {
+		server.HandlePublicQRCode(w, r)
+		return
+	}
+	if isPreviewPath(r.PathValue("code")) {
+		server.HandlePreview(w, r)
+		return
+	}
 
-	// Get the original URL in the database
-	url, err := server.queries.GetURL(r.Context(), id)
+	url, ok := server.getRedirectURL(w, r)
+	if !ok {
+		return
+	}
+
+	// Visitors of a password-protected URL must enter the password first
+	if url.PasswordHash.Valid && !server.HasLinkAccess(r, url) {
+		server.WritePasswordForm(w, http.StatusOK, "")
+		return
+	}
+
+	// Get the visitor information. Bot visits are not recorded if disabled, and never consume the
+	// click budget
+	visitor := server.newVisitorParams(r, url.ID)
+	server.logger.Info("Visitor info", "IP", visitor.Ip, "referrer", visitor.Referrer, "bot", visitor.IsBot)
+	record := !visitor.IsBot || server.config.RecordBotVisits
+
+	// If the URL has a click budget, check and record the visitor in one transaction, so that
+	// concurrent visitors cannot exceed the budget. This cannot be done asynchronously
+	if url.MaxClicks.Valid {
+		err := server.store.ExecTx(r.Context(), func(tx store.Store) error {
+			total, err := tx.CountVisitorForUpdate(r.Context(), url.ID)
+			if err != nil {
+				return err
+			}
+			if total >= int64(url.MaxClicks.Int32) {
+				return errURLGone
+			}
+			if !record {
+				return nil
+			}
+
+			_, err = tx.CreateVisitor(r.Context(), visitor)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, errURLGone) {
+				server.WriteGone(w, url, "This URL has reached its maximum number of visits")
+				return
+			}
+
+			// The click budget cannot be enforced without recording the visitor
+			server.logger.Error("GET /{code}: failed to record the visitor", "error", err)
+			server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+			return
+		}
+	} else if record && server.recorder != nil {
+		// Record the visitor in the background, so that a slow database does not slow down the redirect
+		server.recorder.Record(visitor)
+	} else if record {
+		// Record the visitor
+		_, err := server.store.CreateVisitor(r.Context(), visitor)
+		if err != nil {
+			server.logger.Error("GET /{code}: failed to record the visitor", "error", err)
+			// Should NOT return an error here
+		}
+	}
+
+	// Let the visitors see where they are going before the redirect
+	if url.InterstitialSeconds.Valid {
+		server.WriteInterstitial(w, url)
+		return
+	}
+
+	// Redirect to the original URL
+	status := server.RedirectType(url)
+	w.Header().Set("Cache-Control", redirectCacheControl(url, status))
+	http.Redirect(w, r, url.OriginalUrl, status)
+}
+
+// Helper method to get the URL of the code for redirecting, and check that it can still be visited. Write
+// the error response and return false if the URL does not exist, has been deleted or has expired
+func (server *Server) getRedirectURL(w http.ResponseWriter, r *http.Request) (db.Url, bool) {
+	// Get the original URL from the cache or the database, using the alias or the decoded ID
+	url, err := server.GetCachedURLByCode(r.Context(), r.PathValue("code"))
 	if err != nil {
 		// If the ID is invalid (not match any record)
 		if errors.Is(err, sql.ErrNoRows) {
 			server.WriteError(w, http.StatusBadRequest, ErrorResp{"This URL didn't existed"})
-			return
+			return url, false
 		}
 
 		// Other database errors
-		server.logger.Error("GET /{code}: failed to get original URL", "error", err)
+		server.logger.Error(fmt.Sprintf("%s /{code}: failed to get original URL", r.Method), "error", err)
 		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
-		return
+		return url, false
 	}
 
-	// Get the visitor IP address
-	ip := ""
-	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
-		ip = strings.Split(fwd, ",")[0]
-	} else {
-		ip, _, _ = net.SplitHostPort(r.RemoteAddr)
+	// Check if the URL has been deleted or has expired
+	if url.TimeDeleted.Valid {
+		server.WriteGone(w, url, "This URL has been deleted")
+		return url, false
+	}
+	if url.ExpiresAt.Valid && !time.Now().Before(url.ExpiresAt.Time) {
+		server.WriteGone(w, url, "This URL has expired")
+		return url, false
 	}
-	server.logger.Info("Visitor info", "IP", ip)
 
-	// Record the visitor
-	_, err = server.queries.CreateVisitor(r.Context(), db.CreateVisitorParams{
-		Ip:    ip,
-		UrlID: id,
-	})
+	return url, true
+}
+
+// Maximum time browsers can cache a permanent redirect, so that a destination changed anyway still
+// reaches the repeat visitors eventually
+const permanentRedirectMaxAge = 24 * time.Hour
+
+// Helper method to get the redirect status of an URL: its own redirect type, or the default of the server
+func (server *Server) RedirectType(url db.Url) int {
+	if url.RedirectType.Valid {
+		return int(url.RedirectType.Int32)
+	}
+	if service.IsRedirectType(server.config.DefaultRedirectType) {
+		return server.config.DefaultRedirectType
+	}
+	return http.StatusMovedPermanently
+}
+
+// Helper function to get the Cache-Control header of a redirect. Temporary redirects must not be cached,
+// so that every visit reaches the server, and neither do the redirects of password-protected URLs, so
+// that the password is asked again once the access expires. Permanent redirects are cached, but not
+// after the URL expires
+func redirectCacheControl(url db.Url, status int) string {
+	if status == http.StatusFound || status == http.StatusTemporaryRedirect || url.PasswordHash.Valid {
+		return "private, no-store"
+	}
+
+	maxAge := permanentRedirectMaxAge
+	if url.ExpiresAt.Valid {
+		maxAge = min(maxAge, time.Until(url.ExpiresAt.Time))
+	}
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
+// Helper method to get the IP address of the client. The first X-Forwarded-For address is only used if
+// the header is trusted, since any client can set it
+func (server *Server) clientIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" && server.config.TrustForwardedFor {
+		return strings.TrimSpace(strings.Split(fwd, ",")[0])
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		server.logger.Error("GET /{code}: failed to record the visitor", "error", err)
-		// Should NOT return an error here
+		return r.RemoteAddr
 	}
+	return ip
+}
 
-	// Redirect to the original URL
-	http.Redirect(w, r, url, http.StatusMovedPermanently)
+// Maximum length of the request metadata stored for each visitor, longer values are truncated
+const maxVisitorFieldLength = 1024
+
+// Helper method to build the visitor record from the request, with the location of the IP address
+func (server *Server) newVisitorParams(r *http.Request, urlID int64) db.CreateVisitorParams {
+	ip := server.clientIP(r)
+
+	// Helper to truncate the value without breaking a multi-byte character
+	truncate := func(value string) string {
+		if len(value) <= maxVisitorFieldLength {
+			return value
+		}
+		return strings.ToValidUTF8(value[:maxVisitorFieldLength], "")
+	}
+
+	location := server.geoip.Lookup(ip)
+	agent := service.ParseUserAgent(r.UserAgent())
+	return db.CreateVisitorParams{
+		Ip:             ip,
+		UrlID:          urlID,
+		TimeVisited:    time.Now(),
+		Referrer:       truncate(r.Referer()),
+		UserAgent:      truncate(r.UserAgent()),
+		AcceptLanguage: truncate(r.Header.Get("Accept-Language")),
+		Host:           truncate(strings.ToLower(r.Host)),
+		QueryString:    truncate(r.URL.RawQuery),
+		Country:        location.Country,
+		Region:         truncate(location.Region),
+		City:           truncate(location.City),
+		Asn:            int64(location.ASN),
+		AsOrg:          truncate(location.ASOrg),
+		Browser:        agent.Browser,
+		BrowserVersion: agent.BrowserVersion,
+		Os:             agent.OS,
+		Device:         agent.Device,
+		IsBot:          server.bots.IsBot(r, agent),
+	}
+}
+
+// Helper method to write the response when the URL has been deleted, has expired or reached
+// its click budget
+func (server *Server) WriteGone(w http.ResponseWriter, url db.Url, message string) {
+	server.WriteJSON(w, http.StatusGone, goneResp{
+		Message:     message,
+		FallbackURL: url.FallbackUrl.String,
+	})
 }
 
 // Response struct for listing URLs
 type listURLResponse struct {
-	OriginalURL  string    `json:"original"`
-	ShortenURL   string    `json:"shorten"`
-	TotalVisitor int64     `json:"total_visitor"`
-	CreatedAt    time.Time `json:"created_at"`
+	OriginalURL  string     `json:"original"`
+	ShortenURL   string     `json:"shorten"`
+	TotalVisitor int64      `json:"total_visitor"`
+	HumanVisitor int64      `json:"human_visitor"` // Visits not flagged as bot
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	MaxClicks    *int32     `json:"max_clicks,omitempty"`
+	RedirectType *int32     `json:"redirect_type,omitempty"` // Not set if the URL uses the server default
+	Protected    bool       `json:"password_protected"`
+	Title        string     `json:"title,omitempty"`
+	Preview      bool       `json:"preview"`
+	Interstitial *int32     `json:"interstitial_seconds,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
 }
 
 // HandleListURL godoc
 // @Summary      List registered URLs
-// @Description  Retrieves a paginated list of all shortened URLs in the system.
+// @Description  Retrieves a paginated list of the caller's shortened URLs (admin sees all URLs in the system).
 // @Tags         urls
 // @Accept       json
 // @Produce      json
+// @Security     ApiKeyAuth
 // @Param        page_size  query int true  "Number of items per page" minimum(1) maximum(100)
 // @Param        page_index query int true  "Page index (starting from 1)" minimum(1)
 // @Success      200 {array} listURLResponse "List of shortened URLs"
 // @Failure      400 {object} ErrorResp "Invalid pagination parameters"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
 // @Failure      500 {object} ErrorResp "Internal server error"
 // @Router       /api/urls [get]
 func (server *Server) HandleListURL(w http.ResponseWriter, r *http.Request) {
@@ -156,9 +558,10 @@ func (server *Server) HandleListURL(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the list
-	urls, err := server.queries.ListURL(r.Context(), db.ListURLParams{
-		Offset: (pageIndex - 1) * pageSize,
-		Limit:  pageSize,
+	urls, err := server.store.ListURL(r.Context(), db.ListURLParams{
+		OwnerID: GetPrincipal(r.Context()).OwnerFilter(),
+		Offset:  (pageIndex - 1) * pageSize,
+		Limit:   pageSize,
 	})
 	if err != nil {
 		server.logger.Error("GET /api/urls?page_size=...&page_index=...: failed to get list of URLS",
@@ -172,8 +575,16 @@ func (server *Server) HandleListURL(w http.ResponseWriter, r *http.Request) {
 	for i, url := range urls {
 		resps[i] = listURLResponse{
 			OriginalURL:  url.OriginalUrl,
-			ShortenURL:   service.GenerateShortenURL(server.config, url.ID),
+			ShortenURL:   server.GenerateShortenURL(url.ID, url.Alias, url.Code),
 			TotalVisitor: url.TotalVisitors,
+			HumanVisitor: url.HumanVisitors,
+			ExpiresAt:    fromNullTime(url.ExpiresAt),
+			MaxClicks:    fromNullInt32(url.MaxClicks),
+			RedirectType: fromNullInt32(url.RedirectType),
+			Protected:    url.PasswordHash.Valid,
+			Title:        url.Title.String,
+			Preview:      url.Preview,
+			Interstitial: fromNullInt32(url.InterstitialSeconds),
 			CreatedAt:    url.TimeCreated,
 		}
 	}
@@ -185,30 +596,110 @@ func (server *Server) HandleListURL(w http.ResponseWriter, r *http.Request) {
 
 // Response struct for list visitor for each URL action
 type listVisitorResponse struct {
-	Ip          string    `json:"ip"`
-	OriginalURL string    `json:"original"`
-	ShortenURL  string    `json:"shorten"`
-	TimeVisited time.Time `json:"time_visited"`
+	Ip             string    `json:"ip"`
+	OriginalURL    string    `json:"original"`
+	ShortenURL     string    `json:"shorten"`
+	TimeVisited    time.Time `json:"time_visited"`
+	Referrer       string    `json:"referrer"`
+	UserAgent      string    `json:"user_agent"`
+	AcceptLanguage string    `json:"accept_language"`
+	Host           string    `json:"host"`
+	QueryString    string    `json:"query_string"`
+	Country        string    `json:"country"`
+	Region         string    `json:"region"`
+	City           string    `json:"city"`
+	ASN            int64     `json:"asn"`
+	ASOrg          string    `json:"as_org"`
+	Browser        string    `json:"browser"`
+	BrowserVersion string    `json:"browser_version"`
+	OS             string    `json:"os"`
+	Device         string    `json:"device"`
+	IsBot          bool      `json:"is_bot"`
+}
+
+// Helper function to extract the visitor filters from query parameters. Text filters match any visitor
+// whose field contains the value, case-insensitively
+func extractVisitorFilters(r *http.Request, params *db.ListVisitorParams) error {
+	query := r.URL.Query()
+
+	// Helper to build a LIKE pattern matching the value anywhere, escaping the LIKE wildcards
+	contains := func(name string) sql.NullString {
+		value := query.Get(name)
+		if value == "" {
+			return sql.NullString{}
+		}
+		value = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+		return sql.NullString{String: "%" + value + "%", Valid: true}
+	}
+	params.Referrer = contains("referrer")
+	params.UserAgent = contains("user_agent")
+	params.AcceptLanguage = contains("accept_language")
+	params.Host = contains("host")
+	params.QueryString = contains("query_string")
+	for _, filter := range []struct {
+		name  string
+		value *sql.NullString
+	}{{"country", &params.Country}, {"browser", &params.Browser}, {"os", &params.Os}, {"device", &params.Device}} {
+		if value := query.Get(filter.name); value != "" {
+			*filter.value = sql.NullString{String: value, Valid: true}
+		}
+	}
+
+	if value := query.Get("bot"); value != "" {
+		isBot, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for bot, must be true or false")
+		}
+		params.IsBot = sql.NullBool{Bool: isBot, Valid: true}
+	}
+
+	for _, filter := range []struct {
+		name  string
+		value *sql.NullTime
+	}{{"from", &params.FromTime}, {"to", &params.ToTime}} {
+		if value := query.Get(filter.name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s, must be a RFC 3339 date time", filter.name)
+			}
+			*filter.value = sql.NullTime{Time: t, Valid: true}
+		}
+	}
+
+	return nil
 }
 
 // HandleListVisitor godoc
 // @Summary      List visitors for a shortened URL
-// @Description  Retrieves a paginated list of visitors who accessed the given shortened URL.
+// @Description  Retrieves a paginated list of visitors who accessed the given shortened URL, oldest first.
+// @Description  Visitors can be filtered by request metadata (case-insensitive, matching any part of the value)
+// @Description  and by visit time.
 // @Tags         visitors
 // @Accept       json
 // @Produce      json
-// @Param        id          path  string true  "Shortened URL ID (base62 code)"
-// @Param        page_size   query int    true  "Number of items per page" minimum(1) maximum(100)
-// @Param        page_index  query int    true  "Page index (starting from 1)" minimum(1)
+// @Security     ApiKeyAuth
+// @Param        id              path  string true  "Shortened URL ID (base62 code or alias)"
+// @Param        page_size       query int    true  "Number of items per page" minimum(1) maximum(100)
+// @Param        page_index      query int    true  "Page index (starting from 1)" minimum(1)
+// @Param        referrer        query string false "Only visitors whose Referer header contains this value"
+// @Param        user_agent      query string false "Only visitors whose User-Agent header contains this value"
+// @Param        accept_language query string false "Only visitors whose Accept-Language header contains this value"
+// @Param        host            query string false "Only visitors who requested a host containing this value"
+// @Param        query_string    query string false "Only visitors whose query string contains this value"
+// @Param        country         query string false "Only visitors from this country (ISO 3166-1 alpha-2 code)"
+// @Param        browser         query string false "Only visitors using this browser family, e.g. Chrome"
+// @Param        os              query string false "Only visitors using this OS family, e.g. Android"
+// @Param        device          query string false "Only visitors using this device type" Enums(desktop, mobile, tablet, bot)
+// @Param        bot             query bool   false "Only bot visits (true) or human visits (false)"
+// @Param        from            query string false "Only visits at or after this time (RFC 3339)" format(date-time)
+// @Param        to              query string false "Only visits before this time (RFC 3339)" format(date-time)
 // @Success      200 {array} listVisitorResponse "List of visitors"
-// @Failure      400 {object} ErrorResp "Invalid pagination parameters"
+// @Failure      400 {object} ErrorResp "Invalid pagination parameters or filters"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
 // @Failure      404 {object} ErrorResp "URL ID not found"
 // @Failure      500 {object} ErrorResp "Internal server error"
 // @Router       /api/urls/{id}/visitors [get]
 func (server *Server) HandleListVisitor(w http.ResponseWriter, r *http.Request) {
-	// Get URL ID from path parameter
-	id := service.DecodeBase62(r.PathValue("id"))
-
 	// Get the page_size and page_index parameter
 	pageSize, pageIndex, err := server.ExtractPageParams(r)
 	if err != nil {
@@ -216,23 +707,28 @@ func (server *Server) HandleListVisitor(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Get URL from path parameter, which can be either the Base62 code or the alias. Only the owner
+	// (or admin) can see the visitors
+	url, ok := server.getVisibleURL(w, r)
+	if !ok {
+		return
+	}
+
 	// Get the list of visitor who had visit to this URL
-	visitors, err := server.queries.ListVisitor(r.Context(), db.ListVisitorParams{
-		UrlID:  id,
+	params := db.ListVisitorParams{
+		UrlID:  url.ID,
 		Offset: (pageIndex - 1) * pageSize,
 		Limit:  pageSize,
-	})
+	}
+	if err := extractVisitorFilters(r, &params); err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+		return
+	}
 
+	visitors, err := server.store.ListVisitor(r.Context(), params)
 	if err != nil {
-		// If ID not match any record
-		if errors.Is(err, sql.ErrNoRows) {
-			server.WriteError(w, http.StatusNotFound, ErrorResp{"This URL ID does not match any record"})
-			return
-		}
-
-		// If other database errors
 		server.logger.Error("GET /api/urls/{id}/visitors: failed to get the list of visitor for this url",
-			"url_id", id, "error", err)
+			"url_id", url.ID, "error", err)
 		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
 		return
 	}
@@ -241,10 +737,25 @@ func (server *Server) HandleListVisitor(w http.ResponseWriter, r *http.Request)
 	resps := make([]listVisitorResponse, len(visitors))
 	for i, visitor := range visitors {
 		resps[i] = listVisitorResponse{
-			Ip:          visitor.Ip,
-			OriginalURL: visitor.OriginalUrl,
-			ShortenURL:  service.GenerateShortenURL(server.config, visitor.UrlID),
-			TimeVisited: visitor.TimeVisited,
+			Ip:             visitor.Ip,
+			OriginalURL:    visitor.OriginalUrl,
+			ShortenURL:     server.GenerateShortenURL(visitor.UrlID, visitor.Alias, visitor.Code),
+			TimeVisited:    visitor.TimeVisited,
+			Referrer:       visitor.Referrer,
+			UserAgent:      visitor.UserAgent,
+			AcceptLanguage: visitor.AcceptLanguage,
+			Host:           visitor.Host,
+			QueryString:    visitor.QueryString,
+			Country:        visitor.Country,
+			Region:         visitor.Region,
+			City:           visitor.City,
+			ASN:            visitor.Asn,
+			ASOrg:          visitor.AsOrg,
+			Browser:        visitor.Browser,
+			BrowserVersion: visitor.BrowserVersion,
+			OS:             visitor.Os,
+			Device:         visitor.Device,
+			IsBot:          visitor.IsBot,
 		}
 	}
 
@@ -258,15 +769,17 @@ type countURLResp struct {
 
 // HandleCountURL godoc
 // @Summary      Get total URLs
-// @Description  Returns the total number of shortened URLs stored in the system (useful for pagination).
+// @Description  Returns the total number of the caller's shortened URLs (admin counts all URLs, useful for pagination).
 // @Tags         urls
 // @Accept       json
 // @Produce      json
+// @Security     ApiKeyAuth
 // @Success      200 {object} countURLResp "Total number of URLs"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
 // @Failure      500 {object} ErrorResp "Internal server error"
 // @Router       /api/urls/count [get]
 func (server *Server) HandleCountURL(w http.ResponseWriter, r *http.Request) {
-	count, err := server.queries.CountURL(r.Context())
+	count, err := server.store.CountURL(r.Context(), GetPrincipal(r.Context()).OwnerFilter())
 	if err != nil {
 		server.logger.Error("GET /api/urls/count: failed to get the total of the URLs in database")
 		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
@@ -274,3 +787,305 @@ func (server *Server) HandleCountURL(w http.ResponseWriter, r *http.Request) {
 	}
 	server.WriteJSON(w, http.StatusOK, countURLResp{count})
 }
+
+// Request struct for update shorten URL action. Absent fields are kept unchanged, null fields
+// are cleared (except url, which cannot be cleared)
+type updateShortenURLRequest struct {
+	URL         Optional[string]    `json:"url" swaggertype:"string"`
+	Alias       Optional[string]    `json:"alias" swaggertype:"string"`
+	ExpiresAt   Optional[time.Time] `json:"expires_at" swaggertype:"string" format:"date-time"`
+	MaxClicks   Optional[int32]     `json:"max_clicks" swaggertype:"integer"`
+	FallbackURL Optional[string]    `json:"fallback_url" swaggertype:"string"`
+
+	// Null to use the default of the server
+	RedirectType Optional[int32] `json:"redirect_type" swaggertype:"integer" enums:"301,302,307,308"`
+
+	// Null or empty to remove the password. Changing the password revokes the access of the visitors
+	Password Optional[string] `json:"password" swaggertype:"string"`
+
+	// Null or empty to remove the title
+	Title Optional[string] `json:"title" swaggertype:"string"`
+
+	// Null to disable the preview page
+	Preview Optional[bool] `json:"preview" swaggertype:"boolean"`
+
+	// Null to redirect the visitors directly
+	InterstitialSeconds Optional[int32] `json:"interstitial_seconds" swaggertype:"integer" maximum:"30"`
+}
+
+// Response struct for a single URL
+type urlResponse struct {
+	OriginalURL  string     `json:"original"`
+	ShortenURL   string     `json:"shorten"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	MaxClicks    *int32     `json:"max_clicks,omitempty"`
+	FallbackURL  string     `json:"fallback_url,omitempty"`
+	RedirectType *int32     `json:"redirect_type,omitempty"` // Not set if the URL uses the server default
+	Protected    bool       `json:"password_protected"`
+	Title        string     `json:"title,omitempty"`
+	Preview      bool       `json:"preview"`
+	Interstitial *int32     `json:"interstitial_seconds,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Helper method to get the URL from the path parameter and check if the caller can see it, including
+// deleted URLs. Write the error response and return false if the URL cannot be seen
+func (server *Server) getVisibleURL(w http.ResponseWriter, r *http.Request) (db.Url, bool) {
+	url, err := server.GetURLByCode(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteError(w, http.StatusNotFound, ErrorResp{"This URL ID does not match any record"})
+			return url, false
+		}
+
+		server.logger.Error(fmt.Sprintf("%s %s: failed to get the URL", r.Method, r.URL.Path), "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return url, false
+	}
+
+	// Answer as if the URL does not exist, to avoid leaking the existence of other users' URLs
+	if !GetPrincipal(r.Context()).CanAccess(url.OwnerID) {
+		server.WriteError(w, http.StatusNotFound, ErrorResp{"This URL ID does not match any record"})
+		return url, false
+	}
+
+	return url, true
+}
+
+// Helper method to get the URL from the path parameter and check if the caller can manage it.
+// Write the error response and return false if the URL cannot be managed
+func (server *Server) getManagedURL(w http.ResponseWriter, r *http.Request) (db.Url, bool) {
+	url, ok := server.getVisibleURL(w, r)
+	if !ok {
+		return url, false
+	}
+
+	// Deleted URLs are treated as not existing
+	if url.TimeDeleted.Valid {
+		server.WriteError(w, http.StatusNotFound, ErrorResp{"This URL ID does not match any record"})
+		return url, false
+	}
+
+	return url, true
+}
+
+// HandleUpdateShortenURL godoc
+// @Summary      Update a shortened URL
+// @Description  Changes the destination and settings of a shortened URL. Absent fields are kept unchanged,
+// @Description  null fields are cleared. The url field cannot be cleared.
+// @Tags         urls
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id      path string                  true "Shortened URL ID (base62 code or alias)"
+// @Param        request body updateShortenURLRequest true "Fields to update"
+// @Success      200 {object} urlResponse "Shortened URL updated successfully"
+// @Failure      400 {object} ErrorResp "Invalid input"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      404 {object} ErrorResp "URL ID not found"
+// @Failure      409 {object} ErrorResp "Alias already taken"
+// @Failure      422 {object} ValidationErrorResp "URL rejected by the validation pipeline"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/urls/{id} [patch]
+func (server *Server) HandleUpdateShortenURL(w http.ResponseWriter, r *http.Request) {
+	// Parse JSON request
+	var req updateShortenURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{"Invalid JSON body"})
+		return
+	}
+
+	// Get the current URL
+	url, ok := server.getManagedURL(w, r)
+	if !ok {
+		return
+	}
+
+	// Apply the changes on top of the current values
+	params := db.UpdateURLParams{
+		ID:                  url.ID,
+		OriginalUrl:         url.OriginalUrl,
+		Alias:               url.Alias,
+		ExpiresAt:           url.ExpiresAt,
+		MaxClicks:           url.MaxClicks,
+		FallbackUrl:         url.FallbackUrl,
+		RedirectType:        url.RedirectType,
+		PasswordHash:        url.PasswordHash,
+		Title:               url.Title,
+		Preview:             url.Preview,
+		InterstitialSeconds: url.InterstitialSeconds,
+	}
+
+	if req.URL.Set {
+		if req.URL.Null || req.URL.Value == "" {
+			server.WriteError(w, http.StatusBadRequest, ErrorResp{"url should not be empty"})
+			return
+		}
+		if params.OriginalUrl, ok = server.ValidateURLField(r.Context(), w, "url", req.URL.Value); !ok {
+			return
+		}
+	}
+
+	if req.Alias.Set {
+		params.Alias = sql.NullString{
+			String: req.Alias.Value,
+			Valid:  req.Alias.HasValue() && req.Alias.Value != "",
+		}
+		// An unchanged alias is kept as is
+		if params.Alias.Valid && params.Alias != url.Alias {
+			if reqErr := server.ValidateAlias(r.Context(), req.Alias.Value); reqErr != nil {
+				server.WriteRequestError(w, reqErr)
+				return
+			}
+		}
+	}
+
+	if req.ExpiresAt.Set {
+		params.ExpiresAt = sql.NullTime{Time: req.ExpiresAt.Value, Valid: req.ExpiresAt.HasValue()}
+		if params.ExpiresAt.Valid && !params.ExpiresAt.Time.After(time.Now()) {
+			server.WriteError(w, http.StatusBadRequest, ErrorResp{"expires_at must be in the future"})
+			return
+		}
+	}
+
+	if req.MaxClicks.Set {
+		params.MaxClicks = sql.NullInt32{Int32: req.MaxClicks.Value, Valid: req.MaxClicks.HasValue()}
+		if params.MaxClicks.Valid && params.MaxClicks.Int32 <= 0 {
+			server.WriteError(w, http.StatusBadRequest, ErrorResp{"max_clicks must be greater than 0"})
+			return
+		}
+	}
+
+	if req.FallbackURL.Set {
+		params.FallbackUrl = sql.NullString{
+			String: req.FallbackURL.Value,
+			Valid:  req.FallbackURL.HasValue() && req.FallbackURL.Value != "",
+		}
+		if params.FallbackUrl.Valid {
+			fallbackURL := params.FallbackUrl.String
+			if params.FallbackUrl.String, ok = server.ValidateURLField(r.Context(), w, "fallback_url", fallbackURL); !ok {
+				return
+			}
+		}
+	}
+
+	if req.RedirectType.Set {
+		params.RedirectType = sql.NullInt32{Int32: req.RedirectType.Value, Valid: req.RedirectType.HasValue()}
+		if params.RedirectType.Valid && !service.IsRedirectType(int(params.RedirectType.Int32)) {
+			server.WriteError(w, http.StatusBadRequest, ErrorResp{"redirect_type must be one of 301 302 307 308"})
+			return
+		}
+	}
+
+	if req.Password.Set {
+		params.PasswordHash = sql.NullString{}
+		if req.Password.HasValue() && req.Password.Value != "" {
+			hash, err := service.HashLinkPassword(req.Password.Value)
+			if err != nil {
+				server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+				return
+			}
+			params.PasswordHash = sql.NullString{String: hash, Valid: true}
+		}
+	}
+
+	if req.Title.Set {
+		params.Title = sql.NullString{String: req.Title.Value, Valid: req.Title.HasValue() && req.Title.Value != ""}
+		if utf8.RuneCountInString(params.Title.String) > maxTitleLength {
+			server.WriteError(w, http.StatusBadRequest,
+				ErrorResp{fmt.Sprintf("title must be at most %d characters long", maxTitleLength)})
+			return
+		}
+	}
+
+	if req.Preview.Set {
+		params.Preview = req.Preview.HasValue() && req.Preview.Value
+	}
+
+	if req.InterstitialSeconds.Set {
+		params.InterstitialSeconds = sql.NullInt32{
+			Int32: req.InterstitialSeconds.Value,
+			Valid: req.InterstitialSeconds.HasValue(),
+		}
+		seconds := params.InterstitialSeconds.Int32
+		if params.InterstitialSeconds.Valid && (seconds <= 0 || seconds > maxInterstitialSeconds) {
+			server.WriteError(w, http.StatusBadRequest,
+				ErrorResp{fmt.Sprintf("interstitial_seconds must be between 1 and %d", maxInterstitialSeconds)})
+			return
+		}
+	}
+
+	// Update the URL in database
+	updated, err := server.store.UpdateURL(r.Context(), params)
+	if err != nil {
+		// If the URL has been deleted in the meantime
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteError(w, http.StatusNotFound, ErrorResp{"This URL ID does not match any record"})
+			return
+		}
+
+		// If the alias has been taken by another URL
+		if strings.Contains(err.Error(), "url_alias_key") {
+			server.WriteError(w, http.StatusConflict, ErrorResp{"This alias has been taken"})
+			return
+		}
+
+		server.logger.Error("PATCH /api/urls/{id}: failed to update URL", "url_id", url.ID, "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+	server.logger.Info("Update URL successfully", "url_id", updated.ID)
+
+	// Both the old and the new alias may be cached
+	server.InvalidateURL(url)
+	server.InvalidateURL(updated)
+
+	server.WriteJSON(w, http.StatusOK, urlResponse{
+		OriginalURL:  updated.OriginalUrl,
+		ShortenURL:   server.GenerateShortenURL(updated.ID, updated.Alias, updated.Code),
+		ExpiresAt:    fromNullTime(updated.ExpiresAt),
+		MaxClicks:    fromNullInt32(updated.MaxClicks),
+		FallbackURL:  updated.FallbackUrl.String,
+		RedirectType: fromNullInt32(updated.RedirectType),
+		Protected:    updated.PasswordHash.Valid,
+		Title:        updated.Title.String,
+		Preview:      updated.Preview,
+		Interstitial: fromNullInt32(updated.InterstitialSeconds),
+		CreatedAt:    updated.TimeCreated,
+	})
+}
+
+// HandleDeleteShortenURL godoc
+// @Summary      Delete a shortened URL
+// @Description  Soft deletes a shortened URL: visiting it answers 410 Gone, while its visitor history is kept.
+// @Tags         urls
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id path string true "Shortened URL ID (base62 code or alias)"
+// @Success      204 "Shortened URL deleted successfully"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      404 {object} ErrorResp "URL ID not found"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/urls/{id} [delete]
+func (server *Server) HandleDeleteShortenURL(w http.ResponseWriter, r *http.Request) {
+	url, ok := server.getManagedURL(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := server.store.SoftDeleteURL(r.Context(), url.ID)
+	if err != nil {
+		server.logger.Error("DELETE /api/urls/{id}: failed to delete URL", "url_id", url.ID, "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+	if rows == 0 {
+		server.WriteError(w, http.StatusNotFound, ErrorResp{"This URL ID does not match any record"})
+		return
+	}
+
+	server.logger.Info("Delete URL successfully", "url_id", url.ID)
+	server.InvalidateURL(url)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/danglnh07/URLShortener/service"
+)
+
+// Request struct for create API key action
+type createAPIKeyRequest struct {
+	Name   string `json:"name" validate:"required"`
+	UserID int64  `json:"user_id" validate:"required"`
+}
+
+// Response struct for API key, the raw key is only returned once on creation
+type apiKeyResponse struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Key       string     `json:"key,omitempty"`
+	Prefix    string     `json:"prefix"`
+	UserID    int64      `json:"user_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Helper function to convert database record into response
+func newAPIKeyResponse(apiKey db.ApiKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		UserID:    apiKey.UserID,
+		CreatedAt: apiKey.TimeCreated,
+		RevokedAt: fromNullTime(apiKey.TimeRevoked),
+	}
+}
+
+// HandleCreateAPIKey godoc
+//
+// @Summary      Create an API key
+// @Description  Generates a new API key for a user. The raw key is only returned in this response, store it safely.
+// @Tags         api_keys
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        request body createAPIKeyRequest true "API key request"
+// @Success      201 {object} apiKeyResponse "API key created successfully"
+// @Failure      400 {object} ErrorResp "Invalid input"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      403 {object} ErrorResp "Admin permission required"
+// @Failure      404 {object} ErrorResp "User not found"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/keys [post]
+func (server *Server) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	// Parse JSON request and validate
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{"Invalid JSON body"})
+		return
+	}
+
+	if err := server.validate.Struct(req); err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{server.ValidationMessage(err)})
+		return
+	}
+
+	// Check if the user exists
+	if _, err := server.store.GetUser(r.Context(), req.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteError(w, http.StatusNotFound, ErrorResp{"This user does not exist"})
+			return
+		}
+
+		server.logger.Error("POST /api/keys: failed to get user", "user_id", req.UserID, "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+
+	// Generate the key, only its hash is stored
+	key, err := service.GenerateAPIKey()
+	if err != nil {
+		server.logger.Error("POST /api/keys: failed to generate API key", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+
+	apiKey, err := server.store.CreateAPIKey(r.Context(), db.CreateAPIKeyParams{
+		Name:    req.Name,
+		KeyHash: service.HashAPIKey(key),
+		Prefix:  key[:service.APIKeyDisplayLength],
+		UserID:  req.UserID,
+	})
+	if err != nil {
+		server.logger.Error("POST /api/keys: failed to insert API key into database", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+	server.logger.Info("Create API key successfully", "id", apiKey.ID, "name", apiKey.Name)
+
+	resp := newAPIKeyResponse(apiKey)
+	resp.Key = key
+	server.WriteJSON(w, http.StatusCreated, resp)
+}
+
+// HandleListAPIKey godoc
+//
+// @Summary      List API keys
+// @Description  Retrieves a paginated list of API keys, including the revoked ones. Raw keys are never returned.
+// @Tags         api_keys
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        page_size  query int true  "Number of items per page" minimum(1) maximum(100)
+// @Param        page_index query int true  "Page index (starting from 1)" minimum(1)
+// @Success      200 {array} apiKeyResponse "List of API keys"
+// @Failure      400 {object} ErrorResp "Invalid pagination parameters"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      403 {object} ErrorResp "Admin permission required"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/keys [get]
+func (server *Server) HandleListAPIKey(w http.ResponseWriter, r *http.Request) {
+	// Get the page_size and page_index parameter
+	pageSize, pageIndex, err := server.ExtractPageParams(r)
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+		return
+	}
+
+	apiKeys, err := server.store.ListAPIKey(r.Context(), db.ListAPIKeyParams{
+		Offset: (pageIndex - 1) * pageSize,
+		Limit:  pageSize,
+	})
+	if err != nil {
+		server.logger.Error("GET /api/keys: failed to get list of API keys", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+
+	resps := make([]apiKeyResponse, len(apiKeys))
+	for i, apiKey := range apiKeys {
+		resps[i] = newAPIKeyResponse(apiKey)
+	}
+	server.WriteJSON(w, http.StatusOK, resps)
+}
+
+// HandleRevokeAPIKey godoc
+//
+// @Summary      Revoke an API key
+// @Description  Revokes an API key, any request using this key will be rejected afterward.
+// @Tags         api_keys
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id path int true "API key ID"
+// @Success      204 "API key revoked successfully"
+// @Failure      400 {object} ErrorResp "Invalid API key ID"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      403 {object} ErrorResp "Admin permission required"
+// @Failure      404 {object} ErrorResp "API key not found or already revoked"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/keys/{id} [delete]
+func (server *Server) HandleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{"Invalid API key ID"})
+		return
+	}
+
+	rows, err := server.store.RevokeAPIKey(r.Context(), id)
+	if err != nil {
+		server.logger.Error("DELETE /api/keys/{id}: failed to revoke API key", "id", id, "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+	if rows == 0 {
+		server.WriteError(w, http.StatusNotFound, ErrorResp{"This API key does not exist or has been revoked"})
+		return
+	}
+
+	server.logger.Info("Revoke API key successfully", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -15,10 +15,9 @@ import (
 	"testing"
 	"time"
 
-	_ "github.com/lib/pq"
-
 	db "github.com/danglnh07/URLShortener/db/sqlc"
 	"github.com/danglnh07/URLShortener/service"
+	"github.com/danglnh07/URLShortener/store"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,6 +27,8 @@ var (
 	config service.Config
 )
 
+const adminAPIKey = "test-admin-key"
+
 func TestMain(m *testing.M) {
 	// Load config
 	err := service.LoadConfig("../.env", logger)
@@ -37,28 +38,95 @@ func TestMain(m *testing.M) {
 		// In CI/CD, we can get the enviroment from other source, so we don't return here
 	}
 
+	// Without a database configured, run the tests against the in-memory store
+	driver := os.Getenv("DB_DRIVER")
+	if driver == "" {
+		driver = store.DriverMemory
+	}
+
 	config = service.Config{
-		BaseURL:    os.Getenv("BASE_URL"),
-		DbDriver:   os.Getenv("DB_DRIVER"),
-		DbSource:   os.Getenv("DB_SOURCE"),
-		MaxRequest: 5,
-		RefillRate: time.Duration(10) * time.Second,
+		BaseURL:         os.Getenv("BASE_URL"),
+		DbDriver:        driver,
+		DbSource:        os.Getenv("DB_SOURCE"),
+		MaxRequest:      5,
+		RefillRate:      time.Duration(10) * time.Second,
+		AdminAPIKey:     adminAPIKey,
+		RecordBotVisits: true,
+
+		// The tests act as the proxy in front of the server
+		TrustForwardedFor: true,
+
+		RedirectMaxRequest: 5,
+		RedirectRefillRate: time.Duration(10) * time.Second,
+
+		LinkAccessTTL:         time.Hour,
+		PasswordMaxAttempts:   3,
+		PasswordAttemptRefill: time.Minute,
 	}
 
 	// Connect to database
-	conn, err := sql.Open(config.DbDriver, config.DbSource)
+	storage, err := store.Open(config.DbDriver, config.DbSource)
 	if err != nil {
 		logger.Error("Failed to connect to database", "error", err)
 		os.Exit(1)
 	}
+	if _, err := store.MigrateUp(context.Background(), storage); err != nil {
+		logger.Error("Failed to migrate database", "error", err)
+		os.Exit(1)
+	}
 
 	// Create server
-	server = NewServer(&config, conn, logger)
+	server = NewServer(&config, storage, logger)
 	server.RegisterHandler()
 
 	os.Exit(m.Run())
 }
 
+// Helper to post a create shorten URL request with the admin API key
+func postCreate(t *testing.T, req createShortenURLRequest) *httptest.ResponseRecorder {
+	var buffer bytes.Buffer
+	err := json.NewEncoder(&buffer).Encode(req)
+	require.NoError(t, err)
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/urls", &buffer)
+	httpReq.Header.Set("X-API-Key", adminAPIKey)
+	rr := httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleCreateShortenURL)).ServeHTTP(rr, httpReq)
+	return rr
+}
+
+// Helper to get the short code (or alias) of the shorten URL in a create response
+func createdCode(t *testing.T, rr *httptest.ResponseRecorder) string {
+	var resp createShortenURLResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	require.NoError(t, err)
+	return shortenURLCode(t, resp.ShortenURL)
+}
+
+// shortenURLCode returns the code (or alias) of a shorten URL
+func shortenURLCode(t *testing.T, shortenURL string) string {
+	u, err := url.Parse(shortenURL)
+	require.NoError(t, err)
+	return strings.TrimPrefix(u.Path, "/")
+}
+
+// codeID returns the ID of the URL with the given generated code
+func codeID(t *testing.T, code string) int64 {
+	id, ok := server.codes.Decode(code)
+	require.True(t, ok)
+	return id
+}
+
+// deleteURLs deletes the URLs of the given codes or aliases from the database, with their visitors
+func deleteURLs(t *testing.T, codes ...string) {
+	for _, code := range codes {
+		url, err := server.GetURLByCode(context.Background(), code)
+		require.NoError(t, err)
+		err = server.store.DeleteURL(context.Background(), url.ID)
+		require.NoError(t, err)
+	}
+}
+
 func TestHandleCreateShortenURL(t *testing.T) {
 	data := []string{
 		"https://www.youtube.com/watch?v=LCfEqudu4pc&list=RDLCfEqudu4pc&start_radio=1&ab_channel=ForestOfLight",
@@ -67,6 +135,7 @@ func TestHandleCreateShortenURL(t *testing.T) {
 	}
 
 	// Run each test case
+	codes := []string{}
 	for _, url := range data {
 		// Create request
 		var buffer bytes.Buffer
@@ -75,34 +144,32 @@ func TestHandleCreateShortenURL(t *testing.T) {
 
 		req, err := http.NewRequest("POST", "/api/urls", &buffer)
 		require.NoError(t, err)
+		req.Header.Set("X-API-Key", adminAPIKey)
 
 		// Mock HTTP and test
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(server.HandleCreateShortenURL)
+		handler := server.AuthMiddleware(http.HandlerFunc(server.HandleCreateShortenURL))
 		handler.ServeHTTP(rr, req)
 
 		// Compare result
 		require.Equal(t, rr.Code, 201)
 		require.NotEmpty(t, rr.Body)
+		codes = append(codes, createdCode(t, rr))
 	}
 
 	// Clean up database
-	err := server.queries.DeleteURL(context.Background(), data[0])
-	require.NoError(t, err)
-	err = server.queries.DeleteURL(context.Background(), data[1])
-	require.NoError(t, err)
-	err = server.queries.DeleteURL(context.Background(), data[2])
-	require.NoError(t, err)
+	deleteURLs(t, codes...)
 }
 
 func TestHandleCountURL(t *testing.T) {
 	// Create request
 	req, err := http.NewRequest("GET", "/api/urls/count", nil)
 	require.NoError(t, err)
+	req.Header.Set("X-API-Key", adminAPIKey)
 
 	// Mock HTTP and test
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(server.HandleCountURL)
+	handler := server.AuthMiddleware(http.HandlerFunc(server.HandleCountURL))
 	handler.ServeHTTP(rr, req)
 
 	// Compare result
@@ -119,10 +186,11 @@ func TestHandleListURL(t *testing.T) {
 	// Create request
 	req, err := http.NewRequest("GET", "/api/urls?page_size=5&page_index=1", nil)
 	require.NoError(t, err)
+	req.Header.Set("X-API-Key", adminAPIKey)
 
 	// Mock HTTP and test
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(server.HandleListURL)
+	handler := server.AuthMiddleware(http.HandlerFunc(server.HandleListURL))
 	handler.ServeHTTP(rr, req)
 
 	// Compare result
@@ -146,10 +214,11 @@ func TestHandleListVisitor(t *testing.T) {
 
 	req, err := http.NewRequest("POST", "/api/urls", &buffer)
 	require.NoError(t, err)
+	req.Header.Set("X-API-Key", adminAPIKey)
 
 	// Mock HTTP and test
 	createRecoder := httptest.NewRecorder()
-	createHandler := http.HandlerFunc(server.HandleCreateShortenURL)
+	createHandler := server.AuthMiddleware(http.HandlerFunc(server.HandleCreateShortenURL))
 	createHandler.ServeHTTP(createRecoder, req)
 
 	// Compare result
@@ -176,6 +245,7 @@ func TestHandleListVisitor(t *testing.T) {
 	// Get the list of visitor
 	req, err = http.NewRequest("GET", fmt.Sprintf("/api/urls/%s/visitors?page_size=5&page_index=1", code), nil)
 	require.NoError(t, err)
+	req.Header.Set("X-API-Key", adminAPIKey)
 
 	// Mock HTTP and test
 	listRecorder := httptest.NewRecorder()
@@ -193,10 +263,646 @@ func TestHandleListVisitor(t *testing.T) {
 	require.Equal(t, resp[0].ShortenURL, shortenURL.ShortenURL)
 
 	// Clean up database
-	server.queries.DeleteVisitor(context.Background(), db.DeleteVisitorParams{
+	server.store.DeleteVisitor(context.Background(), db.DeleteVisitorParams{
 		Ip:          resp[0].Ip,
-		UrlID:       service.DecodeBase62(code),
+		UrlID:       codeID(t, code),
 		TimeVisited: resp[0].TimeVisited,
 	})
-	server.queries.DeleteURL(context.Background(), data)
+	server.store.DeleteURL(context.Background(), codeID(t, code))
+}
+
+func TestHandleListVisitorMetadata(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=metadata"
+
+	// Create a shorten URL
+	var buffer bytes.Buffer
+	err := json.NewEncoder(&buffer).Encode(createShortenURLRequest{URL: data, AllowDuplicate: true})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/urls", &buffer)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	rr := httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleCreateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var shortenURL createShortenURLResponse
+	err = json.NewDecoder(rr.Body).Decode(&shortenURL)
+	require.NoError(t, err)
+	u, err := url.Parse(shortenURL.ShortenURL)
+	require.NoError(t, err)
+	code := strings.TrimPrefix(u.Path, "/")
+
+	// Visit it from a newsletter and from a search engine
+	for _, referrer := range []string{"https://mail.example.com/newsletter", "https://www.google.com/"} {
+		req := httptest.NewRequest(http.MethodGet, "http://Short.Example.com/"+code+"?utm_source=test", nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.Header.Set("Referer", referrer)
+		req.Header.Set("User-Agent", "Mozilla/5.0 (test)")
+		req.Header.Set("Accept-Language", "vi-VN,vi;q=0.9")
+		req.SetPathValue("code", code)
+		rr := httptest.NewRecorder()
+		server.HandleRedirect(rr, req)
+		require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	}
+
+	// Helper to list the visitors with the given filters
+	listVisitor := func(filters string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet,
+			fmt.Sprintf("/api/urls/%s/visitors?page_size=10&page_index=1&%s", code, filters), nil)
+		req.Header.Set("X-API-Key", adminAPIKey)
+		req.SetPathValue("id", code)
+		rr := httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleListVisitor)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Filter by referrer, case-insensitively
+	rr = listVisitor("referrer=NEWSLETTER")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp []listVisitorResponse
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	require.NoError(t, err)
+	require.Len(t, resp, 1)
+	require.Equal(t, "https://mail.example.com/newsletter", resp[0].Referrer)
+	require.Equal(t, "Mozilla/5.0 (test)", resp[0].UserAgent)
+	require.Equal(t, "vi-VN,vi;q=0.9", resp[0].AcceptLanguage)
+	require.Equal(t, "short.example.com", resp[0].Host)
+	require.Equal(t, "utm_source=test", resp[0].QueryString)
+
+	// Filters are combined, and LIKE wildcards are matched literally
+	rr = listVisitor("query_string=utm_source&user_agent=mozilla")
+	require.Equal(t, http.StatusOK, rr.Code)
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	require.NoError(t, err)
+	require.Len(t, resp, 2)
+
+	rr = listVisitor("referrer=%25")
+	require.Equal(t, http.StatusOK, rr.Code)
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	require.NoError(t, err)
+	require.Empty(t, resp)
+
+	// HEAD requests are recorded as bot visits, and can be filtered out
+	req = httptest.NewRequest(http.MethodHead, "/"+code, nil)
+	req.RemoteAddr = "127.0.0.1:12345"
+	req.Header.Set("User-Agent", "Mozilla/5.0 (test)")
+	req.SetPathValue("code", code)
+	rr = httptest.NewRecorder()
+	server.HandleRedirect(rr, req)
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+
+	rr = listVisitor("bot=true")
+	require.Equal(t, http.StatusOK, rr.Code)
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	require.NoError(t, err)
+	require.Len(t, resp, 1)
+	require.True(t, resp[0].IsBot)
+
+	rr = listVisitor("bot=false")
+	require.Equal(t, http.StatusOK, rr.Code)
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	require.NoError(t, err)
+	require.Len(t, resp, 2)
+
+	// Invalid filters
+	rr = listVisitor("from=yesterday")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = listVisitor("bot=maybe")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Clean up database
+	deleteURLs(t, code)
+}
+
+func TestHandleCreateAliasURL(t *testing.T) {
+	data := []string{
+		"https://www.youtube.com/watch?v=3JZ_D3ELwOQ&ab_channel=ChilledCow",
+		"https://www.youtube.com/watch?v=5qap5aO4i9A&ab_channel=LofiGirl",
+	}
+	alias := fmt.Sprintf("spring-sale-%d", time.Now().UnixNano())
+
+	// Create with a reserved alias should fail
+	require.Equal(t, http.StatusBadRequest, postCreate(t, createShortenURLRequest{URL: data[0], Alias: "api"}).Code)
+
+	// Create with a valid alias
+	rr := postCreate(t, createShortenURLRequest{URL: data[0], Alias: alias})
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var resp createShortenURLResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(resp.ShortenURL, "/"+alias))
+
+	// The same alias cannot be used twice
+	require.Equal(t, http.StatusConflict, postCreate(t, createShortenURLRequest{URL: data[1], Alias: alias}).Code)
+
+	// Redirect using the alias
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/"+alias, nil)
+	req.RemoteAddr = "127.0.0.1:12345"
+	req.SetPathValue("code", alias)
+	redirectRecoder := httptest.NewRecorder()
+	http.HandlerFunc(server.HandleRedirect).ServeHTTP(redirectRecoder, req)
+	require.Equal(t, 301, redirectRecoder.Code)
+	require.Equal(t, data[0], redirectRecoder.Header().Get("Location"))
+
+	// Clean up database
+	url, err := server.store.GetURLByAlias(context.Background(), sql.NullString{String: alias, Valid: true})
+	require.NoError(t, err)
+	visitors, err := server.store.ListVisitor(context.Background(), db.ListVisitorParams{
+		UrlID:  url.ID,
+		Offset: 0,
+		Limit:  100,
+	})
+	require.NoError(t, err)
+	for _, visitor := range visitors {
+		server.store.DeleteVisitor(context.Background(), db.DeleteVisitorParams{
+			Ip:          visitor.Ip,
+			UrlID:       visitor.UrlID,
+			TimeVisited: visitor.TimeVisited,
+		})
+	}
+	server.store.DeleteURL(context.Background(), url.ID)
+}
+
+func TestAliasTakeover(t *testing.T) {
+	data := []string{
+		"https://www.youtube.com/watch?v=takeover",
+		"https://www.youtube.com/watch?v=takeover-attempt",
+	}
+
+	rr := postCreate(t, createShortenURLRequest{URL: data[0]})
+	require.Equal(t, http.StatusCreated, rr.Code)
+	code := createdCode(t, rr)
+
+	// Neither the code of an existing URL nor the code of a future URL can be used as alias
+	require.Equal(t, http.StatusBadRequest, postCreate(t, createShortenURLRequest{URL: data[1], Alias: code}).Code)
+	require.Equal(t, http.StatusBadRequest, postCreate(t, createShortenURLRequest{
+		URL:   data[1],
+		Alias: server.codes.Encode(1_000_000),
+	}).Code)
+
+	// Nor set by an update
+	rr = postCreate(t, createShortenURLRequest{URL: data[1], Alias: fmt.Sprintf("takeover-%d", time.Now().UnixNano())})
+	require.Equal(t, http.StatusCreated, rr.Code)
+	alias := createdCode(t, rr)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/urls/"+alias, strings.NewReader(`{"alias": "`+code+`"}`))
+	req.Header.Set("X-API-Key", adminAPIKey)
+	req.SetPathValue("id", alias)
+	rr = httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleUpdateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// The code still redirects to its own URL
+	req = httptest.NewRequest(http.MethodGet, "http://localhost:8080/"+code, nil)
+	req.RemoteAddr = "127.0.0.1:12345"
+	req.SetPathValue("code", code)
+	rr = httptest.NewRecorder()
+	server.HandleRedirect(rr, req)
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, data[0], rr.Header().Get("Location"))
+
+	// Clean up database
+	deleteURLs(t, code, alias)
+}
+
+func TestAliasNamespace(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=alias-namespace"
+	defer func(codes service.CodeGenerator) { server.codes = codes }(server.codes)
+
+	// Helper to redirect by the given code
+	redirect := func(code string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.SetPathValue("code", code)
+		rr := httptest.NewRecorder()
+		server.HandleRedirect(rr, req)
+		return rr
+	}
+
+	// Plain words are valid aliases whatever the code strategy
+	aliases := []string{}
+	strategies := []*service.Config{
+		{CodeStrategy: service.CodeStrategySequential},
+		{CodeStrategy: service.CodeStrategyObfuscated, CodeSecret: "test-secret"},
+		{CodeStrategy: service.CodeStrategyRandom},
+	}
+	for _, strategy := range strategies {
+		server.codes = service.NewCodeGenerator(strategy)
+		alias := "promo" + service.EncodeBase62(time.Now().UnixNano())
+		rr := postCreate(t, createShortenURLRequest{URL: data, Alias: alias, AllowDuplicate: true})
+		require.Equal(t, http.StatusCreated, rr.Code, strategy.CodeStrategy)
+		require.Equal(t, alias, createdCode(t, rr))
+		aliases = append(aliases, alias)
+
+		rr = redirect(alias)
+		require.Equal(t, http.StatusMovedPermanently, rr.Code, strategy.CodeStrategy)
+		require.Equal(t, data, rr.Header().Get("Location"))
+	}
+
+	// The codes of the URLs created before codes had a prefix still redirect, and cannot be used as alias
+	server.codes = service.NewCodeGenerator(strategies[0])
+	rr := postCreate(t, createShortenURLRequest{URL: data, AllowDuplicate: true})
+	require.Equal(t, http.StatusCreated, rr.Code)
+	url, err := server.store.GetURL(context.Background(), codeID(t, createdCode(t, rr)))
+	require.NoError(t, err)
+	legacy := service.EncodeBase62(url.ID)
+	require.Equal(t, http.StatusBadRequest, redirect(legacy).Code)
+
+	server.legacyCodeMaxID = url.ID
+	defer func() { server.legacyCodeMaxID = 0 }()
+	rr = redirect(legacy)
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, data, rr.Header().Get("Location"))
+	require.Equal(t, http.StatusBadRequest, postCreate(t, createShortenURLRequest{
+		URL:            data,
+		Alias:          legacy,
+		AllowDuplicate: true,
+	}).Code)
+
+	// Clean up database
+	deleteURLs(t, aliases...)
+	err = server.store.DeleteURL(context.Background(), url.ID)
+	require.NoError(t, err)
+}
+
+func TestHandleRedirectExpired(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=jfKfPfyJRdk&ab_channel=LofiGirl"
+	fallback := "https://www.youtube.com/@LofiGirl"
+
+	// Expiration time in the past and non-positive max_clicks are rejected
+	past := time.Now().Add(-time.Hour)
+	require.Equal(t, http.StatusBadRequest, postCreate(t, createShortenURLRequest{URL: data, ExpiresAt: &past}).Code)
+	zero := int32(0)
+	require.Equal(t, http.StatusBadRequest, postCreate(t, createShortenURLRequest{URL: data, MaxClicks: &zero}).Code)
+
+	// Create an URL that can only be visited once
+	maxClicks := int32(1)
+	rr := postCreate(t, createShortenURLRequest{URL: data, MaxClicks: &maxClicks, FallbackURL: fallback})
+	require.Equal(t, http.StatusCreated, rr.Code)
+	code := createdCode(t, rr)
+
+	// Link unfurlers are redirected without consuming the click budget
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/"+code, nil)
+	req.RemoteAddr = "127.0.0.1:12345"
+	req.Header.Set("User-Agent", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)")
+	req.SetPathValue("code", code)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.HandleRedirect).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+
+	// First human visit is redirected, second visit is gone
+	for _, status := range []int{http.StatusMovedPermanently, http.StatusGone} {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/"+code, nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.Header.Set("User-Agent", "Mozilla/5.0 (test)")
+		req.SetPathValue("code", code)
+		redirectRecoder := httptest.NewRecorder()
+		http.HandlerFunc(server.HandleRedirect).ServeHTTP(redirectRecoder, req)
+		require.Equal(t, status, redirectRecoder.Code)
+
+		if status == http.StatusGone {
+			var resp goneResp
+			err := json.NewDecoder(redirectRecoder.Body).Decode(&resp)
+			require.NoError(t, err)
+			require.Equal(t, fallback, resp.FallbackURL)
+		}
+	}
+
+	// Clean up database
+	visitors, err := server.store.ListVisitor(context.Background(), db.ListVisitorParams{
+		UrlID:  codeID(t, code),
+		Offset: 0,
+		Limit:  100,
+	})
+	require.NoError(t, err)
+	for _, visitor := range visitors {
+		server.store.DeleteVisitor(context.Background(), db.DeleteVisitorParams{
+			Ip:          visitor.Ip,
+			UrlID:       visitor.UrlID,
+			TimeVisited: visitor.TimeVisited,
+		})
+	}
+	server.store.DeleteURL(context.Background(), codeID(t, code))
+}
+
+func TestHandleUpdateDeleteURL(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=4xDzrJKXOOY&ab_channel=LofiGirl"
+	moved := "https://www.youtube.com/watch?v=4xDzrJKXOOY"
+
+	// Create a shorten URL with a click budget
+	maxClicks := int32(10)
+	var buffer bytes.Buffer
+	err := json.NewEncoder(&buffer).Encode(createShortenURLRequest{URL: data, MaxClicks: &maxClicks})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/urls", &buffer)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	rr := httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleCreateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var shortenURL createShortenURLResponse
+	err = json.NewDecoder(rr.Body).Decode(&shortenURL)
+	require.NoError(t, err)
+
+	u, err := url.Parse(shortenURL.ShortenURL)
+	require.NoError(t, err)
+	code := strings.TrimPrefix(u.Path, "/")
+
+	// Change the destination and remove the click budget
+	body := strings.NewReader(fmt.Sprintf(`{"url": "%s", "max_clicks": null}`, moved))
+	req = httptest.NewRequest(http.MethodPatch, "/api/urls/"+code, body)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	req.SetPathValue("id", code)
+	rr = httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleUpdateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var updated urlResponse
+	err = json.NewDecoder(rr.Body).Decode(&updated)
+	require.NoError(t, err)
+	require.Equal(t, moved, updated.OriginalURL)
+	require.Nil(t, updated.MaxClicks)
+
+	// Visit the URL, which now redirects to the new destination
+	redirect := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/"+code, nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.SetPathValue("code", code)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.HandleRedirect).ServeHTTP(rr, req)
+		return rr
+	}
+	rr = redirect()
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, moved, rr.Header().Get("Location"))
+
+	// Delete the URL
+	req = httptest.NewRequest(http.MethodDelete, "/api/urls/"+code, nil)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	req.SetPathValue("id", code)
+	rr = httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleDeleteShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+
+	// The deleted URL answers 410, but its visitors are kept
+	require.Equal(t, http.StatusGone, redirect().Code)
+	visitors, err := server.store.ListVisitor(context.Background(), db.ListVisitorParams{
+		UrlID:  codeID(t, code),
+		Offset: 0,
+		Limit:  100,
+	})
+	require.NoError(t, err)
+	require.Len(t, visitors, 1)
+
+	// Clean up database (visitors are deleted along with the URL)
+	deleteURLs(t, code)
+}
+
+func TestRedirectType(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=redirect-type"
+
+	// Create a shorten URL with a temporary redirect, an invalid redirect type is rejected
+	create := func(redirectType int32) *httptest.ResponseRecorder {
+		return postCreate(t, createShortenURLRequest{URL: data, RedirectType: &redirectType})
+	}
+	rr := create(http.StatusOK)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), "redirect_type must be one of")
+
+	rr = create(http.StatusFound)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	code := createdCode(t, rr)
+
+	// Helpers to visit and update the URL
+	redirect := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.SetPathValue("code", code)
+		rr := httptest.NewRecorder()
+		server.HandleRedirect(rr, req)
+		return rr
+	}
+	update := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/urls/"+code, strings.NewReader(body))
+		req.Header.Set("X-API-Key", adminAPIKey)
+		req.SetPathValue("id", code)
+		rr := httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleUpdateShortenURL)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Temporary redirects are never cached
+	rr = redirect()
+	require.Equal(t, http.StatusFound, rr.Code)
+	require.Equal(t, data, rr.Header().Get("Location"))
+	require.Equal(t, "private, no-store", rr.Header().Get("Cache-Control"))
+
+	// Permanent redirects are cached
+	rr = update(`{"redirect_type": 308}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var updated urlResponse
+	err := json.NewDecoder(rr.Body).Decode(&updated)
+	require.NoError(t, err)
+	require.Equal(t, int32(http.StatusPermanentRedirect), *updated.RedirectType)
+
+	rr = redirect()
+	require.Equal(t, http.StatusPermanentRedirect, rr.Code)
+	require.Equal(t, "public, max-age=86400", rr.Header().Get("Cache-Control"))
+
+	// Without redirect type, the default of the server is used
+	require.Equal(t, http.StatusBadRequest, update(`{"redirect_type": 303}`).Code)
+	require.Equal(t, http.StatusOK, update(`{"redirect_type": null}`).Code)
+	require.Equal(t, http.StatusMovedPermanently, redirect().Code)
+
+	// Clean up database
+	deleteURLs(t, code)
+}
+
+func TestCodeStrategy(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=code-strategy"
+	defer func() { server.codes = service.NewCodeGenerator(server.config) }()
+
+	// Helpers to create a shorten URL and return its code, and to get an URL by its code
+	create := func() string {
+		rr := postCreate(t, createShortenURLRequest{URL: data, AllowDuplicate: true})
+		require.Equal(t, http.StatusCreated, rr.Code)
+		return createdCode(t, rr)
+	}
+	redirect := func(code string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.SetPathValue("code", code)
+		rr := httptest.NewRecorder()
+		server.HandleRedirect(rr, req)
+		return rr
+	}
+	listVisitor := func(code string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/urls/"+code+"/visitors?page_size=10&page_index=1", nil)
+		req.Header.Set("X-API-Key", adminAPIKey)
+		req.SetPathValue("id", code)
+		rr := httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleListVisitor)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Obfuscated codes are decoded by the same strategy, consecutive URLs get unrelated codes
+	server.codes = service.NewCodeGenerator(&service.Config{
+		CodeStrategy: service.CodeStrategyObfuscated,
+		CodeSecret:   "test-secret",
+	})
+	first, second := create(), create()
+	require.Len(t, first, len(service.CodePrefix)+6)
+	require.Len(t, second, len(service.CodePrefix)+6)
+	firstID, secondID := codeID(t, first), codeID(t, second)
+	require.Equal(t, firstID+1, secondID)
+	require.NotEqual(t, service.DecodeBase62(first[1:])+1, service.DecodeBase62(second[1:]))
+
+	rr := redirect(second)
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, data, rr.Header().Get("Location"))
+	require.Equal(t, http.StatusOK, listVisitor(second).Code)
+
+	sequential := service.NewCodeGenerator(&service.Config{})
+	require.Equal(t, http.StatusBadRequest, redirect(sequential.Encode(secondID)).Code)
+	require.Equal(t, http.StatusBadRequest, redirect(service.EncodeBase62(secondID)).Code)
+
+	// Random codes are stored, the URL cannot be found by its ID
+	server.codes = service.NewCodeGenerator(&service.Config{CodeStrategy: service.CodeStrategyRandom})
+	code := create()
+	require.Len(t, code, len(service.CodePrefix)+8)
+	require.Equal(t, http.StatusMovedPermanently, redirect(code).Code)
+	require.Equal(t, http.StatusOK, listVisitor(code).Code)
+
+	url, err := server.store.GetURLByCode(context.Background(), sql.NullString{String: code, Valid: true})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, redirect(sequential.Encode(url.ID)).Code)
+	require.Equal(t, http.StatusNotFound, listVisitor(sequential.Encode(url.ID)).Code)
+
+	// Generated codes cannot be used as alias
+	rr = postCreate(t, createShortenURLRequest{URL: data, Alias: code, AllowDuplicate: true})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, data, redirect(code).Header().Get("Location"))
+
+	// Nor the random codes stored before codes had a prefix
+	stored := fmt.Sprintf("rnd%s", service.EncodeBase62(time.Now().UnixNano()))
+	_, err = server.store.CreateURL(context.Background(), db.CreateURLParams{
+		OriginalUrl: data,
+		Code:        sql.NullString{String: stored, Valid: true},
+	})
+	require.NoError(t, err)
+	rr = postCreate(t, createShortenURLRequest{URL: data, Alias: stored, AllowDuplicate: true})
+	require.Equal(t, http.StatusConflict, rr.Code)
+	require.Equal(t, data, redirect(stored).Header().Get("Location"))
+
+	// Clean up database
+	for _, id := range []int64{firstID, secondID} {
+		err = server.store.DeleteURL(context.Background(), id)
+		require.NoError(t, err)
+	}
+	deleteURLs(t, code, stored)
+}
+
+func TestInitCodes(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemoryStore()
+
+	// Helper to check the code scheme of the given strategy against the store
+	check := func(strategy string, minLength int) error {
+		strategyConfig := config
+		strategyConfig.CodeStrategy = strategy
+		strategyConfig.CodeMinLength = minLength
+		strategyConfig.CodeSecret = "test-secret"
+		return NewServer(&strategyConfig, storage, logger).InitCodes(ctx)
+	}
+
+	// Without URLs, the strategy can change freely
+	require.NoError(t, check(service.CodeStrategyObfuscated, 0))
+	require.NoError(t, check(service.CodeStrategySequential, 0))
+
+	// The URLs without stored code keep the scheme they were created with
+	_, err := storage.CreateURL(ctx, db.CreateURLParams{OriginalUrl: "https://www.youtube.com/watch?v=scheme"})
+	require.NoError(t, err)
+	require.NoError(t, check(service.CodeStrategySequential, 0))
+	require.ErrorContains(t, check(service.CodeStrategyObfuscated, 0), "CODE_STRATEGY")
+	require.ErrorContains(t, check(service.CodeStrategySequential, 4), "CODE_STRATEGY")
+
+	// Random codes are stored, the URLs without one keep their sequential code
+	require.NoError(t, check(service.CodeStrategyRandom, 0))
+	require.NoError(t, check(service.CodeStrategySequential, 0))
+}
+
+func TestHandleCreateDuplicateURL(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=kgx4WGK0oNU&ab_channel=LofiGirl"
+
+	// Helper to create a shorten URL and return the status code with the code of the shorten URL
+	create := func(req createShortenURLRequest) (int, string) {
+		rr := postCreate(t, req)
+		return rr.Code, createdCode(t, rr)
+	}
+
+	// First request creates the shorten URL
+	status, first := create(createShortenURLRequest{URL: data})
+	require.Equal(t, http.StatusCreated, status)
+
+	// Same request returns the existing shorten URL
+	status, second := create(createShortenURLRequest{URL: data})
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, first, second)
+
+	// Explicitly ask for a duplicate creates a new, independent shorten URL
+	status, third := create(createShortenURLRequest{URL: data, AllowDuplicate: true})
+	require.Equal(t, http.StatusCreated, status)
+	require.NotEqual(t, first, third)
+
+	// Different settings create a new shorten URL
+	maxClicks := int32(5)
+	status, fourth := create(createShortenURLRequest{URL: data, MaxClicks: &maxClicks})
+	require.Equal(t, http.StatusCreated, status)
+	require.NotEqual(t, first, fourth)
+
+	// Clean up database
+	deleteURLs(t, first, third, fourth)
+}
+
+func TestHandleCreateInvalidURL(t *testing.T) {
+	// Dangerous, relative and internal URLs are rejected with a structured error
+	cases := []struct {
+		req   createShortenURLRequest
+		field string
+		code  string
+	}{
+		{createShortenURLRequest{URL: "javascript:alert(1)"}, "url", service.URLErrorInvalidScheme},
+		{createShortenURLRequest{URL: "/relative/path"}, "url", service.URLErrorNotAbsolute},
+		{createShortenURLRequest{URL: "http://127.0.0.1:8080/admin"}, "url", service.URLErrorPrivateAddress},
+		{createShortenURLRequest{URL: "http://localhost/"}, "url", service.URLErrorPrivateAddress},
+		{
+			createShortenURLRequest{URL: "https://example.com/", FallbackURL: "data:text/html,hi"},
+			"fallback_url",
+			service.URLErrorInvalidScheme,
+		},
+	}
+	for _, c := range cases {
+		rr := postCreate(t, c.req)
+		require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+		var resp ValidationErrorResp
+		err := json.NewDecoder(rr.Body).Decode(&resp)
+		require.NoError(t, err)
+		require.Equal(t, c.field, resp.Field)
+		require.Equal(t, c.code, resp.Code)
+	}
+
+	// Different spellings of the same URL are stored in the same canonical form
+	rr := postCreate(t, createShortenURLRequest{URL: "HTTPS://WWW.Example.COM:443"})
+	require.Equal(t, http.StatusCreated, rr.Code)
+	code := createdCode(t, rr)
+	rr = postCreate(t, createShortenURLRequest{URL: "https://www.example.com/"})
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, code, createdCode(t, rr))
+
+	// Clean up database
+	deleteURLs(t, code)
 }
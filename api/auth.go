@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/danglnh07/URLShortener/service"
+)
+
+// Principal holds the identity of the caller, extracted from the API key
+type Principal struct {
+	KeyID  int64 // 0 for the bootstrap admin key from config
+	UserID int64 // 0 for the bootstrap admin key from config
+	Name   string
+	Admin  bool
+}
+
+// Get the owner ID to record on the URLs created by this principal. The bootstrap admin key
+// does not belong to any user, so its URLs have no owner
+func (principal *Principal) OwnerID() sql.NullInt64 {
+	return sql.NullInt64{Int64: principal.UserID, Valid: principal.UserID != 0}
+}
+
+// Get the owner filter used when listing URLs: admin sees everything (NULL filter), while
+// other users only see their own URLs
+func (principal *Principal) OwnerFilter() sql.NullInt64 {
+	if principal.Admin {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: principal.UserID, Valid: true}
+}
+
+// Check if the principal can access a resource owned by the given owner
+func (principal *Principal) CanAccess(ownerID sql.NullInt64) bool {
+	return principal.Admin || (ownerID.Valid && ownerID.Int64 == principal.UserID)
+}
+
+// Context key type, to avoid collision with other packages
+type contextKey string
+
+const principalKey contextKey = "principal"
+
+// Get the principal of the current request. Return nil if the request is not authenticated
+func GetPrincipal(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey).(*Principal)
+	return principal
+}
+
+// Return a copy of the context holding the principal. Used by the authentication middleware, and by
+// the command line tools which act without an API key
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// Helper method to extract the API key from the request headers. Both "Authorization: Bearer <key>"
+// and "X-API-Key: <key>" are accepted
+func extractAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, key, found := strings.Cut(auth, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(key)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// Authentication middleware, only applied to the management API (/api/*). Other routes,
+// like the redirect route, stay public
+func (server *Server) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := extractAPIKey(r)
+		if key == "" {
+			server.WriteError(w, http.StatusUnauthorized, ErrorResp{"Missing API key"})
+			return
+		}
+
+		// Check against the bootstrap admin key first, using constant time comparison
+		if server.config.AdminAPIKey != "" &&
+			subtle.ConstantTimeCompare([]byte(key), []byte(server.config.AdminAPIKey)) == 1 {
+			principal := &Principal{Name: "admin", Admin: true}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+			return
+		}
+
+		// Look up the key by its hash
+		apiKey, err := server.store.GetActiveAPIKeyByHash(r.Context(), service.HashAPIKey(key))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				server.WriteError(w, http.StatusUnauthorized, ErrorResp{"Invalid or revoked API key"})
+				return
+			}
+
+			server.logger.Error("Failed to look up API key", "error", err)
+			server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+			return
+		}
+
+		principal := &Principal{
+			KeyID:  apiKey.ID,
+			UserID: apiKey.UserID,
+			Name:   apiKey.UserName,
+			Admin:  apiKey.IsAdmin,
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// Middleware that only allows admin to pass, must be used after AuthMiddleware
+func (server *Server) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := GetPrincipal(r.Context())
+		if principal == nil || !principal.Admin {
+			server.WriteError(w, http.StatusForbidden, ErrorResp{"Admin permission required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	// Handler always responds OK if the request is authenticated
+	handler := server.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		path   string
+		header string
+		value  string
+		status int
+	}{
+		{"/api/urls", "", "", http.StatusUnauthorized},
+		{"/api/urls", "X-API-Key", "usk_invalid", http.StatusUnauthorized},
+		{"/api/urls", "X-API-Key", adminAPIKey, http.StatusOK},
+		{"/api/urls", "Authorization", "Bearer " + adminAPIKey, http.StatusOK},
+		{"/abc", "", "", http.StatusOK}, // Redirect route stays public
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.header != "" {
+			req.Header.Set(c.header, c.value)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, c.status, rr.Code, c.path)
+	}
+}
+
+func TestHandleAPIKey(t *testing.T) {
+	// Helper to wrap the handler with the authentication middlewares, skip the rate limiter
+	withAuth := func(handler http.HandlerFunc) http.Handler {
+		return server.AuthMiddleware(server.AdminMiddleware(handler))
+	}
+
+	// Create a non-admin user
+	user, err := server.store.CreateUser(context.Background(), db.CreateUserParams{
+		Name: fmt.Sprintf("test-user-%d", time.Now().UnixNano()),
+	})
+	require.NoError(t, err)
+
+	// Create an API key for this user using the bootstrap admin key
+	var buffer bytes.Buffer
+	err = json.NewEncoder(&buffer).Encode(createAPIKeyRequest{Name: "test-key", UserID: user.ID})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/keys", &buffer)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	rr := httptest.NewRecorder()
+	withAuth(server.HandleCreateAPIKey).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var apiKey apiKeyResponse
+	err = json.NewDecoder(rr.Body).Decode(&apiKey)
+	require.NoError(t, err)
+	require.NotEmpty(t, apiKey.Key)
+	require.Equal(t, user.ID, apiKey.UserID)
+
+	// A non-admin key cannot manage API keys
+	req = httptest.NewRequest(http.MethodGet, "/api/keys?page_size=5&page_index=1", nil)
+	req.Header.Set("X-API-Key", apiKey.Key)
+	rr = httptest.NewRecorder()
+	withAuth(server.HandleListAPIKey).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+
+	// Revoke the key
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/keys/%d", apiKey.ID), nil)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	req.SetPathValue("id", fmt.Sprint(apiKey.ID))
+	rr = httptest.NewRecorder()
+	withAuth(server.HandleRevokeAPIKey).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+
+	// The revoked key is rejected
+	req = httptest.NewRequest(http.MethodGet, "/api/keys?page_size=5&page_index=1", nil)
+	req.Header.Set("X-API-Key", apiKey.Key)
+	rr = httptest.NewRecorder()
+	withAuth(server.HandleListAPIKey).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// Clean up database (API keys are deleted along with the user)
+	err = server.store.DeleteUser(context.Background(), user.ID)
+	require.NoError(t, err)
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/danglnh07/URLShortener/service"
+	"github.com/danglnh07/URLShortener/store"
+)
+
+// Result of a single URL in a batch create request. Index is the 0-based position of the URL in the request
+type batchItemResult struct {
+	Index      int    `json:"index"`
+	Status     int    `json:"status"`
+	ShortenURL string `json:"shorten_url,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Field      string `json:"field,omitempty"`
+	Code       string `json:"code,omitempty"`
+}
+
+// Response struct for batch create shorten URL action
+type batchCreateResponse struct {
+	Created  int               `json:"created"`
+	Existing int               `json:"existing"`
+	Failed   int               `json:"failed"`
+	Results  []batchItemResult `json:"results"`
+}
+
+// Error sentinel when the batch contains more URLs than allowed
+var errBatchTooLarge = errors.New("batch is too large")
+
+// HandleCreateBatchURL godoc
+//
+// @Summary      Create shortened URLs in batch
+// @Description  Takes a list of create requests, either as a JSON array or as a NDJSON stream (one request per line),
+// @Description  and creates them in one transaction. Each URL is validated the same way as POST /api/urls and gets its
+// @Description  own result: the status code it would have with POST /api/urls, and either the shorten URL or the error.
+// @Description  A rejected URL does not prevent the other URLs from being created.
+// @Description  The whole batch counts as a single request for the rate limiter.
+// @Tags         urls
+// @Accept       json
+// @Accept       application/x-ndjson
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        request body []createShortenURLRequest true "List of original URL requests"
+// @Success      200 {object} batchCreateResponse "Result of each URL, in the request order"
+// @Failure      400 {object} ErrorResp "Invalid input"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      413 {object} ErrorResp "Too many URLs in the batch"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/urls/batch [post]
+func (server *Server) HandleCreateBatchURL(w http.ResponseWriter, r *http.Request) {
+	maxBatchSize := server.maxBatchSize()
+
+	// Parse the request body
+	reqs, err := decodeBatch(r.Body, maxBatchSize)
+	if err != nil {
+		if errors.Is(err, errBatchTooLarge) {
+			server.WriteError(w, http.StatusRequestEntityTooLarge,
+				ErrorResp{fmt.Sprintf("A batch must not contain more than %d URLs", maxBatchSize)})
+			return
+		}
+
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+		return
+	}
+	if len(reqs) == 0 {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{"A batch should not be empty"})
+		return
+	}
+
+	items := make([]batchItem, len(reqs))
+	for i, req := range reqs {
+		items[i].req = req
+	}
+
+	resp, err := server.CreateURLBatch(r.Context(), items)
+	if err != nil {
+		server.logger.Error("POST /api/urls/batch: failed to insert URLs into database", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+	server.logger.Info("Create URL batch successfully",
+		"created", resp.Created, "existing", resp.Existing, "failed", resp.Failed)
+
+	server.WriteJSON(w, http.StatusOK, resp)
+}
+
+// Helper method to get the maximum number of URLs in a batch
+func (server *Server) maxBatchSize() int {
+	if server.config.MaxBatchSize <= 0 {
+		return service.DefaultMaxBatchSize
+	}
+	return server.config.MaxBatchSize
+}
+
+// A single item of a batch create request. err is set if the item could not be parsed
+type batchItem struct {
+	req createShortenURLRequest
+	err *requestError
+}
+
+// Helper method to create a batch of URLs in one transaction, returning the result of each item.
+// Items rejected by the validation or with a taken alias are reported in their result, the other
+// items are still created. Only database errors abort the whole batch
+func (server *Server) CreateURLBatch(ctx context.Context, items []batchItem) (batchCreateResponse, error) {
+	// Validate all URLs before opening the transaction, since the destination policy may resolve host names
+	results := make([]batchItemResult, len(items))
+	resp := batchCreateResponse{Results: results}
+	params := make([]*db.CreateURLParams, len(items))
+	for i, item := range items {
+		results[i].Index = i
+
+		reqErr := item.err
+		if reqErr == nil {
+			var param db.CreateURLParams
+			if param, reqErr = server.PrepareCreateURL(ctx, item.req); reqErr == nil {
+				params[i] = &param
+				continue
+			}
+		}
+
+		results[i].Status = reqErr.Status
+		results[i].Error = reqErr.Message
+		results[i].Field = reqErr.Field
+		results[i].Code = reqErr.Code
+		resp.Failed++
+	}
+
+	// Insert all valid URLs in one transaction. Each URL is inserted inside its own nested transaction, so
+	// that an alias conflict only rolls back that URL
+	err := server.store.ExecTx(ctx, func(tx store.Store) error {
+		for i, item := range items {
+			if params[i] == nil {
+				continue
+			}
+
+			var url db.Url
+			var created bool
+			err := tx.ExecTx(ctx, func(nested store.Store) error {
+				var err error
+				url, created, err = server.CreateURL(ctx, nested, *params[i], item.req.AllowDuplicate)
+				return err
+			})
+			if err != nil {
+				if errors.Is(err, errAliasTaken) {
+					results[i].Status = http.StatusConflict
+					results[i].Error = "This alias has been taken"
+					resp.Failed++
+					continue
+				}
+				return fmt.Errorf("failed to insert item %d: %w", i, err)
+			}
+
+			results[i].ShortenURL = server.GenerateShortenURL(url.ID, url.Alias, url.Code)
+			if created {
+				results[i].Status = http.StatusCreated
+				resp.Created++
+			} else {
+				results[i].Status = http.StatusOK
+				resp.Existing++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return batchCreateResponse{}, err
+	}
+	return resp, nil
+}
+
+// Helper function to decode the batch request body, which is either a JSON array or a stream of JSON
+// objects (NDJSON). The body is decoded item by item, so a too large batch is rejected without reading
+// all of it
+func decodeBatch(body io.Reader, maxBatchSize int) ([]createShortenURLRequest, error) {
+	reader := bufio.NewReader(body)
+
+	// Skip the leading whitespaces to find out the format
+	first, err := peekNonSpace(reader)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, errors.New("Invalid JSON body")
+	}
+
+	decoder := json.NewDecoder(reader)
+	isArray := first == '['
+	if isArray {
+		if _, err := decoder.Token(); err != nil {
+			return nil, errors.New("Invalid JSON body")
+		}
+	}
+
+	reqs := []createShortenURLRequest{}
+	for !isArray || decoder.More() {
+		var req createShortenURLRequest
+		if err := decoder.Decode(&req); err != nil {
+			if !isArray && errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("Invalid JSON body at item %d", len(reqs))
+		}
+
+		if len(reqs) == maxBatchSize {
+			return nil, errBatchTooLarge
+		}
+		reqs = append(reqs, req)
+	}
+
+	// Consume the closing bracket of the array
+	if isArray {
+		if _, err := decoder.Token(); err != nil {
+			return nil, errors.New("Invalid JSON body")
+		}
+	}
+
+	return reqs, nil
+}
+
+// Helper function to return the first non whitespace byte of the reader, without consuming it
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b)) {
+			return b, reader.UnreadByte()
+		}
+	}
+}
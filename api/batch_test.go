@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danglnh07/URLShortener/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleCreateBatchURL(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=batch"
+	alias := fmt.Sprintf("batch-%d", time.Now().UnixNano())
+
+	// Helper to send a batch request and decode the response
+	createBatch := func(body string) (int, batchCreateResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/urls/batch", strings.NewReader(body))
+		req.Header.Set("X-API-Key", adminAPIKey)
+		rr := httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleCreateBatchURL)).ServeHTTP(rr, req)
+
+		var resp batchCreateResponse
+		if rr.Code == http.StatusOK {
+			err := json.NewDecoder(rr.Body).Decode(&resp)
+			require.NoError(t, err)
+		}
+		return rr.Code, resp
+	}
+
+	// JSON array: valid URLs, a rejected URL and an alias conflict inside the same batch
+	status, resp := createBatch(fmt.Sprintf(`[
+		{"url": "%s"},
+		{"url": "javascript:alert(1)"},
+		{"url": "%s", "alias": "%s"},
+		{"url": "%s", "alias": "%s", "allow_duplicate": true}
+	]`, data, data, alias, data, alias))
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, 2, resp.Created)
+	require.Equal(t, 2, resp.Failed)
+	require.Len(t, resp.Results, 4)
+
+	require.Equal(t, http.StatusCreated, resp.Results[0].Status)
+	require.NotEmpty(t, resp.Results[0].ShortenURL)
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Results[1].Status)
+	require.Equal(t, service.URLErrorInvalidScheme, resp.Results[1].Code)
+	require.Equal(t, http.StatusCreated, resp.Results[2].Status)
+	require.True(t, strings.HasSuffix(resp.Results[2].ShortenURL, "/"+alias))
+	require.Equal(t, http.StatusConflict, resp.Results[3].Status)
+	for i, result := range resp.Results {
+		require.Equal(t, i, result.Index)
+	}
+
+	// NDJSON stream: the URL created by the previous batch is returned as is
+	shortenURL := resp.Results[0].ShortenURL
+	status, resp = createBatch(fmt.Sprintf("{\"url\": \"%s\"}\n{\"url\": \"%s/other\"}\n", data, data))
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, 1, resp.Existing)
+	require.Equal(t, 1, resp.Created)
+	require.Equal(t, shortenURL, resp.Results[0].ShortenURL)
+	other := resp.Results[1].ShortenURL
+
+	// Empty, malformed and too large batches are rejected as a whole
+	status, _ = createBatch("[]")
+	require.Equal(t, http.StatusBadRequest, status)
+	status, _ = createBatch(`[{"url": "https://example.com"}, {"url": `)
+	require.Equal(t, http.StatusBadRequest, status)
+	status, _ = createBatch("[" + strings.Repeat(`{"url": "https://example.com"},`, service.DefaultMaxBatchSize) +
+		`{"url": "https://example.com"}]`)
+	require.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	// Clean up database
+	deleteURLs(t, shortenURLCode(t, shortenURL), alias, shortenURLCode(t, other))
+}
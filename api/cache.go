@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+)
+
+// Response struct for the counters of the redirect cache
+type cacheStatsResponse struct {
+	Size         int     `json:"size"`
+	Capacity     int     `json:"capacity"`
+	Hits         uint64  `json:"hits"`
+	NegativeHits uint64  `json:"negative_hits"`
+	Misses       uint64  `json:"misses"`
+	Evictions    uint64  `json:"evictions"`
+	HitRatio     float64 `json:"hit_ratio"` // Share of the lookups answered by the cache, 0 if no lookup yet
+}
+
+// HandleCacheStats godoc
+//
+// @Summary      Get redirect cache counters
+// @Description  Returns the size and the hit/miss counters of the in-process cache of redirect lookups,
+// @Description  since the server started. Negative hits are lookups of unknown codes answered by the cache.
+// @Tags         cache
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200 {object} cacheStatsResponse "Cache counters"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      403 {object} ErrorResp "Admin permission required"
+// @Router       /api/cache [get]
+func (server *Server) HandleCacheStats(w http.ResponseWriter, r *http.Request) {
+	stats := server.cache.Stats()
+
+	resp := cacheStatsResponse{
+		Size:         stats.Size,
+		Capacity:     stats.Capacity,
+		Hits:         stats.Hits,
+		NegativeHits: stats.NegativeHits,
+		Misses:       stats.Misses,
+		Evictions:    stats.Evictions,
+	}
+	if lookups := stats.Hits + stats.NegativeHits + stats.Misses; lookups > 0 {
+		resp.HitRatio = float64(stats.Hits+stats.NegativeHits) / float64(lookups)
+	}
+
+	server.WriteJSON(w, http.StatusOK, resp)
+}
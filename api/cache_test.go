@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/danglnh07/URLShortener/store"
+	"github.com/stretchr/testify/require"
+)
+
+// Store running a hook after each alias lookup, to change the data while a lookup is in progress
+type hookStore struct {
+	store.Store
+	afterGetURLByAlias func()
+}
+
+func (store *hookStore) GetURLByAlias(ctx context.Context, alias sql.NullString) (db.Url, error) {
+	url, err := store.Store.GetURLByAlias(ctx, alias)
+	if hook := store.afterGetURLByAlias; hook != nil {
+		store.afterGetURLByAlias = nil
+		hook()
+	}
+	return url, err
+}
+
+func TestRedirectCache(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=cache"
+	moved := "https://www.youtube.com/watch?v=cache-moved"
+	alias := "cache-test-alias"
+
+	// Server with the redirect cache enabled, sharing the store
+	cacheConfig := config
+	cacheConfig.URLCacheSize = 100
+	cacheConfig.URLCacheTTL = time.Minute
+	cacheConfig.URLCacheNegativeTTL = time.Minute
+	cached := NewServer(&cacheConfig, server.store, logger)
+
+	// Helper to visit the alias
+	redirect := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+alias, nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.Header.Set("User-Agent", "Mozilla/5.0 (test)")
+		req.SetPathValue("code", alias)
+		rr := httptest.NewRecorder()
+		cached.HandleRedirect(rr, req)
+		return rr
+	}
+
+	// Helper to call a management handler with the admin key
+	manage := func(method, body string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/urls/"+alias, bytes.NewBufferString(body))
+		req.Header.Set("X-API-Key", adminAPIKey)
+		req.SetPathValue("id", alias)
+		rr := httptest.NewRecorder()
+		cached.AuthMiddleware(handler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Unknown alias is cached as unknown, until the alias is created
+	require.Equal(t, http.StatusBadRequest, redirect().Code)
+	require.Equal(t, http.StatusBadRequest, redirect().Code)
+	require.Equal(t, uint64(1), cached.cache.Stats().NegativeHits)
+
+	var buffer bytes.Buffer
+	err := json.NewEncoder(&buffer).Encode(createShortenURLRequest{URL: data, Alias: alias})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/api/urls", &buffer)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	rr := httptest.NewRecorder()
+	cached.AuthMiddleware(http.HandlerFunc(cached.HandleCreateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	rr = redirect()
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, data, rr.Header().Get("Location"))
+
+	// Second visit is served from the cache
+	rr = redirect()
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, uint64(1), cached.cache.Stats().Hits)
+
+	// Updating the URL invalidates the cache
+	rr = manage(http.MethodPatch, `{"url": "`+moved+`"}`, cached.HandleUpdateShortenURL)
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr = redirect()
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, moved, rr.Header().Get("Location"))
+
+	// Deleting the URL invalidates the cache
+	rr = manage(http.MethodDelete, "", cached.HandleDeleteShortenURL)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Equal(t, http.StatusGone, redirect().Code)
+
+	// The counters are exposed to admin
+	req = httptest.NewRequest(http.MethodGet, "/api/cache", nil)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	rr = httptest.NewRecorder()
+	cached.AuthMiddleware(cached.AdminMiddleware(http.HandlerFunc(cached.HandleCacheStats))).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var stats cacheStatsResponse
+	err = json.NewDecoder(rr.Body).Decode(&stats)
+	require.NoError(t, err)
+	require.Equal(t, 100, stats.Capacity)
+	require.Equal(t, uint64(1), stats.Hits)
+	require.Equal(t, uint64(1), stats.NegativeHits)
+
+	// Clean up database
+	deleteURLs(t, alias)
+}
+
+func TestRedirectCacheInvalidatedDuringLookup(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=cache-race"
+	moved := "https://www.youtube.com/watch?v=cache-race-moved"
+	alias := "cache-race-alias"
+
+	// Server with the redirect cache enabled, sharing the store through the hook
+	cacheConfig := config
+	cacheConfig.URLCacheSize = 100
+	cacheConfig.URLCacheTTL = time.Minute
+	cacheConfig.URLCacheNegativeTTL = time.Minute
+	storage := &hookStore{Store: server.store}
+	cached := NewServer(&cacheConfig, storage, logger)
+
+	rr := postCreate(t, createShortenURLRequest{URL: data, Alias: alias})
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	// Helper to visit the alias
+	redirect := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+alias, nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.SetPathValue("code", alias)
+		rr := httptest.NewRecorder()
+		cached.HandleRedirect(rr, req)
+		return rr
+	}
+
+	// The URL is updated after the lookup read it, but before the lookup caches it
+	storage.afterGetURLByAlias = func() {
+		req := httptest.NewRequest(http.MethodPatch, "/api/urls/"+alias, bytes.NewBufferString(`{"url": "`+moved+`"}`))
+		req.Header.Set("X-API-Key", adminAPIKey)
+		req.SetPathValue("id", alias)
+		rr := httptest.NewRecorder()
+		cached.AuthMiddleware(http.HandlerFunc(cached.HandleUpdateShortenURL)).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+	rr = redirect()
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, data, rr.Header().Get("Location"))
+
+	// The stale URL was not cached, the next visit gets the new destination
+	_, _, ok := cached.cache.Get(alias)
+	require.False(t, ok)
+	rr = redirect()
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, moved, rr.Header().Get("Location"))
+
+	// Clean up database
+	deleteURLs(t, alias)
+}
@@ -0,0 +1,394 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+)
+
+// Formats supported by the export
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Number of records read from the database at a time when exporting
+const exportChunkSize = 1000
+
+// Record of the export, which can be written as a CSV row or a JSON object
+type exportRecord interface {
+	csvRow() []string
+}
+
+// Record of an URL in the export
+type exportURLRecord struct {
+	ID            int64      `json:"id"`
+	ShortenURL    string     `json:"shorten_url"`
+	OriginalURL   string     `json:"original_url"`
+	Alias         string     `json:"alias,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	MaxClicks     *int32     `json:"max_clicks,omitempty"`
+	FallbackURL   string     `json:"fallback_url,omitempty"`
+	RedirectType  *int32     `json:"redirect_type,omitempty"`
+	Title         string     `json:"title,omitempty"`
+	Preview       bool       `json:"preview"`
+	Interstitial  *int32     `json:"interstitial_seconds,omitempty"`
+	TotalVisitors int64      `json:"total_visitors"`
+	HumanVisitors int64      `json:"human_visitors"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// CSV header of the URL export, in the same order as exportURLRecord.csvRow
+var exportURLHeader = []string{
+	"id", "shorten_url", "original_url", "alias", "expires_at", "max_clicks", "fallback_url", "redirect_type",
+	"title", "preview", "interstitial_seconds", "total_visitors", "human_visitors", "created_at",
+}
+
+func (record exportURLRecord) csvRow() []string {
+	expiresAt, maxClicks, redirectType, interstitial := "", "", "", ""
+	if record.ExpiresAt != nil {
+		expiresAt = record.ExpiresAt.Format(time.RFC3339)
+	}
+	if record.MaxClicks != nil {
+		maxClicks = strconv.Itoa(int(*record.MaxClicks))
+	}
+	if record.RedirectType != nil {
+		redirectType = strconv.Itoa(int(*record.RedirectType))
+	}
+	if record.Interstitial != nil {
+		interstitial = strconv.Itoa(int(*record.Interstitial))
+	}
+
+	return []string{
+		strconv.FormatInt(record.ID, 10),
+		record.ShortenURL,
+		record.OriginalURL,
+		record.Alias,
+		expiresAt,
+		maxClicks,
+		record.FallbackURL,
+		redirectType,
+		record.Title,
+		strconv.FormatBool(record.Preview),
+		interstitial,
+		strconv.FormatInt(record.TotalVisitors, 10),
+		strconv.FormatInt(record.HumanVisitors, 10),
+		record.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// Record of a visitor in the export
+type exportVisitorRecord struct {
+	Ip             string    `json:"ip"`
+	TimeVisited    time.Time `json:"time_visited"`
+	ShortenURL     string    `json:"shorten_url"`
+	OriginalURL    string    `json:"original_url"`
+	Referrer       string    `json:"referrer"`
+	UserAgent      string    `json:"user_agent"`
+	AcceptLanguage string    `json:"accept_language"`
+	Host           string    `json:"host"`
+	QueryString    string    `json:"query_string"`
+	Country        string    `json:"country"`
+	Region         string    `json:"region"`
+	City           string    `json:"city"`
+	ASN            int64     `json:"asn"`
+	ASOrg          string    `json:"as_org"`
+	Browser        string    `json:"browser"`
+	BrowserVersion string    `json:"browser_version"`
+	OS             string    `json:"os"`
+	Device         string    `json:"device"`
+	IsBot          bool      `json:"is_bot"`
+}
+
+// CSV header of the visitor export, in the same order as exportVisitorRecord.csvRow
+var exportVisitorHeader = []string{
+	"ip", "time_visited", "shorten_url", "original_url", "referrer", "user_agent", "accept_language", "host",
+	"query_string", "country", "region", "city", "asn", "as_org", "browser", "browser_version", "os", "device",
+	"is_bot",
+}
+
+func (record exportVisitorRecord) csvRow() []string {
+	return []string{
+		record.Ip,
+		record.TimeVisited.Format(time.RFC3339Nano),
+		record.ShortenURL,
+		record.OriginalURL,
+		record.Referrer,
+		record.UserAgent,
+		record.AcceptLanguage,
+		record.Host,
+		record.QueryString,
+		record.Country,
+		record.Region,
+		record.City,
+		strconv.FormatInt(record.ASN, 10),
+		record.ASOrg,
+		record.Browser,
+		record.BrowserVersion,
+		record.OS,
+		record.Device,
+		strconv.FormatBool(record.IsBot),
+	}
+}
+
+// Writer of the export records in CSV or NDJSON format
+type exportWriter struct {
+	w       io.Writer
+	csv     *csv.Writer // nil if the format is NDJSON
+	encoder *json.Encoder
+}
+
+// Constructor method for exportWriter. The CSV header is written right away
+func newExportWriter(w io.Writer, format string, header []string) (*exportWriter, error) {
+	switch format {
+	case FormatCSV:
+		writer := &exportWriter{w: w, csv: csv.NewWriter(w)}
+		return writer, writer.csv.Write(header)
+	case FormatNDJSON:
+		return &exportWriter{w: w, encoder: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("invalid value for format, must be either %s or %s", FormatCSV, FormatNDJSON)
+	}
+}
+
+// Write a single record
+func (writer *exportWriter) Write(record exportRecord) error {
+	if writer.csv != nil {
+		return writer.csv.Write(record.csvRow())
+	}
+	return writer.encoder.Encode(record)
+}
+
+// Flush the buffered records. If the output is an HTTP response, it is flushed to the client too,
+// so the client receives the records while the export is still running
+func (writer *exportWriter) Flush() error {
+	if writer.csv != nil {
+		writer.csv.Flush()
+		if err := writer.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if flusher, ok := writer.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// Helper function to get the export format from the query parameter, default to CSV
+func extractExportFormat(r *http.Request) (string, error) {
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		return FormatCSV, nil
+	case FormatCSV, FormatNDJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid value for format, must be either %s or %s", FormatCSV, FormatNDJSON)
+	}
+}
+
+// Helper function to write the headers of an export response
+func writeExportHeaders(w http.ResponseWriter, format, name string) {
+	if format == FormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+	w.WriteHeader(http.StatusOK)
+}
+
+// Export all active URLs of an owner (or all URLs if ownerFilter is NULL) into w. The URLs are read
+// from the database in chunks, so the export is not limited in size. Return the number of URLs exported
+func (server *Server) ExportURLs(
+	ctx context.Context, w io.Writer, format string, ownerFilter sql.NullInt64,
+) (int, error) {
+	writer, err := newExportWriter(w, format, exportURLHeader)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	afterID := int64(0)
+	for {
+		urls, err := server.store.ExportURL(ctx, db.ExportURLParams{
+			OwnerID: ownerFilter,
+			AfterID: afterID,
+			Limit:   exportChunkSize,
+		})
+		if err != nil {
+			return total, err
+		}
+
+		for _, url := range urls {
+			err := writer.Write(exportURLRecord{
+				ID:            url.ID,
+				ShortenURL:    server.GenerateShortenURL(url.ID, url.Alias, url.Code),
+				OriginalURL:   url.OriginalUrl,
+				Alias:         url.Alias.String,
+				ExpiresAt:     fromNullTime(url.ExpiresAt),
+				MaxClicks:     fromNullInt32(url.MaxClicks),
+				FallbackURL:   url.FallbackUrl.String,
+				RedirectType:  fromNullInt32(url.RedirectType),
+				Title:         url.Title.String,
+				Preview:       url.Preview,
+				Interstitial:  fromNullInt32(url.InterstitialSeconds),
+				TotalVisitors: url.TotalVisitors,
+				HumanVisitors: url.HumanVisitors,
+				CreatedAt:     url.TimeCreated,
+			})
+			if err != nil {
+				return total, err
+			}
+		}
+		total += len(urls)
+
+		if err := writer.Flush(); err != nil {
+			return total, err
+		}
+		if len(urls) < exportChunkSize {
+			return total, nil
+		}
+		afterID = urls[len(urls)-1].ID
+	}
+}
+
+// Export the whole visitor history of an URL into w, oldest first. The visitors are read from the
+// database in chunks, so the export is not limited in size. Return the number of visitors exported
+func (server *Server) ExportVisitors(ctx context.Context, w io.Writer, format string, url db.Url) (int, error) {
+	writer, err := newExportWriter(w, format, exportVisitorHeader)
+	if err != nil {
+		return 0, err
+	}
+
+	shortenURL := server.GenerateShortenURL(url.ID, url.Alias, url.Code)
+	total := 0
+	after := db.ExportVisitorRow{}
+	for {
+		visitors, err := server.store.ExportVisitor(ctx, db.ExportVisitorParams{
+			UrlID:     url.ID,
+			AfterTime: after.TimeVisited,
+			AfterIp:   after.Ip,
+			Limit:     exportChunkSize,
+		})
+		if err != nil {
+			return total, err
+		}
+
+		for _, visitor := range visitors {
+			err := writer.Write(exportVisitorRecord{
+				Ip:             visitor.Ip,
+				TimeVisited:    visitor.TimeVisited,
+				ShortenURL:     shortenURL,
+				OriginalURL:    url.OriginalUrl,
+				Referrer:       visitor.Referrer,
+				UserAgent:      visitor.UserAgent,
+				AcceptLanguage: visitor.AcceptLanguage,
+				Host:           visitor.Host,
+				QueryString:    visitor.QueryString,
+				Country:        visitor.Country,
+				Region:         visitor.Region,
+				City:           visitor.City,
+				ASN:            visitor.Asn,
+				ASOrg:          visitor.AsOrg,
+				Browser:        visitor.Browser,
+				BrowserVersion: visitor.BrowserVersion,
+				OS:             visitor.Os,
+				Device:         visitor.Device,
+				IsBot:          visitor.IsBot,
+			})
+			if err != nil {
+				return total, err
+			}
+		}
+		total += len(visitors)
+
+		if err := writer.Flush(); err != nil {
+			return total, err
+		}
+		if len(visitors) < exportChunkSize {
+			return total, nil
+		}
+		after = visitors[len(visitors)-1]
+	}
+}
+
+// HandleExportURL godoc
+//
+// @Summary      Export URLs
+// @Description  Exports all the caller's shortened URLs (admin exports all URLs in the system) as CSV or NDJSON.
+// @Description  Unlike GET /api/urls, the export is not paginated: the URLs are streamed from the database.
+// @Tags         urls
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Security     ApiKeyAuth
+// @Param        format query string false "Export format" Enums(csv, ndjson) default(csv)
+// @Success      200 {array} exportURLRecord "Exported URLs, one per line"
+// @Failure      400 {object} ErrorResp "Invalid format"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Router       /api/urls/export [get]
+func (server *Server) HandleExportURL(w http.ResponseWriter, r *http.Request) {
+	format, err := extractExportFormat(r)
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+		return
+	}
+
+	// Once the export has started, errors can only be logged since the status has been sent
+	writeExportHeaders(w, format, "urls")
+	total, err := server.ExportURLs(r.Context(), w, format, GetPrincipal(r.Context()).OwnerFilter())
+	if err != nil {
+		server.logger.Error("GET /api/urls/export: failed to export URLs", "exported", total, "error", err)
+		return
+	}
+	server.logger.Info("Export URLs successfully", "exported", total)
+}
+
+// HandleExportVisitor godoc
+//
+// @Summary      Export visitors of a shortened URL
+// @Description  Exports the whole visitor history of a shortened URL as CSV or NDJSON, oldest first.
+// @Description  Unlike GET /api/urls/{id}/visitors, the export is not paginated: the visitors are streamed
+// @Description  from the database.
+// @Tags         visitors
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Security     ApiKeyAuth
+// @Param        id     path  string true  "Shortened URL code or alias"
+// @Param        format query string false "Export format" Enums(csv, ndjson) default(csv)
+// @Success      200 {array} exportVisitorRecord "Exported visitors, one per line"
+// @Failure      400 {object} ErrorResp "Invalid format"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      404 {object} ErrorResp "URL not found"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/urls/{id}/visitors/export [get]
+func (server *Server) HandleExportVisitor(w http.ResponseWriter, r *http.Request) {
+	format, err := extractExportFormat(r)
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+		return
+	}
+
+	url, ok := server.getVisibleURL(w, r)
+	if !ok {
+		return
+	}
+
+	// Once the export has started, errors can only be logged since the status has been sent
+	writeExportHeaders(w, format, "visitors")
+	total, err := server.ExportVisitors(r.Context(), w, format, url)
+	if err != nil {
+		server.logger.Error("GET /api/urls/{id}/visitors/export: failed to export visitors",
+			"url_id", url.ID, "exported", total, "error", err)
+		return
+	}
+	server.logger.Info("Export visitors successfully", "url_id", url.ID, "exported", total)
+}
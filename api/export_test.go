@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/danglnh07/URLShortener/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportExportURL(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=import"
+	// Back halves of bitlinks are 7 Base62 characters, like 3QxYz7A
+	alias := service.EncodeBase62(56_800_235_584 + time.Now().UnixNano()%1_000_000_000)
+	require.Len(t, alias, 7)
+
+	// Import a Bitly-style export: the back half of the bitlink becomes the alias
+	body := "Bitlink,Long URL,Title,Created\n" +
+		fmt.Sprintf("https://bit.ly/%s,%s,Lofi,2024-01-01\n", alias, data) +
+		"https://bit.ly/xy,ftp://example.com,Invalid,2024-01-01\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/urls/import", strings.NewReader(body))
+	req.Header.Set("X-API-Key", adminAPIKey)
+	rr := httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleImportURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp batchCreateResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	require.NoError(t, err)
+	require.Equal(t, 1, resp.Created)
+	require.Equal(t, 1, resp.Failed)
+	require.True(t, strings.HasSuffix(resp.Results[0].ShortenURL, "/"+alias))
+
+	// A file without URL column is rejected as a whole
+	req = httptest.NewRequest(http.MethodPost, "/api/urls/import", strings.NewReader("name,title\na,b\n"))
+	req.Header.Set("X-API-Key", adminAPIKey)
+	rr = httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleImportURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Files with too many rows or too many bytes are rejected as a whole
+	for _, body := range []string{
+		"url\n" + strings.Repeat("https://www.youtube.com/watch?v=import-limit\n", service.DefaultMaxBatchSize+1),
+		"url,title\nhttps://www.youtube.com/watch?v=import-limit," + strings.Repeat("a", maxImportSize) + "\n",
+	} {
+		req = httptest.NewRequest(http.MethodPost, "/api/urls/import", strings.NewReader(body))
+		req.Header.Set("X-API-Key", adminAPIKey)
+		rr = httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleImportURL)).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	}
+	urls, err := server.store.ListURLByOriginal(context.Background(), db.ListURLByOriginalParams{
+		OriginalUrl: "https://www.youtube.com/watch?v=import-limit",
+	})
+	require.NoError(t, err)
+	require.Empty(t, urls)
+
+	// Helper to export URLs in the given format
+	export := func(format string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/urls/export?format="+format, nil)
+		req.Header.Set("X-API-Key", adminAPIKey)
+		rr := httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleExportURL)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// CSV export contains the imported URL
+	rr = export(FormatCSV)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+
+	rows, err := csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, exportURLHeader, rows[0])
+	found := false
+	for _, row := range rows[1:] {
+		if row[3] == alias {
+			require.Equal(t, data, row[2])
+			found = true
+		}
+	}
+	require.True(t, found)
+
+	// NDJSON export contains the imported URL too
+	rr = export(FormatNDJSON)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	found = false
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		var record exportURLRecord
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		require.NoError(t, err)
+		if record.Alias == alias {
+			found = true
+		}
+	}
+	require.True(t, found)
+
+	// Invalid format is rejected
+	rr = export("xml")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Visit the URL, then export its visitors
+	req = httptest.NewRequest(http.MethodGet, "/"+alias, nil)
+	req.RemoteAddr = "127.0.0.1:12345"
+	req.SetPathValue("code", alias)
+	rr = httptest.NewRecorder()
+	server.HandleRedirect(rr, req)
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/urls/"+alias+"/visitors/export?format=csv", nil)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	req.SetPathValue("id", alias)
+	rr = httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleExportVisitor)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rows, err = csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, exportVisitorHeader, rows[0])
+	require.Equal(t, "127.0.0.1", rows[1][0])
+	require.Equal(t, data, rows[1][3])
+
+	// Clean up database
+	deleteURLs(t, alias)
+}
+
+func TestImportURLChunks(t *testing.T) {
+	data := []string{
+		"https://www.youtube.com/watch?v=import-chunk-1",
+		"ftp://www.youtube.com/watch?v=import-chunk-2",
+		"https://www.youtube.com/watch?v=import-chunk-3",
+	}
+
+	// Without row limit, the rows are inserted in chunks of the batch size
+	chunkConfig := config
+	chunkConfig.MaxBatchSize = 2
+	ctx := WithPrincipal(context.Background(), &Principal{Name: "test", Admin: true})
+	resp, err := NewServer(&chunkConfig, server.store, logger).
+		ImportURLs(ctx, strings.NewReader("url\n"+strings.Join(data, "\n")+"\n"), 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.Created)
+	require.Equal(t, 1, resp.Failed)
+	require.Len(t, resp.Results, len(data))
+	for i, result := range resp.Results {
+		require.Equal(t, i, result.Index)
+	}
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Results[1].Status)
+	require.Equal(t, http.StatusCreated, resp.Results[2].Status)
+
+	// Clean up database
+	deleteURLs(t, shortenURLCode(t, resp.Results[0].ShortenURL), shortenURLCode(t, resp.Results[2].ShortenURL))
+}
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error sentinel when the import file cannot be parsed as a whole
+var errInvalidImport = errors.New("invalid import file")
+
+// Maximum size of the import file sent to the API
+const maxImportSize = 10 << 20
+
+// Columns of the import file, mapped to the field they fill. Both the URL export of this service and
+// Bitly-style exports are accepted. Column names are compared in lowercase, with spaces replaced by "_"
+var importColumns = map[string]string{
+	"url":                  "url",
+	"original_url":         "url",
+	"long_url":             "url",
+	"alias":                "alias",
+	"custom_alias":         "alias",
+	"back_half":            "alias",
+	"expires_at":           "expires_at",
+	"max_clicks":           "max_clicks",
+	"fallback_url":         "fallback_url",
+	"redirect_type":        "redirect_type",
+	"title":                "title",
+	"preview":              "preview",
+	"interstitial_seconds": "interstitial_seconds",
+}
+
+// Columns holding a short link, whose back half is used as alias if the file has no alias column.
+// Custom links are preferred over generated ones
+var importShortLinkColumns = []string{"custom_bitlink", "custom_bitlinks", "bitlink", "short_url", "short_link"}
+
+// Helper function to parse the import CSV file into batch items. Rows with invalid values are reported
+// as failed items, while an invalid file (no URL column, malformed CSV) is rejected as a whole. A file with
+// more than maxRows rows is rejected with errBatchTooLarge, unless maxRows is 0
+func decodeImport(r io.Reader, maxRows int) ([]batchItem, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // Missing trailing cells are treated as empty
+
+	// Find the position of each known column
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: file is empty", errInvalidImport)
+		}
+		return nil, fmt.Errorf("%w: %w", errInvalidImport, err)
+	}
+
+	columns := map[string]int{}
+	names := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff") // Byte order mark written by spreadsheet tools
+		names[i] = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+		if field, ok := importColumns[names[i]]; ok {
+			if _, exists := columns[field]; !exists {
+				columns[field] = i
+			}
+		}
+	}
+	shortLinks := []int{}
+	for _, name := range importShortLinkColumns {
+		if i := slices.Index(names, name); i >= 0 {
+			shortLinks = append(shortLinks, i)
+		}
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, fmt.Errorf("%w: missing url column (url, original_url or long_url)", errInvalidImport)
+	}
+
+	items := []batchItem{}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidImport, err)
+		}
+		if maxRows > 0 && len(items) == maxRows {
+			return nil, errBatchTooLarge
+		}
+
+		// Helper to get the value of a column in this row
+		get := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		item := batchItem{req: createShortenURLRequest{
+			URL:         get("url"),
+			Alias:       get("alias"),
+			FallbackURL: get("fallback_url"),
+			Title:       get("title"),
+		}}
+
+		// Use the back half of the short link as alias, to keep the existing links working
+		if _, ok := columns["alias"]; !ok {
+			for _, i := range shortLinks {
+				if i < len(row) && strings.TrimSpace(row[i]) != "" {
+					item.req.Alias = shortLinkAlias(row[i])
+					break
+				}
+			}
+		}
+
+		if value := get("expires_at"); value != "" {
+			expiresAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				item.err = &requestError{
+					Status:  http.StatusBadRequest,
+					Message: "expires_at must be a RFC 3339 date time",
+				}
+			}
+			item.req.ExpiresAt = &expiresAt
+		}
+		if value := get("max_clicks"); value != "" {
+			maxClicks, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				item.err = &requestError{Status: http.StatusBadRequest, Message: "max_clicks must be an integer"}
+			}
+			clicks := int32(maxClicks)
+			item.req.MaxClicks = &clicks
+		}
+		if value := get("redirect_type"); value != "" {
+			redirectType, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				item.err = &requestError{Status: http.StatusBadRequest, Message: "redirect_type must be an integer"}
+			}
+			status := int32(redirectType)
+			item.req.RedirectType = &status
+		}
+		if value := get("preview"); value != "" {
+			preview, err := strconv.ParseBool(value)
+			if err != nil {
+				item.err = &requestError{Status: http.StatusBadRequest, Message: "preview must be true or false"}
+			}
+			item.req.Preview = preview
+		}
+		if value := get("interstitial_seconds"); value != "" {
+			interstitial, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				item.err = &requestError{
+					Status:  http.StatusBadRequest,
+					Message: "interstitial_seconds must be an integer",
+				}
+			}
+			seconds := int32(interstitial)
+			item.req.InterstitialSeconds = &seconds
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// Helper function to get the back half of a short link, e.g. "abc123" from "https://bit.ly/abc123".
+// A cell can hold several links, only the first one is used
+func shortLinkAlias(link string) string {
+	links := strings.FieldsFunc(link, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if len(links) == 0 {
+		return ""
+	}
+	link, _, _ = strings.Cut(links[0], "?")
+	link = strings.TrimSuffix(link, "/")
+	if i := strings.LastIndex(link, "/"); i >= 0 {
+		return link[i+1:]
+	}
+	return ""
+}
+
+// Import URLs from a CSV file into the database, owned by the principal of the context. The rows are
+// inserted in chunks of the batch size, each chunk in its own transaction, so that a large file does not
+// hold a single transaction. If the database fails, the chunks before are kept. Return errInvalidImport if
+// the file cannot be parsed, or errBatchTooLarge if it has more than maxRows rows (0 for no limit)
+func (server *Server) ImportURLs(ctx context.Context, r io.Reader, maxRows int) (batchCreateResponse, error) {
+	items, err := decodeImport(r, maxRows)
+	if err != nil {
+		return batchCreateResponse{}, err
+	}
+
+	resp := batchCreateResponse{Results: make([]batchItemResult, 0, len(items))}
+	chunkSize := server.maxBatchSize()
+	for start := 0; start < len(items); start += chunkSize {
+		chunk, err := server.CreateURLBatch(ctx, items[start:min(start+chunkSize, len(items))])
+		if err != nil {
+			return resp, fmt.Errorf("failed to import rows from %d: %w", start, err)
+		}
+
+		for _, result := range chunk.Results {
+			result.Index += start
+			resp.Results = append(resp.Results, result)
+		}
+		resp.Created += chunk.Created
+		resp.Existing += chunk.Existing
+		resp.Failed += chunk.Failed
+	}
+	return resp, nil
+}
+
+// HandleImportURL godoc
+//
+// @Summary      Import URLs from CSV
+// @Description  Creates shortened URLs from a CSV file with a header row, of at most MAX_BATCH_SIZE rows and 10 MiB.
+// @Description  Recognized columns: url (or original_url, long_url), alias, expires_at (RFC 3339), max_clicks,
+// @Description  fallback_url, redirect_type, title (as in Bitly exports), preview and interstitial_seconds,
+// @Description  other columns are ignored.
+// @Description  The CSV export of this service can be imported back.
+// @Description  Bitly-style exports are accepted too: without alias column, the back half of the bitlink
+// @Description  (or custom bitlink) column is used as alias, so the existing short links keep working.
+// @Description  Each row gets its own result, the same way as POST /api/urls/batch.
+// @Tags         urls
+// @Accept       text/csv
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        request body string true "CSV file"
+// @Success      200 {object} batchCreateResponse "Result of each row, in the file order"
+// @Failure      400 {object} ErrorResp "Invalid CSV file"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      413 {object} ErrorResp "Too many rows or too large file"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/urls/import [post]
+func (server *Server) HandleImportURL(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	maxRows := server.maxBatchSize()
+	resp, err := server.ImportURLs(r.Context(), r.Body, maxRows)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			server.WriteError(w, http.StatusRequestEntityTooLarge,
+				ErrorResp{fmt.Sprintf("An import file must not be larger than %d bytes", maxBytesErr.Limit)})
+			return
+		}
+		if errors.Is(err, errBatchTooLarge) {
+			server.WriteError(w, http.StatusRequestEntityTooLarge,
+				ErrorResp{fmt.Sprintf("An import file must not contain more than %d rows", maxRows)})
+			return
+		}
+		if errors.Is(err, errInvalidImport) {
+			server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+			return
+		}
+
+		server.logger.Error("POST /api/urls/import: failed to import URLs", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+	server.logger.Info("Import URLs successfully",
+		"created", resp.Created, "existing", resp.Existing, "failed", resp.Failed)
+
+	server.WriteJSON(w, http.StatusOK, resp)
+}
@@ -3,81 +3,87 @@ package api
 import (
 	"fmt"
 	"net/http"
-	"sync"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/danglnh07/URLShortener/service"
 )
 
 // Middleware for CORS
 func (server *Server) CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", fmt.Sprintf("http://%s", server.config.BaseURL))
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Access-Control-Allow-Headers, Authorization, X-Requested-With")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Access-Control-Allow-Headers, Authorization, X-API-Key, X-Requested-With")
+		w.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 
 		next.ServeHTTP(w, r)
 	})
 }
 
-// Rate limiter struct, used Token Bucket strategy
-type RateLimiter struct {
-	tokens     int
-	maxToken   int
-	refillRate time.Duration
-	lastRefill time.Time
-	mutex      sync.Mutex
-}
+// Rate limiting middleware. Every client has its own token bucket, keyed by IP address: the management
+// API (/api/*) and the redirects have their own limits, so that a busy client cannot lock the others out.
+// The API key is not trusted yet at this point, otherwise each made up key would get a fresh bucket, so
+// the management API is limited again per API key once authenticated, see KeyRateLimitMiddleware
+func (server *Server) RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := server.redirectLimiter
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			limiter = server.apiLimiter
+		}
 
-// Constructor method for RateLimiter
-func NewRateLimiter(maxToken int, refillRate time.Duration) *RateLimiter {
-	return &RateLimiter{
-		tokens:     maxToken,
-		maxToken:   maxToken,
-		refillRate: refillRate,
-		lastRefill: time.Now(),
-	}
+		if server.allow(w, limiter, "ip:"+server.clientIP(r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
 }
 
-// Method to check if the current request can pass on, by checking the available token
-// while refill token if needed
-func (limiter *RateLimiter) Allow() bool {
-	// Use mutex to avoid race condition
-	limiter.mutex.Lock()
-	defer limiter.mutex.Unlock()
+// Rate limiting middleware of the authenticated API keys, must be used after AuthMiddleware. Requests
+// without principal (the public routes) are not limited here
+func (server *Server) KeyRateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := GetPrincipal(r.Context())
+		if principal == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-	// Refill token
-	elapsed := time.Since(limiter.lastRefill)
-	refill := int(elapsed / limiter.refillRate)
-	if refill > 0 {
-		limiter.tokens += refill
-		// If tokens exceed max token, we flatten it down
-		if limiter.tokens > limiter.maxToken {
-			limiter.tokens = limiter.maxToken
+		// The bootstrap admin key has no ID
+		key := "key:admin"
+		if principal.KeyID != 0 {
+			key = "key:" + strconv.FormatInt(principal.KeyID, 10)
 		}
-		limiter.lastRefill = time.Now()
-	}
+		if server.allow(w, server.keyLimiter, key) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
 
-	// Consume token
-	if limiter.tokens > 0 {
-		limiter.tokens--
-		return true
+// Helper method to take a token from the bucket of the client. The state of the bucket is reported in
+// the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers (in seconds), and a refused request
+// gets a 429 response with a Retry-After header
+func (server *Server) allow(w http.ResponseWriter, limiter *service.RateLimiter, key string) bool {
+	result := limiter.Allow(key)
+	if result.Limit > 0 {
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
 	}
-
-	// If no token available, simply refuse
-	return false
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		server.WriteError(w, http.StatusTooManyRequests, ErrorResp{"Too many request at a time"})
+		return false
+	}
+	return true
 }
 
-// Rate limiting middleware
-func (server *Server) RateLimitMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !server.limiter.Allow() {
-			server.WriteError(w, http.StatusTooManyRequests, ErrorResp{"Too many request at a time"})
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+// Helper to round a duration up to whole seconds, for the rate limit headers
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
 }
 
-// Chaining middleware, to avoid duplicate code
+// Chaining middleware, to avoid duplicate code. Authentication only applies to /api/* routes
 func (server *Server) ChainingMiddleware(next http.Handler) http.Handler {
-	return server.CORSMiddleware(server.RateLimitMiddleware(next))
+	return server.CORSMiddleware(server.RateLimitMiddleware(server.AuthMiddleware(server.KeyRateLimitMiddleware(next))))
 }
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestRateLimiterStress(t *testing.T) {
@@ -18,8 +23,7 @@ func TestRateLimiterStress(t *testing.T) {
 
 	client := &http.Client{}
 	var wg sync.WaitGroup
-	success := 0
-	tooMany := 0
+	var success, tooMany atomic.Int32
 	total := 50
 
 	for range total {
@@ -35,9 +39,9 @@ func TestRateLimiterStress(t *testing.T) {
 
 			switch resp.StatusCode {
 			case http.StatusOK:
-				success++
+				success.Add(1)
 			case http.StatusTooManyRequests:
-				tooMany++
+				tooMany.Add(1)
 			default:
 				t.Errorf("unexpected status: %d", resp.StatusCode)
 			}
@@ -46,9 +50,78 @@ func TestRateLimiterStress(t *testing.T) {
 
 	wg.Wait()
 
-	t.Logf("Success=%d, TooMany=%d", success, tooMany)
+	t.Logf("Success=%d, TooMany=%d", success.Load(), tooMany.Load())
+
+	if success.Load() > 5 {
+		t.Errorf("Limiter failed: expected at most 5 OK responses, got %d", success.Load())
+	}
+}
+
+func TestRateLimitPerClient(t *testing.T) {
+	limited := NewServer(&config, server.store, logger)
+	handler := limited.ChainingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Helper to send a request from the given IP address, with an optional API key
+	send := func(path, ip, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":12345"
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The first client uses up its redirects, the headers count down
+	for i := range config.RedirectMaxRequest {
+		rr := send("/code", "10.0.0.1", "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "5", rr.Header().Get("RateLimit-Limit"))
+		require.Equal(t, strconv.Itoa(config.RedirectMaxRequest-i-1), rr.Header().Get("RateLimit-Remaining"))
+		require.NotEmpty(t, rr.Header().Get("RateLimit-Reset"))
+	}
+	rr := send("/code", "10.0.0.1", "")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "10", rr.Header().Get("Retry-After"))
+
+	// Other clients are not affected
+	rr = send("/code", "10.0.0.2", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// The management API has its own buckets. Before authentication, they are keyed by IP address, so that
+	// made up keys do not get fresh buckets
+	rr = send("/api/urls", "10.0.0.1", adminAPIKey)
+	require.Equal(t, http.StatusOK, rr.Code)
+	for i := range config.MaxRequest {
+		rr = send("/api/urls", "10.0.0.3", fmt.Sprintf("made-up-key-%d", i))
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+	}
+	rr = send("/api/urls", "10.0.0.3", adminAPIKey)
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
 
-	if success > 5 {
-		t.Errorf("Limiter failed: expected at most 5 OK responses, got %d", success)
+	// Once authenticated, each API key has its own bucket, wherever its requests come from
+	for i := range config.MaxRequest - 1 {
+		rr = send("/api/urls", fmt.Sprintf("10.0.1.%d", i), adminAPIKey)
+		require.Equal(t, http.StatusOK, rr.Code)
 	}
+	rr = send("/api/urls", "10.0.0.4", adminAPIKey)
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/code", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+	// The X-Forwarded-For header is only trusted behind a proxy
+	require.Equal(t, "203.0.113.7", server.clientIP(req))
+
+	untrusted := config
+	untrusted.TrustForwardedFor = false
+	require.Equal(t, "10.0.0.1", NewServer(&untrusted, server.store, logger).clientIP(req))
 }
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Optional is a field of a PATCH request body, which distinguishes between an absent field
+// (keep the current value), an explicit null (clear the value) and a new value
+type Optional[T any] struct {
+	Set   bool // The field is present in the request body
+	Null  bool // The field is explicitly set to null
+	Value T
+}
+
+// Implement json.Unmarshaler. This method is only called when the field is present
+func (optional *Optional[T]) UnmarshalJSON(data []byte) error {
+	optional.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		optional.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &optional.Value)
+}
+
+// Helper method to check if the field holds a new value
+func (optional Optional[T]) HasValue() bool {
+	return optional.Set && !optional.Null
+}
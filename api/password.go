@@ -0,0 +1,120 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/danglnh07/URLShortener/service"
+)
+
+// Name of the cookie granting access to a password-protected URL. The cookie is scoped to the path of the
+// short code, so every URL has its own
+const linkAccessCookie = "link_access"
+
+// Maximum size of the password form body
+const maxPasswordFormSize = 4096
+
+// Password form of the protected URLs, submitted to the same address
+var passwordFormTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body>
+<form method="post">
+<p>This link is protected by a password.</p>
+{{if .}}<p role="alert">{{.}}</p>
+{{end}}<input type="password" name="password" aria-label="Password" autocomplete="current-password" required autofocus>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// Helper method to write the password form of a protected URL, with an optional error message
+func (server *Server) WritePasswordForm(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := passwordFormTemplate.Execute(w, message); err != nil {
+		server.logger.Error("Failed to write the password form", "error", err)
+	}
+}
+
+// Check if the request holds a valid access cookie for the password-protected URL
+func (server *Server) HasLinkAccess(r *http.Request, url db.Url) bool {
+	cookie, err := r.Cookie(linkAccessCookie)
+	if err != nil {
+		return false
+	}
+	return service.VerifyLinkAccess(server.linkSecret, url.ID, url.PasswordHash.String, cookie.Value, time.Now())
+}
+
+// HandleUnlockURL godoc
+//
+// @Summary      Enter the password of a protected URL
+// @Description  Checks the password entered in the form served by GET /{code}. If it matches, a signed
+// @Description  cookie grants access to the URL for a while, and the visitor is sent back to GET /{code}
+// @Description  to be redirected. The number of attempts is limited per URL to slow down guessing.
+// @Tags         urls
+// @Accept       x-www-form-urlencoded
+// @Produce      html
+// @Param        code path string true "Shortened URL code or alias"
+// @Param        password formData string true "Password of the URL"
+// @Success      303 {string} string "Password accepted, redirected to GET /{code}"
+// @Failure      400 {object} ErrorResp "Invalid code or URL not found"
+// @Failure      401 {string} string "Wrong password, the form is served again"
+// @Failure      410 {object} goneResp "URL has been deleted or has expired"
+// @Failure      429 {string} string "Too many attempts for this URL, the form is served again"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /{code} [post]
+func (server *Server) HandleUnlockURL(w http.ResponseWriter, r *http.Request) {
+	link, ok := server.getRedirectURL(w, r)
+	if !ok {
+		return
+	}
+	target := "/" + url.PathEscape(r.PathValue("code"))
+
+	// Nothing to unlock
+	if !link.PasswordHash.Valid {
+		http.Redirect(w, r, target, http.StatusSeeOther)
+		return
+	}
+
+	// Throttle the attempts of each URL, whoever makes them
+	result := server.passwordLimiter.Allow(strconv.FormatInt(link.ID, 10))
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		server.WritePasswordForm(w, http.StatusTooManyRequests, "Too many attempts, please try again later.")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasswordFormSize)
+	if !service.CheckLinkPassword(link.PasswordHash.String, r.PostFormValue("password")) {
+		server.logger.Info("Wrong password for protected URL", "url_id", link.ID, "IP", server.clientIP(r))
+		server.WritePasswordForm(w, http.StatusUnauthorized, "Wrong password.")
+		return
+	}
+
+	// Grant access to the URL, then let GET /{code} redirect the visitor
+	expires := time.Now().Add(server.config.LinkAccessTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     linkAccessCookie,
+		Value:    service.SignLinkAccess(server.linkSecret, link.ID, link.PasswordHash.String, expires),
+		Path:     target,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordProtectedURL(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=password"
+
+	// Create a password-protected shorten URL
+	var buffer bytes.Buffer
+	err := json.NewEncoder(&buffer).Encode(createShortenURLRequest{URL: data, Password: "secret"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/urls", &buffer)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	rr := httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleCreateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var shortenURL createShortenURLResponse
+	err = json.NewDecoder(rr.Body).Decode(&shortenURL)
+	require.NoError(t, err)
+	u, err := url.Parse(shortenURL.ShortenURL)
+	require.NoError(t, err)
+	code := strings.TrimPrefix(u.Path, "/")
+
+	// Helpers to visit the URL and to submit the password form
+	visit := func(cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.SetPathValue("code", code)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		server.HandleRedirect(rr, req)
+		return rr
+	}
+	unlock := func(password string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {password}}
+		req := httptest.NewRequest(http.MethodPost, "/"+code, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.SetPathValue("code", code)
+		rr := httptest.NewRecorder()
+		server.HandleUnlockURL(rr, req)
+		return rr
+	}
+
+	// Without access, the password form is served and no visit is recorded
+	rr = visit()
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, rr.Body.String(), `name="password"`)
+
+	// A wrong password is refused
+	rr = unlock("wrong")
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	require.Contains(t, rr.Body.String(), "Wrong password")
+	require.Empty(t, rr.Result().Cookies())
+
+	// The right password grants access through a cookie scoped to the short code
+	rr = unlock("secret")
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	require.Equal(t, "/"+code, rr.Header().Get("Location"))
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, "/"+code, cookies[0].Path)
+	require.True(t, cookies[0].HttpOnly)
+
+	rr = visit(cookies[0])
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, data, rr.Header().Get("Location"))
+	require.Equal(t, "private, no-store", rr.Header().Get("Cache-Control"))
+
+	// A forged cookie is refused
+	rr = visit(&http.Cookie{Name: linkAccessCookie, Value: cookies[0].Value + "0"})
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// The attempts are throttled per URL, even with the right password
+	require.Equal(t, http.StatusUnauthorized, unlock("wrong").Code)
+	rr = unlock("wrong")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.NotEmpty(t, rr.Header().Get("Retry-After"))
+	require.Equal(t, http.StatusTooManyRequests, unlock("secret").Code)
+
+	// Changing the password revokes the access already given
+	req = httptest.NewRequest(http.MethodPatch, "/api/urls/"+code, strings.NewReader(`{"password": "new"}`))
+	req.Header.Set("X-API-Key", adminAPIKey)
+	req.SetPathValue("id", code)
+	rr = httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleUpdateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var updated urlResponse
+	err = json.NewDecoder(rr.Body).Decode(&updated)
+	require.NoError(t, err)
+	require.True(t, updated.Protected)
+	require.Equal(t, http.StatusOK, visit(cookies[0]).Code)
+
+	// Removing the password makes the URL public again
+	req = httptest.NewRequest(http.MethodPatch, "/api/urls/"+code, strings.NewReader(`{"password": null}`))
+	req.Header.Set("X-API-Key", adminAPIKey)
+	req.SetPathValue("id", code)
+	rr = httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleUpdateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, http.StatusMovedPermanently, visit().Code)
+
+	// Clean up database
+	deleteURLs(t, code)
+}
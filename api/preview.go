@@ -0,0 +1,160 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+)
+
+// Limits of the preview and interstitial settings of an URL
+const (
+	maxTitleLength         = 200
+	maxInterstitialSeconds = 30
+)
+
+// Preview page of an URL, served at /{code}+ instead of redirecting
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Preview of {{.ShortenURL}}</title>
+</head>
+<body>
+<h1>{{if .Title}}{{.Title}}{{else}}{{.ShortenURL}}{{end}}</h1>
+<dl>
+<dt>Short link</dt><dd>{{.ShortenURL}}</dd>
+<dt>Destination</dt><dd>{{if .Protected}}Hidden, this link is protected by a password{{else}}{{.Destination}}{{end}}</dd>
+<dt>Created</dt><dd><time datetime="{{.CreatedAt.Format "2006-01-02T15:04:05Z07:00"}}">{{.CreatedAt.Format "January 2, 2006"}}</time></dd>
+<dt>Clicks</dt><dd>{{.Clicks}}</dd>
+</dl>
+<p><a href="{{.ShortenURL}}" rel="noreferrer">Continue to the destination</a></p>
+</body>
+</html>
+`))
+
+// Data of the preview page
+type previewPage struct {
+	ShortenURL  string
+	Destination string
+	Title       string
+	Protected   bool // The destination is hidden behind the password
+	CreatedAt   time.Time
+	Clicks      int64
+}
+
+// Interstitial page of an URL, redirecting to the destination when the countdown ends. The countdown
+// script is only cosmetic, the refresh happens without it
+var interstitialTemplate = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<meta http-equiv="refresh" content="{{.Seconds}};url={{.Destination}}">
+<title>{{if .Title}}{{.Title}}{{else}}Redirecting{{end}}</title>
+</head>
+<body>
+{{if .Title}}<h1>{{.Title}}</h1>
+{{end}}<p>You will be redirected to {{.Destination}} in <span id="countdown">{{.Seconds}}</span> seconds.</p>
+<p><a href="{{.Destination}}">Continue now</a></p>
+<script>
+(function () {
+  var element = document.getElementById("countdown"), seconds = {{.Seconds}};
+  var timer = setInterval(function () {
+    element.textContent = seconds > 0 ? --seconds : 0;
+    if (seconds <= 0) clearInterval(timer);
+  }, 1000);
+})();
+</script>
+</body>
+</html>
+`))
+
+// Data of the interstitial page
+type interstitialPage struct {
+	Destination string
+	Title       string
+	Seconds     int32
+}
+
+// Helper method to write an HTML page of an URL. These pages are never cached, since they are built
+// from the current state of the URL
+func (server *Server) writePage(w http.ResponseWriter, tmpl *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusOK)
+	if err := tmpl.Execute(w, data); err != nil {
+		server.logger.Error("Failed to write the page", "page", tmpl.Name(), "error", err)
+	}
+}
+
+// Helper method to write the interstitial page of an URL instead of redirecting
+func (server *Server) WriteInterstitial(w http.ResponseWriter, url db.Url) {
+	server.writePage(w, interstitialTemplate, interstitialPage{
+		Destination: url.OriginalUrl,
+		Title:       url.Title.String,
+		Seconds:     url.InterstitialSeconds.Int32,
+	})
+}
+
+// HandlePreview godoc
+//
+// @Summary      Preview a shortened URL
+// @Description  Shows the destination, title, creation date and number of clicks (bots excluded) of a
+// @Description  shortened URL instead of redirecting, so that visitors can check where it leads. Only
+// @Description  available for the URLs with preview enabled, unless the server previews every URL.
+// @Description  The destination of a password-protected URL is hidden.
+// @Description  Previews are not recorded as visits.
+// @Tags         urls
+// @Produce      html
+// @Param        code path string true "Shortened URL code or alias, followed by +"
+// @Success      200 {string} string "HTML preview page"
+// @Failure      400 {object} ErrorResp "Invalid code or URL not found"
+// @Failure      404 {object} ErrorResp "Preview not enabled for this URL"
+// @Failure      410 {object} goneResp "URL has been deleted or has expired"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /{code}+ [get]
+func (server *Server) HandlePreview(w http.ResponseWriter, r *http.Request) {
+	r.SetPathValue("code", strings.TrimSuffix(r.PathValue("code"), "+"))
+
+	url, ok := server.getRedirectURL(w, r)
+	if !ok {
+		return
+	}
+
+	if !url.Preview && !server.config.PreviewAllURLs {
+		server.WriteError(w, http.StatusNotFound, ErrorResp{"Preview is not enabled for this URL"})
+		return
+	}
+
+	summary, err := server.store.GetVisitorSummary(r.Context(), db.GetVisitorSummaryParams{
+		UrlID:    url.ID,
+		FromTime: url.TimeCreated,
+		ToTime:   time.Now(),
+	})
+	if err != nil {
+		server.logger.Error("GET /{code}+: failed to count the clicks", "url_id", url.ID, "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+
+	server.writePage(w, previewTemplate, previewPage{
+		ShortenURL:  server.GenerateShortenURL(url.ID, url.Alias, url.Code),
+		Destination: url.OriginalUrl,
+		Title:       url.Title.String,
+		Protected:   url.PasswordHash.Valid,
+		CreatedAt:   url.TimeCreated,
+		Clicks:      summary.HumanClicks,
+	})
+}
+
+// Check if the path of the redirect route is the preview page of a short code, /{code}+
+func isPreviewPath(code string) bool {
+	return strings.HasSuffix(code, "+")
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewAndInterstitial(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=preview"
+
+	// Create a shorten URL with a preview page and an interstitial page
+	seconds := int32(3)
+	var buffer bytes.Buffer
+	err := json.NewEncoder(&buffer).Encode(createShortenURLRequest{
+		URL:                 data,
+		Title:               "Lofi <music>",
+		Preview:             true,
+		InterstitialSeconds: &seconds,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/urls", &buffer)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	rr := httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleCreateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var shortenURL createShortenURLResponse
+	err = json.NewDecoder(rr.Body).Decode(&shortenURL)
+	require.NoError(t, err)
+	u, err := url.Parse(shortenURL.ShortenURL)
+	require.NoError(t, err)
+	code := strings.TrimPrefix(u.Path, "/")
+
+	// Helpers to visit and update the URL
+	visit := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+path, nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.Header.Set("User-Agent", "Mozilla/5.0 (test)")
+		req.SetPathValue("code", path)
+		rr := httptest.NewRecorder()
+		server.HandleRedirect(rr, req)
+		return rr
+	}
+	update := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/urls/"+code, strings.NewReader(body))
+		req.Header.Set("X-API-Key", adminAPIKey)
+		req.SetPathValue("id", code)
+		rr := httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleUpdateShortenURL)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The preview page shows the URL without recording a visit
+	rr = visit(code + "+")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+	require.Equal(t, "private, no-store", rr.Header().Get("Cache-Control"))
+	require.Contains(t, rr.Body.String(), "Lofi &lt;music&gt;")
+	require.Contains(t, rr.Body.String(), data)
+	require.Contains(t, rr.Body.String(), "<dt>Clicks</dt><dd>0</dd>")
+
+	// The visit is recorded, then the interstitial page counts down before the redirect
+	rr = visit(code)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "private, no-store", rr.Header().Get("Cache-Control"))
+	require.Contains(t, rr.Body.String(), `<meta http-equiv="refresh" content="3;url=`+data+`">`)
+
+	rr = visit(code + "+")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "<dt>Clicks</dt><dd>1</dd>")
+
+	// Invalid settings are rejected
+	require.Equal(t, http.StatusBadRequest, update(`{"interstitial_seconds": 0}`).Code)
+	require.Equal(t, http.StatusBadRequest, update(`{"interstitial_seconds": 31}`).Code)
+	require.Equal(t, http.StatusBadRequest, update(`{"title": "`+strings.Repeat("a", 201)+`"}`).Code)
+
+	// Without interstitial page, the visitors are redirected directly
+	rr = update(`{"interstitial_seconds": null, "preview": false}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var updated urlResponse
+	err = json.NewDecoder(rr.Body).Decode(&updated)
+	require.NoError(t, err)
+	require.Nil(t, updated.Interstitial)
+	require.False(t, updated.Preview)
+	require.Equal(t, "Lofi <music>", updated.Title)
+
+	rr = visit(code)
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, data, rr.Header().Get("Location"))
+
+	// The preview page is opt-in, per URL or for every URL
+	require.Equal(t, http.StatusNotFound, visit(code+"+").Code)
+
+	server.config.PreviewAllURLs = true
+	defer func() { server.config.PreviewAllURLs = false }()
+	require.Equal(t, http.StatusOK, visit(code+"+").Code)
+	require.Equal(t, http.StatusBadRequest, visit("unknown-code+").Code)
+
+	// Clean up database
+	deleteURLs(t, code)
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danglnh07/URLShortener/service"
+)
+
+// Maximum time the public QR codes can be cached. The alias of an URL can change, and with it the shorten
+// URL in the image, so it is kept short
+const publicQRCodeMaxAge = 5 * time.Minute
+
+// Helper method to extract the QR code options from the query parameters, starting from the defaults
+func (server *Server) ExtractQROptions(r *http.Request, format string) (service.QROptions, error) {
+	options := service.DefaultQROptions()
+	options.Format = format
+	params := r.URL.Query()
+
+	var err error
+	if value := params.Get("size"); value != "" {
+		if options.Size, err = strconv.Atoi(value); err != nil {
+			return options, fmt.Errorf("invalid value for size: %v", err)
+		}
+	}
+	if value := params.Get("level"); value != "" {
+		options.Level = strings.ToUpper(value)
+	}
+	if value := params.Get("margin"); value != "" {
+		if options.Margin, err = strconv.Atoi(value); err != nil {
+			return options, fmt.Errorf("invalid value for margin: %v", err)
+		}
+	}
+	if value := params.Get("fg"); value != "" {
+		if options.Foreground, err = service.ParseQRColor(value); err != nil {
+			return options, fmt.Errorf("invalid value for fg: %v", err)
+		}
+	}
+	if value := params.Get("bg"); value != "" {
+		if options.Background, err = service.ParseQRColor(value); err != nil {
+			return options, fmt.Errorf("invalid value for bg: %v", err)
+		}
+	}
+
+	return options, options.Validate()
+}
+
+// Helper method to render the QR code of a shorten URL and write it as the response, with the given
+// Cache-Control header
+func (server *Server) WriteQRCode(
+	w http.ResponseWriter,
+	r *http.Request,
+	shortenURL, format, cacheControl string,
+) {
+	options, err := server.ExtractQROptions(r, format)
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+		return
+	}
+
+	image, err := service.RenderQRCode(shortenURL, options)
+	if err != nil {
+		// The options are valid, but the image is too small for the code
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+		return
+	}
+
+	contentType := "image/png"
+	if format == service.QRFormatSVG {
+		contentType = "image/svg+xml"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(image)))
+	w.Header().Set("Cache-Control", cacheControl)
+	w.WriteHeader(http.StatusOK)
+	w.Write(image)
+}
+
+// HandleQRCode godoc
+//
+// @Summary      Get the QR code of a shortened URL
+// @Description  Renders the shortened URL (with its alias if it has one) as a QR code, in PNG or SVG.
+// @Description  The same image is publicly available at /{code}.png and /{code}.svg.
+// @Tags         urls
+// @Produce      png
+// @Produce      image/svg+xml
+// @Security     ApiKeyAuth
+// @Param        id     path  string true  "ID (Base62 code) or alias of the URL"
+// @Param        format query string false "Image format" Enums(png, svg) default(png)
+// @Param        size   query int    false "Width and height of the image in pixels" minimum(64) maximum(2048) default(256)
+// @Param        level  query string false "Error correction level" Enums(L, M, Q, H) default(M)
+// @Param        margin query int    false "Quiet zone around the code, in modules" minimum(0) maximum(32) default(4)
+// @Param        fg     query string false "Foreground color, RRGGBB or RRGGBBAA" default(000000)
+// @Param        bg     query string false "Background color, RRGGBB or RRGGBBAA" default(ffffff)
+// @Success      200 {file} binary "QR code image"
+// @Failure      400 {object} ErrorResp "Invalid options"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      404 {object} ErrorResp "URL not found"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/urls/{id}/qr [get]
+func (server *Server) HandleQRCode(w http.ResponseWriter, r *http.Request) {
+	url, ok := server.getManagedURL(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = service.QRFormatPNG
+	}
+	// Only the owner can get this image, it must not be stored by shared caches
+	server.WriteQRCode(w, r, server.GenerateShortenURL(url.ID, url.Alias, url.Code), format, "private, no-store")
+}
+
+// HandlePublicQRCode godoc
+//
+// @Summary      Get the QR code of a short code
+// @Description  Renders the shortened URL of the code as a QR code, with the same options as
+// @Description  GET /api/urls/{id}/qr.
+// @Description  Password-protected URLs have a QR code too, which leads to their password form.
+// @Tags         urls
+// @Produce      png
+// @Produce      image/svg+xml
+// @Param        code   path  string true  "Shortened URL code or alias"
+// @Param        format path  string true  "Image format" Enums(png, svg)
+// @Param        size   query int    false "Width and height of the image in pixels" minimum(64) maximum(2048) default(256)
+// @Param        level  query string false "Error correction level" Enums(L, M, Q, H) default(M)
+// @Param        margin query int    false "Quiet zone around the code, in modules" minimum(0) maximum(32) default(4)
+// @Param        fg     query string false "Foreground color, RRGGBB or RRGGBBAA" default(000000)
+// @Param        bg     query string false "Background color, RRGGBB or RRGGBBAA" default(ffffff)
+// @Success      200 {file} binary "QR code image"
+// @Failure      400 {object} ErrorResp "Invalid options, invalid code or URL not found"
+// @Failure      410 {object} goneResp "URL has been deleted or has expired"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /{code}.{format} [get]
+func (server *Server) HandlePublicQRCode(w http.ResponseWriter, r *http.Request) {
+	// Codes never contain a dot, the extension is the format
+	code, format, _ := strings.Cut(r.PathValue("code"), ".")
+	r.SetPathValue("code", code)
+
+	url, ok := server.getRedirectURL(w, r)
+	if !ok {
+		return
+	}
+	server.WriteQRCode(w, r, server.GenerateShortenURL(url.ID, url.Alias, url.Code), format,
+		fmt.Sprintf("public, max-age=%d", int(publicQRCodeMaxAge.Seconds())))
+}
+
+// Check if the path of the redirect route is the public QR code of a short code, /{code}.png or /{code}.svg
+func isQRCodePath(code string) bool {
+	return strings.HasSuffix(code, "."+service.QRFormatPNG) || strings.HasSuffix(code, "."+service.QRFormatSVG)
+}
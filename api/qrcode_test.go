@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQRCode(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=qrcode"
+	alias := "qr-code-test"
+
+	// Create a shorten URL with an alias
+	var buffer bytes.Buffer
+	err := json.NewEncoder(&buffer).Encode(createShortenURLRequest{URL: data, Alias: alias})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/urls", &buffer)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	rr := httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleCreateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	// Helpers to get the QR code from the management API and from the public route
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/urls/"+alias+"/qr?"+query, nil)
+		req.Header.Set("X-API-Key", adminAPIKey)
+		req.SetPathValue("id", alias)
+		rr := httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleQRCode)).ServeHTTP(rr, req)
+		return rr
+	}
+	getPublic := func(code, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+code+"?"+query, nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.SetPathValue("code", code)
+		rr := httptest.NewRecorder()
+		server.HandleRedirect(rr, req)
+		return rr
+	}
+
+	// PNG of the requested size by default
+	rr = get("size=128")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	require.Equal(t, "private, no-store", rr.Header().Get("Cache-Control"))
+	img, err := png.Decode(rr.Body)
+	require.NoError(t, err)
+	require.Equal(t, 128, img.Bounds().Dx())
+
+	// SVG with custom colors
+	rr = get("format=svg&level=h&margin=2&fg=%23112233&bg=ffffff00")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+	require.True(t, strings.HasPrefix(rr.Body.String(), "<svg"))
+	require.Contains(t, rr.Body.String(), `fill="#112233"`)
+
+	// Invalid options are rejected
+	for _, query := range []string{"format=gif", "size=abc", "size=10", "level=X", "margin=-1", "fg=red"} {
+		require.Equal(t, http.StatusBadRequest, get(query).Code, query)
+	}
+
+	// The public route serves the same image, without recording a visit. It is only cached for a short time,
+	// since the alias can change
+	rr = getPublic(alias+".png", "size=128")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	require.Equal(t, "public, max-age=300", rr.Header().Get("Cache-Control"))
+
+	rr = getPublic(alias+".svg", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+
+	require.Equal(t, http.StatusBadRequest, getPublic("unknown-code.png", "").Code)
+
+	url, err := server.store.GetURLByAlias(context.Background(), sql.NullString{String: alias, Valid: true})
+	require.NoError(t, err)
+	visitors, err := server.store.CountVisitorForUpdate(context.Background(), url.ID)
+	require.NoError(t, err)
+	require.Zero(t, visitors)
+
+	// Clean up database
+	deleteURLs(t, alias)
+}
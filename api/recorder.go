@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+)
+
+// VisitorRecorder records visitors asynchronously: visitors are put in a bounded in-memory queue, drained
+// by background workers that insert them in batches. A batch is inserted when it reaches the batch size,
+// or at every flush interval if it is not empty. If the queue is full, the visitor is dropped instead of
+// slowing down the redirect
+type VisitorRecorder struct {
+	queue         chan db.CreateVisitorParams
+	batchSize     int
+	flushInterval time.Duration
+	insert        func(ctx context.Context, visitors []db.CreateVisitorParams) error
+	logger        *slog.Logger
+
+	wg       sync.WaitGroup
+	closing  sync.Once
+	recorded atomic.Uint64 // Visitors inserted
+	dropped  atomic.Uint64 // Visitors dropped because the queue was full
+	failed   atomic.Uint64 // Visitors lost because their batch failed to insert
+}
+
+// Constructor method for VisitorRecorder. The insert function is called by the workers with each batch
+func NewVisitorRecorder(
+	queueSize, batchSize int, flushInterval time.Duration,
+	insert func(ctx context.Context, visitors []db.CreateVisitorParams) error, logger *slog.Logger,
+) *VisitorRecorder {
+	return &VisitorRecorder{
+		queue:         make(chan db.CreateVisitorParams, queueSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		insert:        insert,
+		logger:        logger,
+	}
+}
+
+// Start the background workers
+func (recorder *VisitorRecorder) Start(workers int) {
+	for range workers {
+		recorder.wg.Add(1)
+		go recorder.work()
+	}
+}
+
+// Put a visitor in the queue without blocking. Return false if the queue is full and the visitor is dropped
+func (recorder *VisitorRecorder) Record(visitor db.CreateVisitorParams) bool {
+	select {
+	case recorder.queue <- visitor:
+		return true
+	default:
+		recorder.dropped.Add(1)
+		return false
+	}
+}
+
+// Stop accepting visitors and wait for the workers to insert all the queued visitors, or until the
+// context is done. Record must not be called after Close
+func (recorder *VisitorRecorder) Close(ctx context.Context) error {
+	recorder.closing.Do(func() { close(recorder.queue) })
+
+	done := make(chan struct{})
+	go func() {
+		recorder.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Counters of the recorder since it was created
+type RecorderStats struct {
+	Queued   int
+	Capacity int
+	Recorded uint64
+	Dropped  uint64
+	Failed   uint64
+}
+
+// Get the counters of the recorder
+func (recorder *VisitorRecorder) Stats() RecorderStats {
+	return RecorderStats{
+		Queued:   len(recorder.queue),
+		Capacity: cap(recorder.queue),
+		Recorded: recorder.recorded.Load(),
+		Dropped:  recorder.dropped.Load(),
+		Failed:   recorder.failed.Load(),
+	}
+}
+
+// Worker loop, collecting visitors from the queue into batches until the queue is closed and empty
+func (recorder *VisitorRecorder) work() {
+	defer recorder.wg.Done()
+
+	ticker := time.NewTicker(recorder.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]db.CreateVisitorParams, 0, recorder.batchSize)
+	for {
+		select {
+		case visitor, ok := <-recorder.queue:
+			if !ok {
+				recorder.flush(batch)
+				return
+			}
+			batch = append(batch, visitor)
+			if len(batch) >= recorder.batchSize {
+				recorder.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			recorder.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// Helper method to insert a batch. The insertion is not canceled on shutdown, so that the queued
+// visitors are not lost, but it is bounded by a timeout in case the database is unreachable
+func (recorder *VisitorRecorder) flush(batch []db.CreateVisitorParams) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := recorder.insert(ctx, batch); err != nil {
+		recorder.failed.Add(uint64(len(batch)))
+		recorder.logger.Error("Failed to record visitors", "count", len(batch), "error", err)
+		return
+	}
+	recorder.recorded.Add(uint64(len(batch)))
+}
+
+// Helper method to insert a batch of visitors in one statement
+func (server *Server) insertVisitors(ctx context.Context, visitors []db.CreateVisitorParams) error {
+	var params db.CreateVisitorsParams
+	for _, visitor := range visitors {
+		params.Ips = append(params.Ips, visitor.Ip)
+		params.UrlIds = append(params.UrlIds, visitor.UrlID)
+		params.TimesVisited = append(params.TimesVisited, visitor.TimeVisited)
+		params.Referrers = append(params.Referrers, visitor.Referrer)
+		params.UserAgents = append(params.UserAgents, visitor.UserAgent)
+		params.AcceptLanguages = append(params.AcceptLanguages, visitor.AcceptLanguage)
+		params.Hosts = append(params.Hosts, visitor.Host)
+		params.QueryStrings = append(params.QueryStrings, visitor.QueryString)
+		params.Countries = append(params.Countries, visitor.Country)
+		params.Regions = append(params.Regions, visitor.Region)
+		params.Cities = append(params.Cities, visitor.City)
+		params.Asns = append(params.Asns, visitor.Asn)
+		params.AsOrgs = append(params.AsOrgs, visitor.AsOrg)
+		params.Browsers = append(params.Browsers, visitor.Browser)
+		params.BrowserVersions = append(params.BrowserVersions, visitor.BrowserVersion)
+		params.Oses = append(params.Oses, visitor.Os)
+		params.Devices = append(params.Devices, visitor.Device)
+		params.IsBots = append(params.IsBots, visitor.IsBot)
+	}
+
+	_, err := server.store.CreateVisitors(ctx, params)
+	return err
+}
+
+// Response struct for the counters of the visitor recorder
+type recorderStatsResponse struct {
+	Async    bool   `json:"async"` // False if visitors are recorded synchronously, other counters are 0
+	Queued   int    `json:"queued"`
+	Capacity int    `json:"capacity"`
+	Recorded uint64 `json:"recorded"`
+	Dropped  uint64 `json:"dropped"`
+	Failed   uint64 `json:"failed"`
+}
+
+// HandleRecorderStats godoc
+//
+// @Summary      Get visitor recorder counters
+// @Description  Returns the queue length and the counters of the asynchronous visitor recorder since the
+// @Description  server started. Dropped visitors were not recorded because the queue was full, failed
+// @Description  visitors were lost because their batch could not be inserted.
+// @Tags         visitors
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200 {object} recorderStatsResponse "Recorder counters"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      403 {object} ErrorResp "Admin permission required"
+// @Router       /api/recorder [get]
+func (server *Server) HandleRecorderStats(w http.ResponseWriter, r *http.Request) {
+	resp := recorderStatsResponse{}
+	if server.recorder != nil {
+		stats := server.recorder.Stats()
+		resp = recorderStatsResponse{
+			Async:    true,
+			Queued:   stats.Queued,
+			Capacity: stats.Capacity,
+			Recorded: stats.Recorded,
+			Dropped:  stats.Dropped,
+			Failed:   stats.Failed,
+		}
+	}
+
+	server.WriteJSON(w, http.StatusOK, resp)
+}
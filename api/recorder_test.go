@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisitorRecorder(t *testing.T) {
+	var mu sync.Mutex
+	batches := [][]string{}
+	insert := func(ctx context.Context, visitors []db.CreateVisitorParams) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ips := []string{}
+		for _, visitor := range visitors {
+			ips = append(ips, visitor.Ip)
+		}
+		batches = append(batches, ips)
+		if visitors[0].Ip == "fail" {
+			return errors.New("database is down")
+		}
+		return nil
+	}
+
+	// Visitors are dropped when the queue is full
+	recorder := NewVisitorRecorder(3, 2, time.Hour, insert, logger)
+	for _, ip := range []string{"1", "2", "3"} {
+		require.True(t, recorder.Record(db.CreateVisitorParams{Ip: ip}))
+	}
+	require.False(t, recorder.Record(db.CreateVisitorParams{Ip: "4"}))
+
+	// Full batches are inserted right away, the rest is inserted on close
+	recorder.Start(1)
+	require.NoError(t, recorder.Close(context.Background()))
+	require.Equal(t, [][]string{{"1", "2"}, {"3"}}, batches)
+	require.Equal(t, RecorderStats{Queued: 0, Capacity: 3, Recorded: 3, Dropped: 1, Failed: 0}, recorder.Stats())
+
+	// Partial batches are inserted at every flush interval, failed batches are counted
+	batches = [][]string{}
+	recorder = NewVisitorRecorder(10, 100, 10*time.Millisecond, insert, logger)
+	recorder.Start(1)
+	require.True(t, recorder.Record(db.CreateVisitorParams{Ip: "fail"}))
+	require.Eventually(t, func() bool {
+		return recorder.Stats().Failed == 1
+	}, time.Second, 5*time.Millisecond)
+
+	require.True(t, recorder.Record(db.CreateVisitorParams{Ip: "5"}))
+	require.Eventually(t, func() bool {
+		return recorder.Stats().Recorded == 1
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, recorder.Close(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, [][]string{{"fail"}, {"5"}}, batches)
+}
@@ -1,16 +1,24 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
 
 	db "github.com/danglnh07/URLShortener/db/sqlc"
 	_ "github.com/danglnh07/URLShortener/docs"
 	"github.com/danglnh07/URLShortener/service"
+	"github.com/danglnh07/URLShortener/store"
 	"github.com/go-playground/validator/v10"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
@@ -19,22 +27,88 @@ import (
 type Server struct {
 	mux      *http.ServeMux
 	config   *service.Config
-	queries  *db.Queries
+	store    store.Store
 	validate *validator.Validate
-	limiter  *RateLimiter
+	policy   *service.DestinationPolicy
+	geoip    *service.GeoIP // nil if no GeoIP database is configured
+	bots     *service.BotDetector
+	codes    service.CodeGenerator  // Short codes of the URLs without alias
+	cache    *service.Cache[db.Url] // Cache of the redirect lookups, keyed by code or alias
+	recorder *VisitorRecorder       // nil if visitors are recorded synchronously
 	logger   *slog.Logger
+
+	// Rate limiters of the management API (per IP address, then per API key) and of the redirects
+	apiLimiter      *service.RateLimiter
+	keyLimiter      *service.RateLimiter
+	redirectLimiter *service.RateLimiter
+
+	// Password-protected URLs: limiter of the password attempts of each URL, and secret of the access cookies
+	passwordLimiter *service.RateLimiter
+	linkSecret      []byte
+
+	// Last ID with a legacy code, loaded by InitCodes. 0 if there is none
+	legacyCodeMaxID int64
 }
 
 // Constructor method for Server
-func NewServer(config *service.Config, conn *sql.DB, logger *slog.Logger) *Server {
-	return &Server{
+func NewServer(config *service.Config, store store.Store, logger *slog.Logger) *Server {
+	// Report validation errors using the JSON field name instead of the struct field name
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// Only resolve the destination host names if enabled, since it requires network access
+	var resolver service.Resolver
+	if config.ResolveDestinations {
+		resolver = net.DefaultResolver
+	}
+
+	// GeoIP is optional
+	var geoip *service.GeoIP
+	if len(config.GeoIPDatabases) > 0 {
+		geoip = service.NewGeoIP(config.GeoIPDatabases, logger)
+	}
+
+	// Without a configured secret, the access cookies of the password-protected URLs are signed with a
+	// random secret, so they are only valid until the server restarts
+	linkSecret := []byte(config.LinkCookieSecret)
+	if len(linkSecret) == 0 {
+		linkSecret = make([]byte, 32)
+		rand.Read(linkSecret) // Never fails
+	}
+
+	server := &Server{
 		mux:      http.NewServeMux(),
 		config:   config,
-		queries:  db.New(conn),
-		validate: validator.New(validator.WithRequiredStructEnabled()),
-		limiter:  NewRateLimiter(config.MaxRequest, config.RefillRate),
+		store:    store,
+		validate: validate,
+		policy:   service.NewDestinationPolicy(config, resolver),
+		geoip:    geoip,
+		bots:     service.NewBotDetector(config.BotUserAgents),
+		codes:    service.NewCodeGenerator(config),
+		cache:    service.NewCache[db.Url](config.URLCacheSize, config.URLCacheTTL, config.URLCacheNegativeTTL),
 		logger:   logger,
+
+		apiLimiter: service.NewRateLimiter(config.MaxRequest, config.RefillRate, config.RateLimitMaxClients),
+		keyLimiter: service.NewRateLimiter(config.MaxRequest, config.RefillRate, config.RateLimitMaxClients),
+		redirectLimiter: service.NewRateLimiter(config.RedirectMaxRequest, config.RedirectRefillRate,
+			config.RateLimitMaxClients),
+		passwordLimiter: service.NewRateLimiter(config.PasswordMaxAttempts, config.PasswordAttemptRefill,
+			config.RateLimitMaxClients),
+		linkSecret: linkSecret,
 	}
+
+	// Visitors are recorded asynchronously if a queue is configured, the workers are started by Start
+	if config.VisitorQueueSize > 0 {
+		server.recorder = NewVisitorRecorder(config.VisitorQueueSize, config.VisitorBatchSize,
+			config.VisitorFlushInterval, server.insertVisitors, logger)
+	}
+	return server
 }
 
 // Helper method for registering handler
@@ -43,6 +117,27 @@ func (server *Server) RegisterHandler() {
 	server.mux.Handle("GET /api/urls/{id}/visitors", http.Handler(
 		server.ChainingMiddleware(http.HandlerFunc(server.HandleListVisitor))),
 	)
+	server.mux.Handle("GET /api/urls/{id}/stats", http.Handler(
+		server.ChainingMiddleware(http.HandlerFunc(server.HandleURLStats))),
+	)
+	server.mux.Handle("GET /api/urls/{id}/stats/{dimension}", http.Handler(
+		server.ChainingMiddleware(http.HandlerFunc(server.HandleURLBreakdown))),
+	)
+	server.mux.Handle("GET /api/urls/{id}/qr", http.Handler(
+		server.ChainingMiddleware(http.HandlerFunc(server.HandleQRCode))),
+	)
+	server.mux.Handle("GET /api/urls/{id}/visitors/export", http.Handler(
+		server.ChainingMiddleware(http.HandlerFunc(server.HandleExportVisitor))),
+	)
+	server.mux.Handle("GET /api/urls/export", http.Handler(
+		server.ChainingMiddleware(http.HandlerFunc(server.HandleExportURL))),
+	)
+	server.mux.Handle("POST /api/urls/import", http.Handler(
+		server.ChainingMiddleware(http.HandlerFunc(server.HandleImportURL))),
+	)
+	server.mux.Handle("POST /api/urls/batch", http.Handler(
+		server.ChainingMiddleware(http.HandlerFunc(server.HandleCreateBatchURL))),
+	)
 	server.mux.Handle("POST /api/urls", http.Handler(
 		server.ChainingMiddleware(http.HandlerFunc(server.HandleCreateShortenURL))),
 	)
@@ -52,31 +147,160 @@ func (server *Server) RegisterHandler() {
 	server.mux.Handle("GET /api/urls", http.Handler(
 		server.ChainingMiddleware(http.HandlerFunc(server.HandleListURL))),
 	)
+	server.mux.Handle("PATCH /api/urls/{id}", http.Handler(
+		server.ChainingMiddleware(http.HandlerFunc(server.HandleUpdateShortenURL))),
+	)
+	server.mux.Handle("DELETE /api/urls/{id}", http.Handler(
+		server.ChainingMiddleware(http.HandlerFunc(server.HandleDeleteShortenURL))),
+	)
+
+	// Register user handlers, admin only
+	server.mux.Handle("POST /api/users", http.Handler(
+		server.ChainingMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleCreateUser)))),
+	)
+	server.mux.Handle("GET /api/users", http.Handler(
+		server.ChainingMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleListUser)))),
+	)
+
+	// Register API key handlers, admin only
+	server.mux.Handle("POST /api/keys", http.Handler(
+		server.ChainingMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleCreateAPIKey)))),
+	)
+	server.mux.Handle("GET /api/keys", http.Handler(
+		server.ChainingMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleListAPIKey)))),
+	)
+	server.mux.Handle("DELETE /api/keys/{id}", http.Handler(
+		server.ChainingMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleRevokeAPIKey)))),
+	)
+
+	// Register cache and recorder handlers, admin only
+	server.mux.Handle("GET /api/cache", http.Handler(
+		server.ChainingMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleCacheStats)))),
+	)
+	server.mux.Handle("GET /api/recorder", http.Handler(
+		server.ChainingMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleRecorderStats)))),
+	)
 
 	// Shorten URL handling
 	server.mux.Handle("GET /{code}", http.Handler(
 		server.ChainingMiddleware(http.HandlerFunc(server.HandleRedirect))),
 	)
+	server.mux.Handle("POST /{code}", http.Handler(
+		server.ChainingMiddleware(http.HandlerFunc(server.HandleUnlockURL))),
+	)
 
 	// Swagger handler
 	server.mux.Handle("/swagger/", httpSwagger.WrapHandler)
 }
 
-// Method to start the server
-func (server *Server) Start() error {
+// Maximum time to wait for the in-flight requests and the queued visitors on shutdown
+const shutdownTimeout = 30 * time.Second
+
+// Names of the settings holding the scheme of the codes derived from the IDs, and the last ID with a legacy
+// (unprefixed) code
+const (
+	codeSchemeSetting      = "code_scheme"
+	legacyCodeMaxIDSetting = "legacy_code_max_id"
+)
+
+// Load the range of the legacy codes, then check that the code strategy gives the existing URLs the codes
+// they were created with and record its scheme. The codes derived from the IDs depend on the strategy, its
+// minimum length and its secret, so a changed setting is refused while some URLs have no stored code, since
+// their links would all break. Must be called before serving requests
+func (server *Server) InitCodes(ctx context.Context) error {
+	scheme := server.codes.Scheme()
+	return server.store.ExecTx(ctx, func(tx store.Store) error {
+		legacyMaxID, err := tx.GetSetting(ctx, legacyCodeMaxIDSetting)
+		if err == nil {
+			if server.legacyCodeMaxID, err = strconv.ParseInt(legacyMaxID, 10, 64); err != nil {
+				return fmt.Errorf("invalid setting %s: %w", legacyCodeMaxIDSetting, err)
+			}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		stored, err := tx.GetSetting(ctx, codeSchemeSetting)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil && stored == scheme {
+			return nil
+		}
+
+		if err == nil {
+			count, err := tx.CountURLWithoutCode(ctx)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("the codes of %d existing URLs were made by another code scheme (%s), "+
+					"CODE_STRATEGY, CODE_MIN_LENGTH and CODE_SECRET must not change", count, stored)
+			}
+		}
+		return tx.SetSetting(ctx, db.SetSettingParams{Name: codeSchemeSetting, Value: scheme})
+	})
+}
+
+// Method to start the server. It runs until the context is canceled (e.g. on SIGINT or SIGTERM), then
+// stops accepting requests, waits for the in-flight requests and records the queued visitors
+func (server *Server) Start(ctx context.Context) error {
 	// Register handler
 	server.RegisterHandler()
 
+	// Reload the GeoIP databases when they change
+	if server.geoip != nil && server.config.GeoIPReloadInterval > 0 {
+		go server.geoip.Watch(ctx, server.config.GeoIPReloadInterval)
+	}
+
+	// Start recording visitors in the background
+	if server.recorder != nil {
+		server.recorder.Start(server.config.VisitorWorkers)
+	}
+
 	// Startserver
 	server.logger.Info("Starting server", "address",
 		fmt.Sprintf("http://%s", server.config.BaseURL))
-	return http.ListenAndServe(":8080", server.mux)
+	httpServer := &http.Server{Addr: ":8080", Handler: server.mux}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Graceful shutdown
+	server.logger.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down the server: %w", err)
+	}
+	if server.recorder != nil {
+		if err := server.recorder.Close(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to record the queued visitors: %w", err)
+		}
+		stats := server.recorder.Stats()
+		server.logger.Info("Record the queued visitors successfully", "recorded", stats.Recorded,
+			"dropped", stats.Dropped, "failed", stats.Failed)
+	}
+	return nil
 }
 
 type ErrorResp struct {
 	Message string `json:"error"`
 }
 
+// Response struct when a field of the request is rejected by the validation pipeline
+type ValidationErrorResp struct {
+	Message string `json:"error"`
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+}
+
 // WriteError writes an error response in JSON format
 func (server *Server) WriteError(w http.ResponseWriter, status int, message ErrorResp) {
 	w.Header().Set("Content-Type", "application/json")
@@ -115,3 +339,233 @@ func (server *Server) ExtractPageParams(r *http.Request) (int32, int32, error) {
 
 	return int32(pageSize), int32(pageIndex), nil
 }
+
+// Helper method to build a readable message from the validator error
+func (server *Server) ValidationMessage(err error) string {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) || len(errs) == 0 {
+		return "invalid request body"
+	}
+
+	// Only report the first error, similar to how the client would fix them one by one
+	fieldErr := errs[0]
+	switch fieldErr.Tag() {
+	case "required":
+		return fmt.Sprintf("%s should not be empty", fieldErr.Field())
+	case "url":
+		return fmt.Sprintf("%s must be a valid URL", fieldErr.Field())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", fieldErr.Field(), fieldErr.Param())
+	case "max":
+		return fmt.Sprintf("%s must be at most %s characters long", fieldErr.Field(), fieldErr.Param())
+	case "lte":
+		return fmt.Sprintf("%s must be at most %s", fieldErr.Field(), fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", fieldErr.Field(), fieldErr.Param())
+	default:
+		return fmt.Sprintf("invalid value for %s", fieldErr.Field())
+	}
+}
+
+// Helpers to convert between optional values in request/response and nullable database values
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func fromNullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func toNullInt32(n *int32) sql.NullInt32 {
+	if n == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *n, Valid: true}
+}
+
+func fromNullInt32(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
+
+// Error of a single request field or item, with the status code it should be reported with. Field and
+// Code are only set when an URL is rejected by the validation pipeline
+type requestError struct {
+	Status  int
+	Message string
+	Field   string
+	Code    string
+}
+
+// Helper method to write the request error as ErrorResp, or ValidationErrorResp if it has a field
+func (server *Server) WriteRequestError(w http.ResponseWriter, err *requestError) {
+	if err.Field == "" {
+		server.WriteError(w, err.Status, ErrorResp{err.Message})
+		return
+	}
+
+	server.WriteJSON(w, err.Status, ValidationErrorResp{
+		Message: err.Message,
+		Field:   err.Field,
+		Code:    err.Code,
+	})
+}
+
+// Helper method to validate and normalize an URL field of the request into its canonical form, then
+// check it against the destination policy
+func (server *Server) NormalizeURLField(ctx context.Context, field, raw string) (string, *requestError) {
+	normalized, err := service.NormalizeURL(raw, server.config.NormalizeOptions())
+	if err == nil {
+		err = server.policy.Check(ctx, normalized)
+	}
+	if err != nil {
+		code := service.URLErrorMalformed
+		var urlErr *service.URLError
+		if errors.As(err, &urlErr) {
+			code = urlErr.Code
+		}
+
+		return "", &requestError{
+			Status:  http.StatusUnprocessableEntity,
+			Message: fmt.Sprintf("%s: %s", field, err.Error()),
+			Field:   field,
+			Code:    code,
+		}
+	}
+	return normalized, nil
+}
+
+// Same as NormalizeURLField, but write a 422 response and return false if the URL is rejected
+func (server *Server) ValidateURLField(
+	ctx context.Context, w http.ResponseWriter, field, raw string,
+) (string, bool) {
+	normalized, reqErr := server.NormalizeURLField(ctx, field, raw)
+	if reqErr != nil {
+		server.WriteRequestError(w, reqErr)
+		return "", false
+	}
+	return normalized, true
+}
+
+// Helper method to validate a custom alias. Aliases are resolved before the short codes, so an alias must
+// not be the code of another URL. The generated codes start with a prefix that aliases cannot have, only
+// the legacy codes and the random codes stored before the prefix are checked
+func (server *Server) ValidateAlias(ctx context.Context, alias string) *requestError {
+	if err := service.ValidateAlias(alias); err != nil {
+		return &requestError{Status: http.StatusBadRequest, Message: err.Error()}
+	}
+	if _, ok := server.decodeLegacyCode(alias); ok {
+		return &requestError{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("alias '%s' is the short code of an existing URL", alias),
+		}
+	}
+
+	_, err := server.store.GetURLByCode(ctx, sql.NullString{String: alias, Valid: true})
+	if err == nil {
+		return &requestError{Status: http.StatusConflict, Message: "This alias has been taken"}
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		server.logger.Error("Failed to check the alias against the random codes", "alias", alias, "error", err)
+		return &requestError{Status: http.StatusInternalServerError, Message: "Internal server error"}
+	}
+	return nil
+}
+
+// Helper method to get the ID of a legacy code: the code of an URL created before the codes had a prefix,
+// derived from its ID by the same code strategy without the prefix
+func (server *Server) decodeLegacyCode(code string) (int64, bool) {
+	if strings.HasPrefix(code, service.CodePrefix) {
+		return 0, false
+	}
+	id, ok := server.codes.Decode(service.CodePrefix + code)
+	return id, ok && id <= server.legacyCodeMaxID
+}
+
+// Helper method to get the URL record from a shorten code. The code is resolved as a custom alias
+// first, then as a random code, then fall back to the ID decoded by the code strategy, or by the legacy
+// codes for the URLs created before the codes had a prefix
+func (server *Server) GetURLByCode(ctx context.Context, code string) (db.Url, error) {
+	url, err := server.store.GetURLByAlias(ctx, sql.NullString{String: code, Valid: true})
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return url, err
+	}
+
+	url, err = server.store.GetURLByCode(ctx, sql.NullString{String: code, Valid: true})
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return url, err
+	}
+
+	id, ok := server.codes.Decode(code)
+	if !ok {
+		id, ok = server.decodeLegacyCode(code)
+	}
+	if !ok {
+		return db.Url{}, sql.ErrNoRows
+	}
+	url, err = server.store.GetURL(ctx, id)
+	if err == nil && url.Code.Valid {
+		// URLs with a random code can only be found by their code, not by walking the IDs
+		return db.Url{}, sql.ErrNoRows
+	}
+	return url, err
+}
+
+// Helper method to get the URL of a code for redirecting, through the cache. Unknown codes are cached
+// too, so that probing random codes does not hit the database every time
+func (server *Server) GetCachedURLByCode(ctx context.Context, code string) (db.Url, error) {
+	if url, found, ok := server.cache.Get(code); ok {
+		if !found {
+			return db.Url{}, sql.ErrNoRows
+		}
+		return url, nil
+	}
+
+	// The URL may be invalidated while it is read, then the read result is not cached
+	version := server.cache.Version()
+	url, err := server.GetURLByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.cache.SetMissing(code, version)
+		}
+		return url, err
+	}
+	server.cache.Set(code, url, version)
+	return url, nil
+}
+
+// Helper method to remove the cached lookups of an URL, must be called after the URL is created,
+// updated or deleted. The encoded ID, the legacy code, the random code and the alias can all be cached
+func (server *Server) InvalidateURL(url db.Url) {
+	keys := []string{server.codes.Encode(url.ID)}
+	if url.ID <= server.legacyCodeMaxID {
+		keys = append(keys, strings.TrimPrefix(keys[0], service.CodePrefix))
+	}
+	if url.Code.Valid {
+		keys = append(keys, url.Code.String)
+	}
+	if url.Alias.Valid {
+		keys = append(keys, url.Alias.String)
+	}
+	server.cache.Delete(keys...)
+}
+
+// Helper method to generate the shorten URL of a record, prefer the custom alias if it has one, then
+// the random code, then the ID encoded by the code strategy
+func (server *Server) GenerateShortenURL(id int64, alias, code sql.NullString) string {
+	if alias.Valid {
+		return service.GenerateAliasURL(server.config, alias.String)
+	}
+	if code.Valid {
+		return service.GenerateShortenURL(server.config, code.String)
+	}
+	return service.GenerateShortenURL(server.config, server.codes.Encode(id))
+}
@@ -0,0 +1,318 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/danglnh07/URLShortener/service"
+)
+
+// Number of clicks in a single bucket of the statistics. Human counts exclude the visits flagged as bot
+type statsBucket struct {
+	Start               time.Time `json:"start"`
+	Clicks              int64     `json:"clicks"`
+	UniqueVisitors      int64     `json:"unique_visitors"`
+	HumanClicks         int64     `json:"human_clicks"`
+	HumanUniqueVisitors int64     `json:"human_unique_visitors"`
+}
+
+// Number of clicks of a single value of a breakdown dimension (e.g. a country or a browser). Value is
+// empty for visitors whose value is unknown
+type statsGroup struct {
+	Value               string `json:"value"`
+	Clicks              int64  `json:"clicks"`
+	UniqueVisitors      int64  `json:"unique_visitors"`
+	HumanClicks         int64  `json:"human_clicks"`
+	HumanUniqueVisitors int64  `json:"human_unique_visitors"`
+}
+
+// Response struct for URL statistics
+type statsResponse struct {
+	ShortenURL     string        `json:"shorten"`
+	Interval       string        `json:"interval"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	TotalClicks    int64         `json:"total_clicks"`
+	UniqueVisitors int64         `json:"unique_visitors"`
+	HumanClicks    int64         `json:"human_clicks"`
+	HumanVisitors  int64         `json:"human_unique_visitors"`
+	Buckets        []statsBucket `json:"buckets"`
+	Countries      []statsGroup  `json:"countries"`
+}
+
+// Response struct for the breakdown of the clicks of an URL by a single dimension
+type breakdownResponse struct {
+	ShortenURL     string       `json:"shorten"`
+	Dimension      string       `json:"dimension"`
+	From           time.Time    `json:"from"`
+	To             time.Time    `json:"to"`
+	TotalClicks    int64        `json:"total_clicks"`
+	UniqueVisitors int64        `json:"unique_visitors"`
+	HumanClicks    int64        `json:"human_clicks"`
+	HumanVisitors  int64        `json:"human_unique_visitors"`
+	Groups         []statsGroup `json:"groups"`
+}
+
+// Dimensions the clicks can be broken down by
+var breakdownDimensions = map[string]bool{
+	"country": true,
+	"browser": true,
+	"os":      true,
+	"device":  true,
+}
+
+// Default time range of the statistics of each interval, ending now
+var defaultStatsRange = map[string]func(to time.Time) time.Time{
+	service.IntervalHour:  func(to time.Time) time.Time { return to.Add(-24 * time.Hour) },
+	service.IntervalDay:   func(to time.Time) time.Time { return to.AddDate(0, 0, -30) },
+	service.IntervalWeek:  func(to time.Time) time.Time { return to.AddDate(0, 0, -7*12) },
+	service.IntervalMonth: func(to time.Time) time.Time { return to.AddDate(0, -12, 0) },
+}
+
+// Helper function to extract the interval and the time range of the statistics from query parameters
+func extractStatsParams(r *http.Request) (string, time.Time, time.Time, error) {
+	params := r.URL.Query()
+
+	interval := params.Get("interval")
+	if interval == "" {
+		interval = service.IntervalDay
+	}
+	if !service.IsValidInterval(interval) {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("invalid value for interval, must be one of %s, %s, %s or %s",
+			service.IntervalHour, service.IntervalDay, service.IntervalWeek, service.IntervalMonth)
+	}
+
+	to := time.Now().UTC()
+	if value := params.Get("to"); value != "" {
+		var err error
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			return "", time.Time{}, time.Time{}, fmt.Errorf("invalid value for to, must be a RFC 3339 date time")
+		}
+	}
+
+	from := defaultStatsRange[interval](to)
+	if value := params.Get("from"); value != "" {
+		var err error
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			return "", time.Time{}, time.Time{}, fmt.Errorf("invalid value for from, must be a RFC 3339 date time")
+		}
+	}
+
+	if !from.Before(to) {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+
+	return interval, from, to, nil
+}
+
+// HandleURLStats godoc
+//
+// @Summary      Get click statistics of a shortened URL
+// @Description  Counts the clicks and unique IP addresses of a shortened URL in the time range [from, to),
+// @Description  bucketed by hour, day, week (starting on Monday) or month. Buckets are computed in UTC, and
+// @Description  buckets without clicks are included, so the result can be used for charts directly.
+// @Description  Without from, the range covers the last 24 hours, 30 days, 12 weeks or 12 months, depending
+// @Description  on the interval. Clicks are also broken down by country, resolved from the visitor IP by GeoIP.
+// @Description  Human counts exclude bot visits (crawlers, link unfurlers, HEAD and prefetch requests).
+// @Tags         visitors
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id       path  string true  "Shortened URL code or alias"
+// @Param        interval query string false "Bucket interval" Enums(hour, day, week, month) default(day)
+// @Param        from     query string false "Start of the time range (RFC 3339)" format(date-time)
+// @Param        to       query string false "End of the time range (RFC 3339), default to now" format(date-time)
+// @Success      200 {object} statsResponse "Click statistics"
+// @Failure      400 {object} ErrorResp "Invalid parameters"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      404 {object} ErrorResp "URL not found"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/urls/{id}/stats [get]
+func (server *Server) HandleURLStats(w http.ResponseWriter, r *http.Request) {
+	interval, from, to, err := extractStatsParams(r)
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+		return
+	}
+
+	buckets, err := service.ListBuckets(from, to, interval)
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+		return
+	}
+
+	// Get URL from path parameter, which can be either the Base62 code or the alias. Only the owner
+	// (or admin) can see the statistics
+	url, ok := server.getVisibleURL(w, r)
+	if !ok {
+		return
+	}
+
+	// Count the clicks of each bucket
+	stats, err := server.store.ListVisitorStats(r.Context(), db.ListVisitorStatsParams{
+		Bucket:   interval,
+		UrlID:    url.ID,
+		FromTime: from,
+		ToTime:   to,
+	})
+	if err != nil {
+		server.logger.Error("GET /api/urls/{id}/stats: failed to count clicks", "url_id", url.ID, "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+
+	// Unique visitors of the whole range cannot be computed from the buckets, since the same IP
+	// can visit in many buckets
+	summary, err := server.store.GetVisitorSummary(r.Context(), db.GetVisitorSummaryParams{
+		UrlID:    url.ID,
+		FromTime: from,
+		ToTime:   to,
+	})
+	if err != nil {
+		server.logger.Error("GET /api/urls/{id}/stats: failed to count unique visitors",
+			"url_id", url.ID, "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+
+	// Break down the clicks by country
+	countries, err := server.listBreakdown(r, url.ID, "country", from, to)
+	if err != nil {
+		server.logger.Error("GET /api/urls/{id}/stats: failed to count clicks by country",
+			"url_id", url.ID, "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+
+	// Fill the buckets without clicks with zero
+	counts := make(map[int64]db.ListVisitorStatsRow, len(stats))
+	for _, stat := range stats {
+		counts[stat.BucketStart.Unix()] = stat
+	}
+	resp := statsResponse{
+		ShortenURL:     server.GenerateShortenURL(url.ID, url.Alias, url.Code),
+		Interval:       interval,
+		From:           from,
+		To:             to,
+		TotalClicks:    summary.Clicks,
+		UniqueVisitors: summary.UniqueVisitors,
+		HumanClicks:    summary.HumanClicks,
+		HumanVisitors:  summary.HumanUniqueVisitors,
+		Buckets:        make([]statsBucket, len(buckets)),
+		Countries:      countries,
+	}
+	for i, start := range buckets {
+		stat := counts[start.Unix()]
+		resp.Buckets[i] = statsBucket{
+			Start:               start,
+			Clicks:              stat.Clicks,
+			UniqueVisitors:      stat.UniqueVisitors,
+			HumanClicks:         stat.HumanClicks,
+			HumanUniqueVisitors: stat.HumanUniqueVisitors,
+		}
+	}
+
+	server.WriteJSON(w, http.StatusOK, resp)
+}
+
+// Helper method to count the clicks of an URL in [from, to) grouped by the given dimension
+func (server *Server) listBreakdown(r *http.Request, urlID int64, dimension string, from, to time.Time) (
+	[]statsGroup, error) {
+	rows, err := server.store.ListVisitorBreakdown(r.Context(), db.ListVisitorBreakdownParams{
+		Dimension: dimension,
+		UrlID:     urlID,
+		FromTime:  from,
+		ToTime:    to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]statsGroup, len(rows))
+	for i, row := range rows {
+		groups[i] = statsGroup{
+			Value:               row.Value,
+			Clicks:              row.Clicks,
+			UniqueVisitors:      row.UniqueVisitors,
+			HumanClicks:         row.HumanClicks,
+			HumanUniqueVisitors: row.HumanUniqueVisitors,
+		}
+	}
+	return groups, nil
+}
+
+// HandleURLBreakdown godoc
+//
+// @Summary      Break down the clicks of a shortened URL
+// @Description  Counts the clicks and unique IP addresses of a shortened URL in the time range [from, to),
+// @Description  grouped by country, browser family, OS family or device type (desktop, mobile, tablet or bot),
+// @Description  most clicks first. Browser, OS and device are parsed from the User-Agent header of each visit.
+// @Description  Without from, the range covers the last 30 days. Human counts exclude bot visits.
+// @Tags         visitors
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id        path  string true  "Shortened URL code or alias"
+// @Param        dimension path  string true  "Dimension to group the clicks by" Enums(country, browser, os, device)
+// @Param        from      query string false "Start of the time range (RFC 3339)" format(date-time)
+// @Param        to        query string false "End of the time range (RFC 3339), default to now" format(date-time)
+// @Success      200 {object} breakdownResponse "Click breakdown"
+// @Failure      400 {object} ErrorResp "Invalid parameters"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      404 {object} ErrorResp "URL not found"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/urls/{id}/stats/{dimension} [get]
+func (server *Server) HandleURLBreakdown(w http.ResponseWriter, r *http.Request) {
+	dimension := r.PathValue("dimension")
+	if !breakdownDimensions[dimension] {
+		server.WriteError(w, http.StatusBadRequest,
+			ErrorResp{"invalid dimension, must be one of country, browser, os or device"})
+		return
+	}
+
+	// The interval is not used, only the time range
+	_, from, to, err := extractStatsParams(r)
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+		return
+	}
+
+	url, ok := server.getVisibleURL(w, r)
+	if !ok {
+		return
+	}
+
+	summary, err := server.store.GetVisitorSummary(r.Context(), db.GetVisitorSummaryParams{
+		UrlID:    url.ID,
+		FromTime: from,
+		ToTime:   to,
+	})
+	if err != nil {
+		server.logger.Error("GET /api/urls/{id}/stats/{dimension}: failed to count unique visitors",
+			"url_id", url.ID, "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+
+	groups, err := server.listBreakdown(r, url.ID, dimension, from, to)
+	if err != nil {
+		server.logger.Error("GET /api/urls/{id}/stats/{dimension}: failed to count clicks",
+			"url_id", url.ID, "dimension", dimension, "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, breakdownResponse{
+		ShortenURL:     server.GenerateShortenURL(url.ID, url.Alias, url.Code),
+		Dimension:      dimension,
+		From:           from,
+		To:             to,
+		TotalClicks:    summary.Clicks,
+		UniqueVisitors: summary.UniqueVisitors,
+		HumanClicks:    summary.HumanClicks,
+		HumanVisitors:  summary.HumanUniqueVisitors,
+		Groups:         groups,
+	})
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleURLStats(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=stats"
+
+	// Create a shorten URL
+	var buffer bytes.Buffer
+	err := json.NewEncoder(&buffer).Encode(createShortenURLRequest{URL: data, AllowDuplicate: true})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/urls", &buffer)
+	req.Header.Set("X-API-Key", adminAPIKey)
+	rr := httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleCreateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var shortenURL createShortenURLResponse
+	err = json.NewDecoder(rr.Body).Decode(&shortenURL)
+	require.NoError(t, err)
+	u, err := url.Parse(shortenURL.ShortenURL)
+	require.NoError(t, err)
+	code := strings.TrimPrefix(u.Path, "/")
+
+	// Visit it 3 times from 2 different IPs, one on mobile and one on desktop
+	mobile := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) " +
+		"Version/17.4 Mobile/15E148 Safari/604.1"
+	desktop := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
+		"Chrome/124.0.0.0 Safari/537.36"
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.1"} {
+		req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+		req.RemoteAddr = ip + ":12345"
+		if ip == "10.0.0.1" {
+			req.Header.Set("User-Agent", mobile)
+		} else {
+			req.Header.Set("User-Agent", desktop)
+		}
+		req.SetPathValue("code", code)
+		rr := httptest.NewRecorder()
+		server.HandleRedirect(rr, req)
+		require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	}
+
+	// Helper to get the statistics with the given query
+	getStats := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/urls/"+code+"/stats?"+query, nil)
+		req.Header.Set("X-API-Key", adminAPIKey)
+		req.SetPathValue("id", code)
+		rr := httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleURLStats)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Hourly statistics of the last 24 hours, all clicks are in the last bucket
+	rr = getStats("interval=hour")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp statsResponse
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	require.NoError(t, err)
+	require.Equal(t, "hour", resp.Interval)
+	require.Equal(t, int64(3), resp.TotalClicks)
+	require.Equal(t, int64(2), resp.UniqueVisitors)
+	require.GreaterOrEqual(t, len(resp.Buckets), 24)
+
+	last := resp.Buckets[len(resp.Buckets)-1]
+	require.Equal(t, int64(3), last.Clicks)
+	require.Equal(t, int64(2), last.UniqueVisitors)
+	require.Equal(t, int64(0), resp.Buckets[0].Clicks)
+
+	// Without GeoIP database, the country of all visitors is unknown
+	require.Equal(t, []statsGroup{{Value: "", Clicks: 3, UniqueVisitors: 2, HumanClicks: 3, HumanUniqueVisitors: 2}}, resp.Countries)
+
+	// Time range before the visits has no click
+	from := time.Now().AddDate(0, 0, -3).Format(time.RFC3339)
+	to := time.Now().AddDate(0, 0, -1).Format(time.RFC3339)
+	rr = getStats("interval=day&from=" + url.QueryEscape(from) + "&to=" + url.QueryEscape(to))
+	require.Equal(t, http.StatusOK, rr.Code)
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), resp.TotalClicks)
+
+	// Invalid parameters
+	require.Equal(t, http.StatusBadRequest, getStats("interval=year").Code)
+	require.Equal(t, http.StatusBadRequest, getStats("from="+url.QueryEscape(to)+"&to="+url.QueryEscape(from)).Code)
+	require.Equal(t, http.StatusBadRequest, getStats("interval=hour&from=2000-01-01T00:00:00Z").Code)
+
+	// Helper to get the breakdown by the given dimension
+	getBreakdown := func(dimension string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/urls/"+code+"/stats/"+dimension, nil)
+		req.Header.Set("X-API-Key", adminAPIKey)
+		req.SetPathValue("id", code)
+		req.SetPathValue("dimension", dimension)
+		rr := httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleURLBreakdown)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Break down the clicks by device and browser, parsed from the User-Agent
+	rr = getBreakdown("device")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var breakdown breakdownResponse
+	err = json.NewDecoder(rr.Body).Decode(&breakdown)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), breakdown.TotalClicks)
+	require.Equal(t, []statsGroup{
+		{Value: "mobile", Clicks: 2, UniqueVisitors: 1, HumanClicks: 2, HumanUniqueVisitors: 1},
+		{Value: "desktop", Clicks: 1, UniqueVisitors: 1, HumanClicks: 1, HumanUniqueVisitors: 1},
+	}, breakdown.Groups)
+
+	rr = getBreakdown("browser")
+	require.Equal(t, http.StatusOK, rr.Code)
+	err = json.NewDecoder(rr.Body).Decode(&breakdown)
+	require.NoError(t, err)
+	require.Equal(t, []statsGroup{
+		{Value: "Safari", Clicks: 2, UniqueVisitors: 1, HumanClicks: 2, HumanUniqueVisitors: 1},
+		{Value: "Chrome", Clicks: 1, UniqueVisitors: 1, HumanClicks: 1, HumanUniqueVisitors: 1},
+	}, breakdown.Groups)
+
+	require.Equal(t, http.StatusBadRequest, getBreakdown("language").Code)
+
+	// Clean up database
+	deleteURLs(t, code)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+)
+
+// Request struct for create user action
+type createUserRequest struct {
+	Name    string `json:"name" validate:"required"`
+	IsAdmin bool   `json:"is_admin"`
+}
+
+// Response struct for user
+type userResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	IsAdmin   bool      `json:"is_admin"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Helper function to convert database record into response
+func newUserResponse(user db.User) userResponse {
+	return userResponse{
+		ID:        user.ID,
+		Name:      user.Name,
+		IsAdmin:   user.IsAdmin,
+		CreatedAt: user.TimeCreated,
+	}
+}
+
+// HandleCreateUser godoc
+//
+// @Summary      Create a user
+// @Description  Creates a new user. Use POST /api/keys afterward to give this user an API key.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        request body createUserRequest true "User request"
+// @Success      201 {object} userResponse "User created successfully"
+// @Failure      400 {object} ErrorResp "Invalid input"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      403 {object} ErrorResp "Admin permission required"
+// @Failure      409 {object} ErrorResp "User name already taken"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/users [post]
+func (server *Server) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
+	// Parse JSON request and validate
+	var req createUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{"Invalid JSON body"})
+		return
+	}
+
+	if err := server.validate.Struct(req); err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{server.ValidationMessage(err)})
+		return
+	}
+
+	user, err := server.store.CreateUser(r.Context(), db.CreateUserParams{
+		Name:    req.Name,
+		IsAdmin: req.IsAdmin,
+	})
+	if err != nil {
+		// If the name has been taken by another user
+		if strings.Contains(err.Error(), "user_name_key") {
+			server.WriteError(w, http.StatusConflict, ErrorResp{"This user name has been taken"})
+			return
+		}
+
+		server.logger.Error("POST /api/users: failed to insert user into database", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+	server.logger.Info("Create user successfully", "id", user.ID, "name", user.Name)
+
+	server.WriteJSON(w, http.StatusCreated, newUserResponse(user))
+}
+
+// HandleListUser godoc
+//
+// @Summary      List users
+// @Description  Retrieves a paginated list of users.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        page_size  query int true  "Number of items per page" minimum(1) maximum(100)
+// @Param        page_index query int true  "Page index (starting from 1)" minimum(1)
+// @Success      200 {array} userResponse "List of users"
+// @Failure      400 {object} ErrorResp "Invalid pagination parameters"
+// @Failure      401 {object} ErrorResp "Missing or invalid API key"
+// @Failure      403 {object} ErrorResp "Admin permission required"
+// @Failure      500 {object} ErrorResp "Internal server error"
+// @Router       /api/users [get]
+func (server *Server) HandleListUser(w http.ResponseWriter, r *http.Request) {
+	// Get the page_size and page_index parameter
+	pageSize, pageIndex, err := server.ExtractPageParams(r)
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, ErrorResp{err.Error()})
+		return
+	}
+
+	users, err := server.store.ListUser(r.Context(), db.ListUserParams{
+		Offset: (pageIndex - 1) * pageSize,
+		Limit:  pageSize,
+	})
+	if err != nil {
+		server.logger.Error("GET /api/users: failed to get list of users", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, ErrorResp{"Internal server error"})
+		return
+	}
+
+	resps := make([]userResponse, len(users))
+	for i, user := range users {
+		resps[i] = newUserResponse(user)
+	}
+	server.WriteJSON(w, http.StatusOK, resps)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/danglnh07/URLShortener/service"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper to create a user with an API key directly in the database
+func createTestUser(t *testing.T, isAdmin bool) (db.User, string) {
+	user, err := server.store.CreateUser(context.Background(), db.CreateUserParams{
+		Name:    fmt.Sprintf("test-user-%d", time.Now().UnixNano()),
+		IsAdmin: isAdmin,
+	})
+	require.NoError(t, err)
+
+	key, err := service.GenerateAPIKey()
+	require.NoError(t, err)
+
+	_, err = server.store.CreateAPIKey(context.Background(), db.CreateAPIKeyParams{
+		Name:    "test-key",
+		KeyHash: service.HashAPIKey(key),
+		Prefix:  key[:service.APIKeyDisplayLength],
+		UserID:  user.ID,
+	})
+	require.NoError(t, err)
+
+	return user, key
+}
+
+func TestURLOwnership(t *testing.T) {
+	data := "https://www.youtube.com/watch?v=rUxyKA_-grg&ab_channel=LofiGirl"
+	owner, ownerKey := createTestUser(t, false)
+	other, otherKey := createTestUser(t, false)
+
+	// The owner creates a shorten URL
+	var buffer bytes.Buffer
+	err := json.NewEncoder(&buffer).Encode(createShortenURLRequest{URL: data})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/urls", &buffer)
+	req.Header.Set("X-API-Key", ownerKey)
+	rr := httptest.NewRecorder()
+	server.AuthMiddleware(http.HandlerFunc(server.HandleCreateShortenURL)).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var shortenURL createShortenURLResponse
+	err = json.NewDecoder(rr.Body).Decode(&shortenURL)
+	require.NoError(t, err)
+
+	u, err := url.Parse(shortenURL.ShortenURL)
+	require.NoError(t, err)
+	code := strings.TrimPrefix(u.Path, "/")
+
+	// Helper to list URLs as the given API key
+	listURL := func(key string) []listURLResponse {
+		req := httptest.NewRequest(http.MethodGet, "/api/urls?page_size=100&page_index=1", nil)
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleListURL)).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp []listURLResponse
+		err := json.NewDecoder(rr.Body).Decode(&resp)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// The owner sees the URL, the other user does not
+	ownerURLs := listURL(ownerKey)
+	require.Len(t, ownerURLs, 1)
+	require.Equal(t, data, ownerURLs[0].OriginalURL)
+	require.Empty(t, listURL(otherKey))
+
+	// Only the owner and admin can list the visitors
+	for key, status := range map[string]int{
+		ownerKey:    http.StatusOK,
+		otherKey:    http.StatusNotFound,
+		adminAPIKey: http.StatusOK,
+	} {
+		path := fmt.Sprintf("/api/urls/%s/visitors?page_size=5&page_index=1", code)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-Key", key)
+		req.SetPathValue("id", code)
+		rr := httptest.NewRecorder()
+		server.AuthMiddleware(http.HandlerFunc(server.HandleListVisitor)).ServeHTTP(rr, req)
+		require.Equal(t, status, rr.Code)
+	}
+
+	// Clean up database
+	server.store.DeleteURL(context.Background(), codeID(t, code))
+	server.store.DeleteUser(context.Background(), owner.ID)
+	server.store.DeleteUser(context.Background(), other.ID)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/danglnh07/URLShortener/api"
+	"github.com/danglnh07/URLShortener/store"
+)
+
+// Usage of the command line subcommands
+const usage = `Usage:
+  main [serve]                                                      Start the server
+  main export urls [-format csv|ndjson] [-owner id] [-o file]       Export all URLs (or the URLs of a user)
+  main export visitors [-format csv|ndjson] [-o file] <code>        Export the visitor history of an URL
+  main import [-owner id] <file>                                    Import URLs from a CSV file ("-" for stdin)
+  main migrate up                                                   Apply the pending schema migrations
+  main migrate down [-steps n]                                      Revert the last n applied migrations (default 1)
+  main migrate status                                               Show the applied and pending migrations`
+
+// Run a command line subcommand. The subcommands act as admin, since they have direct access to the database
+func runCommand(server *api.Server, args []string) error {
+	if len(args) >= 2 && args[0] == "export" && args[1] == "urls" {
+		return runExportURLs(server, args[2:])
+	}
+	if len(args) >= 2 && args[0] == "export" && args[1] == "visitors" {
+		return runExportVisitors(server, args[2:])
+	}
+	if len(args) >= 1 && args[0] == "import" {
+		return runImport(server, args[1:])
+	}
+	return usageError()
+}
+
+// Helper function to print the usage and return the error for an invalid command
+func usageError() error {
+	fmt.Fprintln(os.Stderr, usage)
+	return errors.New("invalid command or arguments")
+}
+
+// Export all URLs, or the URLs of a user if -owner is set
+func runExportURLs(server *api.Server, args []string) error {
+	flags := flag.NewFlagSet("export urls", flag.ContinueOnError)
+	format := flags.String("format", api.FormatCSV, "export format, csv or ndjson")
+	owner := flags.Int64("owner", 0, "only export the URLs of this user ID")
+	output := flags.String("o", "", "output file, default to stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	principal := &api.Principal{Name: "cli", Admin: true}
+	if *owner != 0 {
+		principal = &api.Principal{Name: "cli", UserID: *owner}
+	}
+
+	return writeOutput(*output, func(w io.Writer) error {
+		total, err := server.ExportURLs(context.Background(), w, *format, principal.OwnerFilter())
+		fmt.Fprintf(os.Stderr, "Exported %d URLs\n", total)
+		return err
+	})
+}
+
+// Export the visitor history of an URL
+func runExportVisitors(server *api.Server, args []string) error {
+	flags := flag.NewFlagSet("export visitors", flag.ContinueOnError)
+	format := flags.String("format", api.FormatCSV, "export format, csv or ndjson")
+	output := flags.String("o", "", "output file, default to stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageError()
+	}
+
+	url, err := server.GetURLByCode(context.Background(), flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to get the URL %s: %w", flags.Arg(0), err)
+	}
+
+	return writeOutput(*output, func(w io.Writer) error {
+		total, err := server.ExportVisitors(context.Background(), w, *format, url)
+		fmt.Fprintf(os.Stderr, "Exported %d visitors\n", total)
+		return err
+	})
+}
+
+// Import URLs from a CSV file, owned by the user of -owner if set
+func runImport(server *api.Server, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	owner := flags.Int64("owner", 0, "user ID owning the imported URLs, default to no owner")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageError()
+	}
+
+	input := os.Stdin
+	if flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	ctx := api.WithPrincipal(context.Background(), &api.Principal{Name: "cli", UserID: *owner, Admin: true})
+	resp, err := server.ImportURLs(ctx, input, 0)
+	if err != nil {
+		return err
+	}
+
+	// Report the failed rows, row 1 being the first row after the header
+	for _, result := range resp.Results {
+		if result.Error != "" {
+			fmt.Fprintf(os.Stderr, "Row %d: %s\n", result.Index+1, result.Error)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Processed %d rows: %d created, %d already existed, %d failed\n",
+		len(resp.Results), resp.Created, resp.Existing, resp.Failed)
+	return nil
+}
+
+// Apply, revert or list the schema migrations. Unlike the other subcommands, it runs before the pending
+// migrations are applied at startup
+func runMigrate(storage store.Store, args []string) error {
+	if len(args) == 0 {
+		return usageError()
+	}
+
+	migratable, ok := storage.(store.Migratable)
+	if !ok {
+		return errors.New("the database driver has no schema to migrate")
+	}
+	migrator, err := migratable.Migrator()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(os.Stderr, "Applied migration %d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(os.Stderr, "No pending migration")
+		}
+		return err
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *steps < 1 || flags.NArg() != 0 {
+			return usageError()
+		}
+
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, migration := range reverted {
+			fmt.Fprintf(os.Stderr, "Reverted migration %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied at " + status.TimeApplied.Format(time.RFC3339)
+			}
+			fmt.Printf("%d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return usageError()
+	}
+}
+
+// Helper function to write into the output file, or stdout if the path is empty
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
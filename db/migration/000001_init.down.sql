@@ -1,2 +1,2 @@
 DROP TABLE IF EXISTS visitor;
-DROP TABLE IF EXISTS url;
\ No newline at end of file
+DROP TABLE IF EXISTS url;
@@ -1,3 +1,6 @@
+-- Initial schema, as it was before the migrations were introduced. Tables are created only if they do not
+-- exist, so that such a database is adopted as version 1 and brought up to date by the next migrations
+
 -- Create table url
 CREATE TABLE IF NOT EXISTS url (
     id BIGSERIAL PRIMARY KEY,
@@ -11,4 +14,4 @@ CREATE TABLE IF NOT EXISTS visitor (
     url_id BIGSERIAL NOT NULL REFERENCES url(id),
     time_visited TIMESTAMPTZ NOT NULL DEFAULT now(),
     PRIMARY KEY (Ip, url_id, time_visited)
-)
\ No newline at end of file
+);
@@ -0,0 +1 @@
+ALTER TABLE url DROP COLUMN IF EXISTS alias;
@@ -0,0 +1,2 @@
+-- Optional custom code (vanity alias), used instead of the Base62 ID
+ALTER TABLE url ADD COLUMN IF NOT EXISTS alias VARCHAR(64) CONSTRAINT url_alias_key UNIQUE;
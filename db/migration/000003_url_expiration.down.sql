@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS visitor_url_id_idx;
+ALTER TABLE url DROP COLUMN IF EXISTS fallback_url;
+ALTER TABLE url DROP COLUMN IF EXISTS max_clicks;
+ALTER TABLE url DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,11 @@
+-- Optional time after which the URL cannot be visited anymore
+ALTER TABLE url ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+-- Optional maximum number of visits allowed
+ALTER TABLE url ADD COLUMN IF NOT EXISTS max_clicks INTEGER;
+
+-- Optional URL returned to visitor once the URL has expired
+ALTER TABLE url ADD COLUMN IF NOT EXISTS fallback_url VARCHAR;
+
+-- Index for looking up visitors of an URL, used to count the clicks
+CREATE INDEX IF NOT EXISTS visitor_url_id_idx ON visitor(url_id);
@@ -0,0 +1,3 @@
+ALTER TABLE url DROP COLUMN IF EXISTS owner_id;
+DROP TABLE IF EXISTS api_key;
+DROP TABLE IF EXISTS "user";
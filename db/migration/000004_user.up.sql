@@ -0,0 +1,24 @@
+-- Create table user ("user" is a reserved word in Postgres, so it must be quoted)
+CREATE TABLE IF NOT EXISTS "user" (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR NOT NULL UNIQUE,
+    is_admin BOOLEAN NOT NULL DEFAULT false, -- Admin can see and manage everything
+    time_created TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Create table api_key
+CREATE TABLE IF NOT EXISTS api_key (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR NOT NULL, -- Human readable name, to know who/what is using the key
+    key_hash CHAR(64) NOT NULL UNIQUE, -- SHA-256 hash (hex) of the key, the raw key is never stored
+    prefix VARCHAR(16) NOT NULL, -- First few characters of the key, to help identify it
+    user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE, -- The key acts on behalf of this user
+    time_created TIMESTAMPTZ NOT NULL DEFAULT now(),
+    time_revoked TIMESTAMPTZ -- NULL if the key is still active
+);
+
+-- Owner of the URL, NULL if created with the bootstrap admin key
+ALTER TABLE url ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES "user"(id);
+
+-- Index for looking up URLs of an owner
+CREATE INDEX IF NOT EXISTS url_owner_id_idx ON url(owner_id);
@@ -0,0 +1,5 @@
+CREATE SEQUENCE IF NOT EXISTS visitor_url_id_seq OWNED BY visitor.url_id;
+ALTER TABLE visitor ALTER COLUMN url_id SET DEFAULT nextval('visitor_url_id_seq');
+ALTER TABLE visitor DROP CONSTRAINT IF EXISTS visitor_url_id_fkey;
+ALTER TABLE visitor ADD CONSTRAINT visitor_url_id_fkey FOREIGN KEY (url_id) REFERENCES url(id);
+ALTER TABLE url DROP COLUMN IF EXISTS time_deleted;
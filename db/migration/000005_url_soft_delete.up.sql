@@ -0,0 +1,9 @@
+-- Soft delete: the URL cannot be visited, but its visitors are kept
+ALTER TABLE url ADD COLUMN IF NOT EXISTS time_deleted TIMESTAMPTZ;
+
+-- The visitors of an URL are only deleted along with the URL itself. The URL ID of a visitor is not
+-- generated, so it does not need the sequence of BIGSERIAL
+ALTER TABLE visitor DROP CONSTRAINT IF EXISTS visitor_url_id_fkey;
+ALTER TABLE visitor ADD CONSTRAINT visitor_url_id_fkey FOREIGN KEY (url_id) REFERENCES url(id) ON DELETE CASCADE;
+ALTER TABLE visitor ALTER COLUMN url_id DROP DEFAULT;
+DROP SEQUENCE IF EXISTS visitor_url_id_seq;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS url_original_url_idx;
+ALTER TABLE url ADD CONSTRAINT url_original_url_key UNIQUE (original_url);
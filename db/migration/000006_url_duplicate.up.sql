@@ -0,0 +1,5 @@
+-- The same URL can be shortened many times
+ALTER TABLE url DROP CONSTRAINT IF EXISTS url_original_url_key;
+
+-- Index for looking up URLs by their original URL, used to detect duplicate
+CREATE INDEX IF NOT EXISTS url_original_url_idx ON url(original_url);
@@ -0,0 +1,6 @@
+ALTER TABLE visitor
+    DROP COLUMN IF EXISTS query_string,
+    DROP COLUMN IF EXISTS host,
+    DROP COLUMN IF EXISTS accept_language,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS referrer;
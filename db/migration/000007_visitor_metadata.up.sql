@@ -0,0 +1,9 @@
+-- Metadata of the visit request
+ALTER TABLE visitor
+    ADD COLUMN IF NOT EXISTS referrer VARCHAR NOT NULL DEFAULT '', -- Referer header, empty if not sent
+    ADD COLUMN IF NOT EXISTS user_agent VARCHAR NOT NULL DEFAULT '', -- User-Agent header
+    ADD COLUMN IF NOT EXISTS accept_language VARCHAR NOT NULL DEFAULT '', -- Accept-Language header
+    -- Host requested by the visitor, useful if the service has many domains
+    ADD COLUMN IF NOT EXISTS host VARCHAR NOT NULL DEFAULT '',
+    -- Query string of the short link (e.g. utm_source=newsletter)
+    ADD COLUMN IF NOT EXISTS query_string VARCHAR NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+ALTER TABLE visitor
+    DROP COLUMN IF EXISTS as_org,
+    DROP COLUMN IF EXISTS asn,
+    DROP COLUMN IF EXISTS city,
+    DROP COLUMN IF EXISTS region,
+    DROP COLUMN IF EXISTS country;
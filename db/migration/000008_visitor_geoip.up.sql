@@ -0,0 +1,7 @@
+-- Location of the visitor, resolved from the IP by GeoIP. Empty or 0 if unknown
+ALTER TABLE visitor
+    ADD COLUMN IF NOT EXISTS country VARCHAR(2) NOT NULL DEFAULT '', -- ISO country code
+    ADD COLUMN IF NOT EXISTS region VARCHAR NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS city VARCHAR NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS asn BIGINT NOT NULL DEFAULT 0, -- Autonomous system number of the IP network
+    ADD COLUMN IF NOT EXISTS as_org VARCHAR NOT NULL DEFAULT ''; -- Organization of the autonomous system
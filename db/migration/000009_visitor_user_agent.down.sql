@@ -0,0 +1,5 @@
+ALTER TABLE visitor
+    DROP COLUMN IF EXISTS device,
+    DROP COLUMN IF EXISTS os,
+    DROP COLUMN IF EXISTS browser_version,
+    DROP COLUMN IF EXISTS browser;
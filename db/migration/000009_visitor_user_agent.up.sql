@@ -0,0 +1,6 @@
+-- Parsed user agent of the visitor
+ALTER TABLE visitor
+    ADD COLUMN IF NOT EXISTS browser VARCHAR NOT NULL DEFAULT '', -- Browser family, or the bot name
+    ADD COLUMN IF NOT EXISTS browser_version VARCHAR NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS os VARCHAR NOT NULL DEFAULT '', -- OS family
+    ADD COLUMN IF NOT EXISTS device VARCHAR NOT NULL DEFAULT ''; -- Device type: desktop, mobile, tablet or bot
@@ -0,0 +1 @@
+ALTER TABLE visitor DROP COLUMN IF EXISTS is_bot;
@@ -0,0 +1,2 @@
+-- Visit from a crawler, link unfurler, HEAD or prefetch request
+ALTER TABLE visitor ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+-- HTTP status used to redirect the visitors of an URL: 301 or 308 (permanent, cached by browsers), 302 or
+-- 307 (temporary). NULL to use the default of the server
+ALTER TABLE url ADD COLUMN IF NOT EXISTS redirect_type INTEGER
+    CONSTRAINT url_redirect_type_check CHECK (redirect_type IN (301, 302, 307, 308));
@@ -0,0 +1,3 @@
+ALTER TABLE url DROP COLUMN IF EXISTS interstitial_seconds;
+ALTER TABLE url DROP COLUMN IF EXISTS preview;
+ALTER TABLE url DROP COLUMN IF EXISTS title;
@@ -0,0 +1,8 @@
+-- Title shown on the preview and interstitial pages of an URL
+ALTER TABLE url ADD COLUMN IF NOT EXISTS title VARCHAR;
+
+-- Allow visitors to see the preview page of the URL (/{code}+) instead of being redirected
+ALTER TABLE url ADD COLUMN IF NOT EXISTS preview BOOLEAN NOT NULL DEFAULT false;
+
+-- Seconds the interstitial page counts down before redirecting the visitors, NULL to redirect directly
+ALTER TABLE url ADD COLUMN IF NOT EXISTS interstitial_seconds INTEGER;
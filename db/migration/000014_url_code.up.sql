@@ -0,0 +1,2 @@
+-- Random short code of the URL, NULL if its code is derived from the ID (see CODE_STRATEGY)
+ALTER TABLE url ADD COLUMN IF NOT EXISTS code VARCHAR(64) CONSTRAINT url_code_key UNIQUE;
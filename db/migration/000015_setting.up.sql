@@ -0,0 +1,9 @@
+-- Settings of the service that must outlive its configuration
+CREATE TABLE IF NOT EXISTS setting (
+    name VARCHAR PRIMARY KEY,
+    value VARCHAR NOT NULL
+);
+
+-- Scheme of the short codes derived from the IDs, see CODE_STRATEGY. The URLs created before the code
+-- strategies have sequential codes
+INSERT INTO setting(name, value) VALUES ('code_scheme', 'sequential:1') ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1 @@
+DELETE FROM setting WHERE name = 'legacy_code_max_id';
@@ -0,0 +1,4 @@
+-- The generated codes now start with a prefix that aliases cannot have. The URLs created before keep their
+-- unprefixed sequential code, which is only valid up to this ID
+INSERT INTO setting(name, value)
+SELECT 'legacy_code_max_id', COALESCE(MAX(id), 0)::VARCHAR FROM url;
@@ -0,0 +1,9 @@
+// Package migration embeds the versioned migrations of the PostgreSQL schema. Each migration is a pair of
+// files <version>_<name>.up.sql and <version>_<name>.down.sql, applied in version order. A schema change
+// is a new pair of files, applied migrations must never be edited
+package migration
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
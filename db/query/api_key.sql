@@ -0,0 +1,22 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_key(name, key_hash, prefix, user_id)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetActiveAPIKeyByHash :one
+SELECT k.*, u.name AS user_name, u.is_admin FROM api_key k
+JOIN "user" u ON u.id = k.user_id
+WHERE k.key_hash = $1 AND k.time_revoked IS NULL;
+
+-- name: ListAPIKey :many
+SELECT * FROM api_key
+ORDER BY id
+OFFSET $1
+LIMIT $2;
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_key SET time_revoked = now()
+WHERE id = $1 AND time_revoked IS NULL;
+
+-- name: DeleteAPIKey :exec
+DELETE FROM api_key WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- name: GetSetting :one
+SELECT value FROM setting
+WHERE name = $1;
+
+-- name: SetSetting :exec
+INSERT INTO setting(name, value)
+VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value;
@@ -1,20 +1,73 @@
 -- name: CreateURL :one
-INSERT INTO url(original_url)
-VALUES ($1)
+INSERT INTO url(
+    original_url, alias, expires_at, max_clicks, fallback_url, owner_id, redirect_type, password_hash,
+    title, preview, interstitial_seconds, code
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 RETURNING *;
 
 -- name: GetURL :one
-SELECT original_url FROM url 
+SELECT * FROM url 
 WHERE id = $1;
 
+-- name: GetURLByAlias :one
+SELECT * FROM url
+WHERE alias = $1;
+
+-- name: GetURLByCode :one
+SELECT * FROM url
+WHERE code = $1;
+
+-- name: ListURLByOriginal :many
+-- List active URLs of an owner with the given original URL, oldest first
+SELECT * FROM url
+WHERE original_url = $1 AND owner_id IS NOT DISTINCT FROM $2 AND time_deleted IS NULL
+ORDER BY id;
+
 -- name: ListURL :many
-SELECT u.*, (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id) AS total_visitors
+-- List URLs of an owner, or all URLs if owner_id is NULL. Human visitors exclude the visits flagged as bot
+SELECT u.*, (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id) AS total_visitors,
+    (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id AND NOT v.is_bot) AS human_visitors
+FROM url u
+WHERE u.time_deleted IS NULL
+AND (sqlc.narg(owner_id)::BIGINT IS NULL OR u.owner_id = sqlc.narg(owner_id))
+ORDER BY u.id
+OFFSET sqlc.arg('offset')
+LIMIT sqlc.arg('limit');
+
+-- name: ExportURL :many
+-- List URLs of an owner (or all URLs if owner_id is NULL) after the given ID, used to stream all URLs
+-- in chunks without OFFSET
+SELECT u.*, (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id) AS total_visitors,
+    (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id AND NOT v.is_bot) AS human_visitors
 FROM url u
-OFFSET $1
-LIMIT $2;
+WHERE u.time_deleted IS NULL
+AND (sqlc.narg(owner_id)::BIGINT IS NULL OR u.owner_id = sqlc.narg(owner_id))
+AND u.id > sqlc.arg(after_id)
+ORDER BY u.id
+LIMIT sqlc.arg('limit');
 
 -- name: CountURL :one
-SELECT COUNT(*) FROM url;
+-- Count URLs of an owner, or all URLs if owner_id is NULL
+SELECT COUNT(*) FROM url
+WHERE time_deleted IS NULL
+AND (sqlc.narg(owner_id)::BIGINT IS NULL OR owner_id = sqlc.narg(owner_id));
+
+-- name: CountURLWithoutCode :one
+-- Count URLs (deleted ones included) whose code is derived from their ID by the code strategy
+SELECT COUNT(*) FROM url
+WHERE code IS NULL;
+
+-- name: UpdateURL :one
+UPDATE url
+SET original_url = $2, alias = $3, expires_at = $4, max_clicks = $5, fallback_url = $6, redirect_type = $7,
+    password_hash = $8, title = $9, preview = $10, interstitial_seconds = $11
+WHERE id = $1 AND time_deleted IS NULL
+RETURNING *;
+
+-- name: SoftDeleteURL :execrows
+UPDATE url SET time_deleted = now()
+WHERE id = $1 AND time_deleted IS NULL;
 
 -- name: DeleteURL :exec
-DELETE FROM url WHERE original_url = $1;
\ No newline at end of file
+DELETE FROM url WHERE id = $1;
\ No newline at end of file
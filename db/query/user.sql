@@ -0,0 +1,17 @@
+-- name: CreateUser :one
+INSERT INTO "user"(name, is_admin)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetUser :one
+SELECT * FROM "user"
+WHERE id = $1;
+
+-- name: ListUser :many
+SELECT * FROM "user"
+ORDER BY id
+OFFSET $1
+LIMIT $2;
+
+-- name: DeleteUser :exec
+DELETE FROM "user" WHERE id = $1;
\ No newline at end of file
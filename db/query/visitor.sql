@@ -1,14 +1,127 @@
 -- name: CreateVisitor :one
-INSERT INTO visitor(ip, url_id)
-VALUES ($1, $2)
+INSERT INTO visitor(ip, url_id, time_visited, referrer, user_agent, accept_language, host, query_string,
+    country, region, city, asn, as_org, browser, browser_version, os, device, is_bot)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 RETURNING *;
 
+-- name: CreateVisitors :execrows
+-- Insert many visitors in one statement, used by the asynchronous visitor recorder. The i-th visitor is
+-- made of the i-th element of each array. Visitors already recorded (same IP, URL and time) or whose URL
+-- no longer exists are skipped, so that one bad visitor does not fail the whole batch
+INSERT INTO visitor(ip, url_id, time_visited, referrer, user_agent, accept_language, host, query_string,
+    country, region, city, asn, as_org, browser, browser_version, os, device, is_bot)
+SELECT v.* FROM (
+  SELECT
+      unnest(sqlc.arg(ips)::VARCHAR[]) AS ip,
+      unnest(sqlc.arg(url_ids)::BIGINT[]) AS url_id,
+      unnest(sqlc.arg(times_visited)::TIMESTAMPTZ[]) AS time_visited,
+      unnest(sqlc.arg(referrers)::VARCHAR[]) AS referrer,
+      unnest(sqlc.arg(user_agents)::VARCHAR[]) AS user_agent,
+      unnest(sqlc.arg(accept_languages)::VARCHAR[]) AS accept_language,
+      unnest(sqlc.arg(hosts)::VARCHAR[]) AS host,
+      unnest(sqlc.arg(query_strings)::VARCHAR[]) AS query_string,
+      unnest(sqlc.arg(countries)::VARCHAR[]) AS country,
+      unnest(sqlc.arg(regions)::VARCHAR[]) AS region,
+      unnest(sqlc.arg(cities)::VARCHAR[]) AS city,
+      unnest(sqlc.arg(asns)::BIGINT[]) AS asn,
+      unnest(sqlc.arg(as_orgs)::VARCHAR[]) AS as_org,
+      unnest(sqlc.arg(browsers)::VARCHAR[]) AS browser,
+      unnest(sqlc.arg(browser_versions)::VARCHAR[]) AS browser_version,
+      unnest(sqlc.arg(oses)::VARCHAR[]) AS os,
+      unnest(sqlc.arg(devices)::VARCHAR[]) AS device,
+      unnest(sqlc.arg(is_bots)::BOOLEAN[]) AS is_bot
+) AS v
+WHERE EXISTS (SELECT 1 FROM url u WHERE u.id = v.url_id)
+ON CONFLICT DO NOTHING;
+
+-- name: CountVisitorForUpdate :one
+-- Lock the URL row and count its human visitors, used to enforce max_clicks atomically with CreateVisitor.
+-- Bot visits do not consume the click budget
+SELECT (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id AND NOT v.is_bot) AS total_visitors
+FROM url u
+WHERE u.id = $1
+FOR UPDATE;
+
 -- name: ListVisitor :many
-SELECT v.ip, v.time_visited, v.url_id, u.original_url FROM visitor v
+-- List visitors of an URL, oldest first. Text filters are case-insensitive LIKE patterns, country, browser,
+-- os and device filters are exact (case-insensitive), time filters select the visits in [from_time, to_time).
+-- NULL filters are ignored
+SELECT v.ip, v.time_visited, v.url_id, v.referrer, v.user_agent, v.accept_language, v.host, v.query_string,
+    v.country, v.region, v.city, v.asn, v.as_org, v.browser, v.browser_version, v.os, v.device,
+    v.is_bot, u.original_url, u.alias, u.code
+FROM visitor v
 JOIN url u ON u.id = v.url_id
-WHERE url_id = $1
-OFFSET $2
-LIMIT $3;
+WHERE v.url_id = sqlc.arg(url_id)
+AND (sqlc.narg(referrer)::VARCHAR IS NULL OR v.referrer ILIKE sqlc.narg(referrer))
+AND (sqlc.narg(user_agent)::VARCHAR IS NULL OR v.user_agent ILIKE sqlc.narg(user_agent))
+AND (sqlc.narg(accept_language)::VARCHAR IS NULL OR v.accept_language ILIKE sqlc.narg(accept_language))
+AND (sqlc.narg(host)::VARCHAR IS NULL OR v.host ILIKE sqlc.narg(host))
+AND (sqlc.narg(query_string)::VARCHAR IS NULL OR v.query_string ILIKE sqlc.narg(query_string))
+AND (sqlc.narg(country)::VARCHAR IS NULL OR v.country = upper(sqlc.narg(country)))
+AND (sqlc.narg(browser)::VARCHAR IS NULL OR lower(v.browser) = lower(sqlc.narg(browser)))
+AND (sqlc.narg(os)::VARCHAR IS NULL OR lower(v.os) = lower(sqlc.narg(os)))
+AND (sqlc.narg(device)::VARCHAR IS NULL OR v.device = lower(sqlc.narg(device)))
+AND (sqlc.narg(is_bot)::BOOLEAN IS NULL OR v.is_bot = sqlc.narg(is_bot))
+AND (sqlc.narg(from_time)::TIMESTAMPTZ IS NULL OR v.time_visited >= sqlc.narg(from_time))
+AND (sqlc.narg(to_time)::TIMESTAMPTZ IS NULL OR v.time_visited < sqlc.narg(to_time))
+ORDER BY v.time_visited, v.ip
+OFFSET sqlc.arg('offset')
+LIMIT sqlc.arg('limit');
+
+-- name: ExportVisitor :many
+-- List visitors of an URL after the given (time_visited, ip), used to stream the whole history in chunks
+-- without OFFSET
+SELECT ip, time_visited, referrer, user_agent, accept_language, host, query_string,
+    country, region, city, asn, as_org, browser, browser_version, os, device, is_bot
+FROM visitor
+WHERE url_id = sqlc.arg(url_id)
+AND (time_visited, ip) > (sqlc.arg(after_time)::TIMESTAMPTZ, sqlc.arg(after_ip)::VARCHAR)
+ORDER BY time_visited, ip
+LIMIT sqlc.arg('limit');
+
+-- name: ListVisitorStats :many
+-- Count the clicks and unique IPs of an URL in [from_time, to_time), bucketed by the given interval
+-- (hour, day, week or month). Buckets are computed in UTC, empty buckets are not returned. Human counts
+-- exclude the visits flagged as bot
+SELECT date_trunc(sqlc.arg(bucket)::TEXT, time_visited, 'UTC')::TIMESTAMPTZ AS bucket_start,
+    COUNT(*) AS clicks,
+    COUNT(DISTINCT ip) AS unique_visitors,
+    COUNT(*) FILTER (WHERE NOT is_bot) AS human_clicks,
+    COUNT(DISTINCT ip) FILTER (WHERE NOT is_bot) AS human_unique_visitors
+FROM visitor
+WHERE url_id = sqlc.arg(url_id)
+AND time_visited >= sqlc.arg(from_time)::TIMESTAMPTZ AND time_visited < sqlc.arg(to_time)::TIMESTAMPTZ
+GROUP BY bucket_start
+ORDER BY bucket_start;
+
+-- name: ListVisitorBreakdown :many
+-- Count the clicks and unique IPs of an URL in [from_time, to_time) grouped by the given dimension
+-- (country, browser, os or device), most clicks first. Unknown values are counted under an empty value
+SELECT (CASE sqlc.arg(dimension)::TEXT
+        WHEN 'country' THEN country
+        WHEN 'browser' THEN browser
+        WHEN 'os' THEN os
+        WHEN 'device' THEN device
+        ELSE ''
+    END)::VARCHAR AS value,
+    COUNT(*) AS clicks,
+    COUNT(DISTINCT ip) AS unique_visitors,
+    COUNT(*) FILTER (WHERE NOT is_bot) AS human_clicks,
+    COUNT(DISTINCT ip) FILTER (WHERE NOT is_bot) AS human_unique_visitors
+FROM visitor
+WHERE url_id = sqlc.arg(url_id)
+AND time_visited >= sqlc.arg(from_time)::TIMESTAMPTZ AND time_visited < sqlc.arg(to_time)::TIMESTAMPTZ
+GROUP BY value
+ORDER BY clicks DESC, value;
+
+-- name: GetVisitorSummary :one
+-- Count the clicks and unique IPs of an URL in [from_time, to_time), with and without bots
+SELECT COUNT(*) AS clicks, COUNT(DISTINCT ip) AS unique_visitors,
+    COUNT(*) FILTER (WHERE NOT is_bot) AS human_clicks,
+    COUNT(DISTINCT ip) FILTER (WHERE NOT is_bot) AS human_unique_visitors
+FROM visitor
+WHERE url_id = sqlc.arg(url_id)
+AND time_visited >= sqlc.arg(from_time)::TIMESTAMPTZ AND time_visited < sqlc.arg(to_time)::TIMESTAMPTZ;
 
 -- name: DeleteVisitor :exec
 DELETE FROM visitor WHERE ip = $1 AND url_id = $2 AND time_visited = $3;
\ No newline at end of file
@@ -0,0 +1,145 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: api_key.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_key(name, key_hash, prefix, user_id)
+VALUES ($1, $2, $3, $4)
+RETURNING id, name, key_hash, prefix, user_id, time_created, time_revoked
+`
+
+type CreateAPIKeyParams struct {
+	Name    string `json:"name"`
+	KeyHash string `json:"key_hash"`
+	Prefix  string `json:"prefix"`
+	UserID  int64  `json:"user_id"`
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, createAPIKey,
+		arg.Name,
+		arg.KeyHash,
+		arg.Prefix,
+		arg.UserID,
+	)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.KeyHash,
+		&i.Prefix,
+		&i.UserID,
+		&i.TimeCreated,
+		&i.TimeRevoked,
+	)
+	return i, err
+}
+
+const deleteAPIKey = `-- name: DeleteAPIKey :exec
+DELETE FROM api_key WHERE id = $1
+`
+
+func (q *Queries) DeleteAPIKey(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteAPIKey, id)
+	return err
+}
+
+const getActiveAPIKeyByHash = `-- name: GetActiveAPIKeyByHash :one
+SELECT k.id, k.name, k.key_hash, k.prefix, k.user_id, k.time_created, k.time_revoked, u.name AS user_name, u.is_admin FROM api_key k
+JOIN "user" u ON u.id = k.user_id
+WHERE k.key_hash = $1 AND k.time_revoked IS NULL
+`
+
+type GetActiveAPIKeyByHashRow struct {
+	ID          int64        `json:"id"`
+	Name        string       `json:"name"`
+	KeyHash     string       `json:"key_hash"`
+	Prefix      string       `json:"prefix"`
+	UserID      int64        `json:"user_id"`
+	TimeCreated time.Time    `json:"time_created"`
+	TimeRevoked sql.NullTime `json:"time_revoked"`
+	UserName    string       `json:"user_name"`
+	IsAdmin     bool         `json:"is_admin"`
+}
+
+func (q *Queries) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (GetActiveAPIKeyByHashRow, error) {
+	row := q.db.QueryRowContext(ctx, getActiveAPIKeyByHash, keyHash)
+	var i GetActiveAPIKeyByHashRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.KeyHash,
+		&i.Prefix,
+		&i.UserID,
+		&i.TimeCreated,
+		&i.TimeRevoked,
+		&i.UserName,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const listAPIKey = `-- name: ListAPIKey :many
+SELECT id, name, key_hash, prefix, user_id, time_created, time_revoked FROM api_key
+ORDER BY id
+OFFSET $1
+LIMIT $2
+`
+
+type ListAPIKeyParams struct {
+	Offset int32 `json:"offset"`
+	Limit  int32 `json:"limit"`
+}
+
+func (q *Queries) ListAPIKey(ctx context.Context, arg ListAPIKeyParams) ([]ApiKey, error) {
+	rows, err := q.db.QueryContext(ctx, listAPIKey, arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ApiKey{}
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.KeyHash,
+			&i.Prefix,
+			&i.UserID,
+			&i.TimeCreated,
+			&i.TimeRevoked,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :execrows
+UPDATE api_key SET time_revoked = now()
+WHERE id = $1 AND time_revoked IS NULL
+`
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeAPIKey, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -5,17 +5,67 @@
 package db
 
 import (
+	"database/sql"
 	"time"
 )
 
+type ApiKey struct {
+	ID          int64        `json:"id"`
+	Name        string       `json:"name"`
+	KeyHash     string       `json:"key_hash"`
+	Prefix      string       `json:"prefix"`
+	UserID      int64        `json:"user_id"`
+	TimeCreated time.Time    `json:"time_created"`
+	TimeRevoked sql.NullTime `json:"time_revoked"`
+}
+
+type Setting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
 type Url struct {
+	ID                  int64          `json:"id"`
+	OriginalUrl         string         `json:"original_url"`
+	TimeCreated         time.Time      `json:"time_created"`
+	Alias               sql.NullString `json:"alias"`
+	ExpiresAt           sql.NullTime   `json:"expires_at"`
+	MaxClicks           sql.NullInt32  `json:"max_clicks"`
+	FallbackUrl         sql.NullString `json:"fallback_url"`
+	OwnerID             sql.NullInt64  `json:"owner_id"`
+	TimeDeleted         sql.NullTime   `json:"time_deleted"`
+	RedirectType        sql.NullInt32  `json:"redirect_type"`
+	PasswordHash        sql.NullString `json:"password_hash"`
+	Title               sql.NullString `json:"title"`
+	Preview             bool           `json:"preview"`
+	InterstitialSeconds sql.NullInt32  `json:"interstitial_seconds"`
+	Code                sql.NullString `json:"code"`
+}
+
+type User struct {
 	ID          int64     `json:"id"`
-	OriginalUrl string    `json:"original_url"`
+	Name        string    `json:"name"`
+	IsAdmin     bool      `json:"is_admin"`
 	TimeCreated time.Time `json:"time_created"`
 }
 
 type Visitor struct {
-	Ip          string    `json:"ip"`
-	UrlID       int64     `json:"url_id"`
-	TimeVisited time.Time `json:"time_visited"`
+	Ip             string    `json:"ip"`
+	UrlID          int64     `json:"url_id"`
+	TimeVisited    time.Time `json:"time_visited"`
+	Referrer       string    `json:"referrer"`
+	UserAgent      string    `json:"user_agent"`
+	AcceptLanguage string    `json:"accept_language"`
+	Host           string    `json:"host"`
+	QueryString    string    `json:"query_string"`
+	Country        string    `json:"country"`
+	Region         string    `json:"region"`
+	City           string    `json:"city"`
+	Asn            int64     `json:"asn"`
+	AsOrg          string    `json:"as_org"`
+	Browser        string    `json:"browser"`
+	BrowserVersion string    `json:"browser_version"`
+	Os             string    `json:"os"`
+	Device         string    `json:"device"`
+	IsBot          bool      `json:"is_bot"`
 }
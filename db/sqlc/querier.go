@@ -0,0 +1,69 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Querier interface {
+	// Count URLs of an owner, or all URLs if owner_id is NULL
+	CountURL(ctx context.Context, ownerID sql.NullInt64) (int64, error)
+	// Count URLs (deleted ones included) whose code is derived from their ID by the code strategy
+	CountURLWithoutCode(ctx context.Context) (int64, error)
+	// Lock the URL row and count its human visitors, used to enforce max_clicks atomically with CreateVisitor.
+	// Bot visits do not consume the click budget
+	CountVisitorForUpdate(ctx context.Context, id int64) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
+	CreateURL(ctx context.Context, arg CreateURLParams) (Url, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVisitor(ctx context.Context, arg CreateVisitorParams) (Visitor, error)
+	// Insert many visitors in one statement, used by the asynchronous visitor recorder. The i-th visitor is
+	// made of the i-th element of each array. Visitors already recorded (same IP, URL and time) or whose URL
+	// no longer exists are skipped, so that one bad visitor does not fail the whole batch
+	CreateVisitors(ctx context.Context, arg CreateVisitorsParams) (int64, error)
+	DeleteAPIKey(ctx context.Context, id int64) error
+	DeleteURL(ctx context.Context, id int64) error
+	DeleteUser(ctx context.Context, id int64) error
+	DeleteVisitor(ctx context.Context, arg DeleteVisitorParams) error
+	// List URLs of an owner (or all URLs if owner_id is NULL) after the given ID, used to stream all URLs
+	// in chunks without OFFSET
+	ExportURL(ctx context.Context, arg ExportURLParams) ([]ExportURLRow, error)
+	// List visitors of an URL after the given (time_visited, ip), used to stream the whole history in chunks
+	// without OFFSET
+	ExportVisitor(ctx context.Context, arg ExportVisitorParams) ([]ExportVisitorRow, error)
+	GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (GetActiveAPIKeyByHashRow, error)
+	GetSetting(ctx context.Context, name string) (string, error)
+	GetURL(ctx context.Context, id int64) (Url, error)
+	GetURLByAlias(ctx context.Context, alias sql.NullString) (Url, error)
+	GetURLByCode(ctx context.Context, code sql.NullString) (Url, error)
+	GetUser(ctx context.Context, id int64) (User, error)
+	// Count the clicks and unique IPs of an URL in [from_time, to_time), with and without bots
+	GetVisitorSummary(ctx context.Context, arg GetVisitorSummaryParams) (GetVisitorSummaryRow, error)
+	ListAPIKey(ctx context.Context, arg ListAPIKeyParams) ([]ApiKey, error)
+	// List URLs of an owner, or all URLs if owner_id is NULL. Human visitors exclude the visits flagged as bot
+	ListURL(ctx context.Context, arg ListURLParams) ([]ListURLRow, error)
+	// List active URLs of an owner with the given original URL, oldest first
+	ListURLByOriginal(ctx context.Context, arg ListURLByOriginalParams) ([]Url, error)
+	ListUser(ctx context.Context, arg ListUserParams) ([]User, error)
+	// List visitors of an URL, oldest first. Text filters are case-insensitive LIKE patterns, country, browser,
+	// os and device filters are exact (case-insensitive), time filters select the visits in [from_time, to_time).
+	// NULL filters are ignored
+	ListVisitor(ctx context.Context, arg ListVisitorParams) ([]ListVisitorRow, error)
+	// Count the clicks and unique IPs of an URL in [from_time, to_time) grouped by the given dimension
+	// (country, browser, os or device), most clicks first. Unknown values are counted under an empty value
+	ListVisitorBreakdown(ctx context.Context, arg ListVisitorBreakdownParams) ([]ListVisitorBreakdownRow, error)
+	// Count the clicks and unique IPs of an URL in [from_time, to_time), bucketed by the given interval
+	// (hour, day, week or month). Buckets are computed in UTC, empty buckets are not returned. Human counts
+	// exclude the visits flagged as bot
+	ListVisitorStats(ctx context.Context, arg ListVisitorStatsParams) ([]ListVisitorStatsRow, error)
+	RevokeAPIKey(ctx context.Context, id int64) (int64, error)
+	SetSetting(ctx context.Context, arg SetSettingParams) error
+	SoftDeleteURL(ctx context.Context, id int64) (int64, error)
+	UpdateURL(ctx context.Context, arg UpdateURLParams) (Url, error)
+}
+
+var _ Querier = (*Queries)(nil)
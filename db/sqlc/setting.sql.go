@@ -0,0 +1,38 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: setting.sql
+
+package db
+
+import (
+	"context"
+)
+
+const getSetting = `-- name: GetSetting :one
+SELECT value FROM setting
+WHERE name = $1
+`
+
+func (q *Queries) GetSetting(ctx context.Context, name string) (string, error) {
+	row := q.db.QueryRowContext(ctx, getSetting, name)
+	var value string
+	err := row.Scan(&value)
+	return value, err
+}
+
+const setSetting = `-- name: SetSetting :exec
+INSERT INTO setting(name, value)
+VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value
+`
+
+type SetSettingParams struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (q *Queries) SetSetting(ctx context.Context, arg SetSettingParams) error {
+	_, err := q.db.ExecContext(ctx, setSetting, arg.Name, arg.Value)
+	return err
+}
@@ -7,75 +7,310 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"time"
 )
 
 const countURL = `-- name: CountURL :one
 SELECT COUNT(*) FROM url
+WHERE time_deleted IS NULL
+AND ($1::BIGINT IS NULL OR owner_id = $1)
 `
 
-func (q *Queries) CountURL(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countURL)
+// Count URLs of an owner, or all URLs if owner_id is NULL
+func (q *Queries) CountURL(ctx context.Context, ownerID sql.NullInt64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countURL, ownerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countURLWithoutCode = `-- name: CountURLWithoutCode :one
+SELECT COUNT(*) FROM url
+WHERE code IS NULL
+`
+
+// Count URLs (deleted ones included) whose code is derived from their ID by the code strategy
+func (q *Queries) CountURLWithoutCode(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countURLWithoutCode)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
 const createURL = `-- name: CreateURL :one
-INSERT INTO url(original_url)
-VALUES ($1)
-RETURNING id, original_url, time_created
+INSERT INTO url(
+    original_url, alias, expires_at, max_clicks, fallback_url, owner_id, redirect_type, password_hash,
+    title, preview, interstitial_seconds, code
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING id, original_url, time_created, alias, expires_at, max_clicks, fallback_url, owner_id, time_deleted, redirect_type, password_hash, title, preview, interstitial_seconds, code
 `
 
-func (q *Queries) CreateURL(ctx context.Context, originalUrl string) (Url, error) {
-	row := q.db.QueryRowContext(ctx, createURL, originalUrl)
+type CreateURLParams struct {
+	OriginalUrl         string         `json:"original_url"`
+	Alias               sql.NullString `json:"alias"`
+	ExpiresAt           sql.NullTime   `json:"expires_at"`
+	MaxClicks           sql.NullInt32  `json:"max_clicks"`
+	FallbackUrl         sql.NullString `json:"fallback_url"`
+	OwnerID             sql.NullInt64  `json:"owner_id"`
+	RedirectType        sql.NullInt32  `json:"redirect_type"`
+	PasswordHash        sql.NullString `json:"password_hash"`
+	Title               sql.NullString `json:"title"`
+	Preview             bool           `json:"preview"`
+	InterstitialSeconds sql.NullInt32  `json:"interstitial_seconds"`
+	Code                sql.NullString `json:"code"`
+}
+
+func (q *Queries) CreateURL(ctx context.Context, arg CreateURLParams) (Url, error) {
+	row := q.db.QueryRowContext(ctx, createURL,
+		arg.OriginalUrl,
+		arg.Alias,
+		arg.ExpiresAt,
+		arg.MaxClicks,
+		arg.FallbackUrl,
+		arg.OwnerID,
+		arg.RedirectType,
+		arg.PasswordHash,
+		arg.Title,
+		arg.Preview,
+		arg.InterstitialSeconds,
+		arg.Code,
+	)
 	var i Url
-	err := row.Scan(&i.ID, &i.OriginalUrl, &i.TimeCreated)
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.TimeCreated,
+		&i.Alias,
+		&i.ExpiresAt,
+		&i.MaxClicks,
+		&i.FallbackUrl,
+		&i.OwnerID,
+		&i.TimeDeleted,
+		&i.RedirectType,
+		&i.PasswordHash,
+		&i.Title,
+		&i.Preview,
+		&i.InterstitialSeconds,
+		&i.Code,
+	)
 	return i, err
 }
 
 const deleteURL = `-- name: DeleteURL :exec
-DELETE FROM url WHERE original_url = $1
+DELETE FROM url WHERE id = $1
 `
 
-func (q *Queries) DeleteURL(ctx context.Context, originalUrl string) error {
-	_, err := q.db.ExecContext(ctx, deleteURL, originalUrl)
+func (q *Queries) DeleteURL(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteURL, id)
 	return err
 }
 
+const exportURL = `-- name: ExportURL :many
+SELECT u.id, u.original_url, u.time_created, u.alias, u.expires_at, u.max_clicks, u.fallback_url, u.owner_id, u.time_deleted, u.redirect_type, u.password_hash, u.title, u.preview, u.interstitial_seconds, u.code, (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id) AS total_visitors,
+    (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id AND NOT v.is_bot) AS human_visitors
+FROM url u
+WHERE u.time_deleted IS NULL
+AND ($1::BIGINT IS NULL OR u.owner_id = $1)
+AND u.id > $2
+ORDER BY u.id
+LIMIT $3
+`
+
+type ExportURLParams struct {
+	OwnerID sql.NullInt64 `json:"owner_id"`
+	AfterID int64         `json:"after_id"`
+	Limit   int32         `json:"limit"`
+}
+
+type ExportURLRow struct {
+	ID                  int64          `json:"id"`
+	OriginalUrl         string         `json:"original_url"`
+	TimeCreated         time.Time      `json:"time_created"`
+	Alias               sql.NullString `json:"alias"`
+	ExpiresAt           sql.NullTime   `json:"expires_at"`
+	MaxClicks           sql.NullInt32  `json:"max_clicks"`
+	FallbackUrl         sql.NullString `json:"fallback_url"`
+	OwnerID             sql.NullInt64  `json:"owner_id"`
+	TimeDeleted         sql.NullTime   `json:"time_deleted"`
+	RedirectType        sql.NullInt32  `json:"redirect_type"`
+	PasswordHash        sql.NullString `json:"password_hash"`
+	Title               sql.NullString `json:"title"`
+	Preview             bool           `json:"preview"`
+	InterstitialSeconds sql.NullInt32  `json:"interstitial_seconds"`
+	Code                sql.NullString `json:"code"`
+	TotalVisitors       int64          `json:"total_visitors"`
+	HumanVisitors       int64          `json:"human_visitors"`
+}
+
+// List URLs of an owner (or all URLs if owner_id is NULL) after the given ID, used to stream all URLs
+// in chunks without OFFSET
+func (q *Queries) ExportURL(ctx context.Context, arg ExportURLParams) ([]ExportURLRow, error) {
+	rows, err := q.db.QueryContext(ctx, exportURL, arg.OwnerID, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ExportURLRow{}
+	for rows.Next() {
+		var i ExportURLRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalUrl,
+			&i.TimeCreated,
+			&i.Alias,
+			&i.ExpiresAt,
+			&i.MaxClicks,
+			&i.FallbackUrl,
+			&i.OwnerID,
+			&i.TimeDeleted,
+			&i.RedirectType,
+			&i.PasswordHash,
+			&i.Title,
+			&i.Preview,
+			&i.InterstitialSeconds,
+			&i.Code,
+			&i.TotalVisitors,
+			&i.HumanVisitors,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getURL = `-- name: GetURL :one
-SELECT original_url FROM url 
+SELECT id, original_url, time_created, alias, expires_at, max_clicks, fallback_url, owner_id, time_deleted, redirect_type, password_hash, title, preview, interstitial_seconds, code FROM url 
 WHERE id = $1
 `
 
-func (q *Queries) GetURL(ctx context.Context, id int64) (string, error) {
+func (q *Queries) GetURL(ctx context.Context, id int64) (Url, error) {
 	row := q.db.QueryRowContext(ctx, getURL, id)
-	var original_url string
-	err := row.Scan(&original_url)
-	return original_url, err
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.TimeCreated,
+		&i.Alias,
+		&i.ExpiresAt,
+		&i.MaxClicks,
+		&i.FallbackUrl,
+		&i.OwnerID,
+		&i.TimeDeleted,
+		&i.RedirectType,
+		&i.PasswordHash,
+		&i.Title,
+		&i.Preview,
+		&i.InterstitialSeconds,
+		&i.Code,
+	)
+	return i, err
+}
+
+const getURLByAlias = `-- name: GetURLByAlias :one
+SELECT id, original_url, time_created, alias, expires_at, max_clicks, fallback_url, owner_id, time_deleted, redirect_type, password_hash, title, preview, interstitial_seconds, code FROM url
+WHERE alias = $1
+`
+
+func (q *Queries) GetURLByAlias(ctx context.Context, alias sql.NullString) (Url, error) {
+	row := q.db.QueryRowContext(ctx, getURLByAlias, alias)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.TimeCreated,
+		&i.Alias,
+		&i.ExpiresAt,
+		&i.MaxClicks,
+		&i.FallbackUrl,
+		&i.OwnerID,
+		&i.TimeDeleted,
+		&i.RedirectType,
+		&i.PasswordHash,
+		&i.Title,
+		&i.Preview,
+		&i.InterstitialSeconds,
+		&i.Code,
+	)
+	return i, err
+}
+
+const getURLByCode = `-- name: GetURLByCode :one
+SELECT id, original_url, time_created, alias, expires_at, max_clicks, fallback_url, owner_id, time_deleted, redirect_type, password_hash, title, preview, interstitial_seconds, code FROM url
+WHERE code = $1
+`
+
+func (q *Queries) GetURLByCode(ctx context.Context, code sql.NullString) (Url, error) {
+	row := q.db.QueryRowContext(ctx, getURLByCode, code)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.TimeCreated,
+		&i.Alias,
+		&i.ExpiresAt,
+		&i.MaxClicks,
+		&i.FallbackUrl,
+		&i.OwnerID,
+		&i.TimeDeleted,
+		&i.RedirectType,
+		&i.PasswordHash,
+		&i.Title,
+		&i.Preview,
+		&i.InterstitialSeconds,
+		&i.Code,
+	)
+	return i, err
 }
 
 const listURL = `-- name: ListURL :many
-SELECT u.id, u.original_url, u.time_created, (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id) AS total_visitors
+SELECT u.id, u.original_url, u.time_created, u.alias, u.expires_at, u.max_clicks, u.fallback_url, u.owner_id, u.time_deleted, u.redirect_type, u.password_hash, u.title, u.preview, u.interstitial_seconds, u.code, (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id) AS total_visitors,
+    (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id AND NOT v.is_bot) AS human_visitors
 FROM url u
-OFFSET $1
-LIMIT $2
+WHERE u.time_deleted IS NULL
+AND ($1::BIGINT IS NULL OR u.owner_id = $1)
+ORDER BY u.id
+OFFSET $2
+LIMIT $3
 `
 
 type ListURLParams struct {
-	Offset int32 `json:"offset"`
-	Limit  int32 `json:"limit"`
+	OwnerID sql.NullInt64 `json:"owner_id"`
+	Offset  int32         `json:"offset"`
+	Limit   int32         `json:"limit"`
 }
 
 type ListURLRow struct {
-	ID            int64     `json:"id"`
-	OriginalUrl   string    `json:"original_url"`
-	TimeCreated   time.Time `json:"time_created"`
-	TotalVisitors int64     `json:"total_visitors"`
+	ID                  int64          `json:"id"`
+	OriginalUrl         string         `json:"original_url"`
+	TimeCreated         time.Time      `json:"time_created"`
+	Alias               sql.NullString `json:"alias"`
+	ExpiresAt           sql.NullTime   `json:"expires_at"`
+	MaxClicks           sql.NullInt32  `json:"max_clicks"`
+	FallbackUrl         sql.NullString `json:"fallback_url"`
+	OwnerID             sql.NullInt64  `json:"owner_id"`
+	TimeDeleted         sql.NullTime   `json:"time_deleted"`
+	RedirectType        sql.NullInt32  `json:"redirect_type"`
+	PasswordHash        sql.NullString `json:"password_hash"`
+	Title               sql.NullString `json:"title"`
+	Preview             bool           `json:"preview"`
+	InterstitialSeconds sql.NullInt32  `json:"interstitial_seconds"`
+	Code                sql.NullString `json:"code"`
+	TotalVisitors       int64          `json:"total_visitors"`
+	HumanVisitors       int64          `json:"human_visitors"`
 }
 
+// List URLs of an owner, or all URLs if owner_id is NULL. Human visitors exclude the visits flagged as bot
 func (q *Queries) ListURL(ctx context.Context, arg ListURLParams) ([]ListURLRow, error) {
-	rows, err := q.db.QueryContext(ctx, listURL, arg.Offset, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, listURL, arg.OwnerID, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +322,71 @@ func (q *Queries) ListURL(ctx context.Context, arg ListURLParams) ([]ListURLRow,
 			&i.ID,
 			&i.OriginalUrl,
 			&i.TimeCreated,
+			&i.Alias,
+			&i.ExpiresAt,
+			&i.MaxClicks,
+			&i.FallbackUrl,
+			&i.OwnerID,
+			&i.TimeDeleted,
+			&i.RedirectType,
+			&i.PasswordHash,
+			&i.Title,
+			&i.Preview,
+			&i.InterstitialSeconds,
+			&i.Code,
 			&i.TotalVisitors,
+			&i.HumanVisitors,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listURLByOriginal = `-- name: ListURLByOriginal :many
+SELECT id, original_url, time_created, alias, expires_at, max_clicks, fallback_url, owner_id, time_deleted, redirect_type, password_hash, title, preview, interstitial_seconds, code FROM url
+WHERE original_url = $1 AND owner_id IS NOT DISTINCT FROM $2 AND time_deleted IS NULL
+ORDER BY id
+`
+
+type ListURLByOriginalParams struct {
+	OriginalUrl string        `json:"original_url"`
+	OwnerID     sql.NullInt64 `json:"owner_id"`
+}
+
+// List active URLs of an owner with the given original URL, oldest first
+func (q *Queries) ListURLByOriginal(ctx context.Context, arg ListURLByOriginalParams) ([]Url, error) {
+	rows, err := q.db.QueryContext(ctx, listURLByOriginal, arg.OriginalUrl, arg.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Url{}
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalUrl,
+			&i.TimeCreated,
+			&i.Alias,
+			&i.ExpiresAt,
+			&i.MaxClicks,
+			&i.FallbackUrl,
+			&i.OwnerID,
+			&i.TimeDeleted,
+			&i.RedirectType,
+			&i.PasswordHash,
+			&i.Title,
+			&i.Preview,
+			&i.InterstitialSeconds,
+			&i.Code,
 		); err != nil {
 			return nil, err
 		}
@@ -101,3 +400,73 @@ func (q *Queries) ListURL(ctx context.Context, arg ListURLParams) ([]ListURLRow,
 	}
 	return items, nil
 }
+
+const softDeleteURL = `-- name: SoftDeleteURL :execrows
+UPDATE url SET time_deleted = now()
+WHERE id = $1 AND time_deleted IS NULL
+`
+
+func (q *Queries) SoftDeleteURL(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteURL, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateURL = `-- name: UpdateURL :one
+UPDATE url
+SET original_url = $2, alias = $3, expires_at = $4, max_clicks = $5, fallback_url = $6, redirect_type = $7,
+    password_hash = $8, title = $9, preview = $10, interstitial_seconds = $11
+WHERE id = $1 AND time_deleted IS NULL
+RETURNING id, original_url, time_created, alias, expires_at, max_clicks, fallback_url, owner_id, time_deleted, redirect_type, password_hash, title, preview, interstitial_seconds, code
+`
+
+type UpdateURLParams struct {
+	ID                  int64          `json:"id"`
+	OriginalUrl         string         `json:"original_url"`
+	Alias               sql.NullString `json:"alias"`
+	ExpiresAt           sql.NullTime   `json:"expires_at"`
+	MaxClicks           sql.NullInt32  `json:"max_clicks"`
+	FallbackUrl         sql.NullString `json:"fallback_url"`
+	RedirectType        sql.NullInt32  `json:"redirect_type"`
+	PasswordHash        sql.NullString `json:"password_hash"`
+	Title               sql.NullString `json:"title"`
+	Preview             bool           `json:"preview"`
+	InterstitialSeconds sql.NullInt32  `json:"interstitial_seconds"`
+}
+
+func (q *Queries) UpdateURL(ctx context.Context, arg UpdateURLParams) (Url, error) {
+	row := q.db.QueryRowContext(ctx, updateURL,
+		arg.ID,
+		arg.OriginalUrl,
+		arg.Alias,
+		arg.ExpiresAt,
+		arg.MaxClicks,
+		arg.FallbackUrl,
+		arg.RedirectType,
+		arg.PasswordHash,
+		arg.Title,
+		arg.Preview,
+		arg.InterstitialSeconds,
+	)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.TimeCreated,
+		&i.Alias,
+		&i.ExpiresAt,
+		&i.MaxClicks,
+		&i.FallbackUrl,
+		&i.OwnerID,
+		&i.TimeDeleted,
+		&i.RedirectType,
+		&i.PasswordHash,
+		&i.Title,
+		&i.Preview,
+		&i.InterstitialSeconds,
+		&i.Code,
+	)
+	return i, err
+}
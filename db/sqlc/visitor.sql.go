@@ -7,27 +7,180 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
+const countVisitorForUpdate = `-- name: CountVisitorForUpdate :one
+SELECT (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id AND NOT v.is_bot) AS total_visitors
+FROM url u
+WHERE u.id = $1
+FOR UPDATE
+`
+
+// Lock the URL row and count its human visitors, used to enforce max_clicks atomically with CreateVisitor.
+// Bot visits do not consume the click budget
+func (q *Queries) CountVisitorForUpdate(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countVisitorForUpdate, id)
+	var total_visitors int64
+	err := row.Scan(&total_visitors)
+	return total_visitors, err
+}
+
 const createVisitor = `-- name: CreateVisitor :one
-INSERT INTO visitor(ip, url_id)
-VALUES ($1, $2)
-RETURNING ip, url_id, time_visited
+INSERT INTO visitor(ip, url_id, time_visited, referrer, user_agent, accept_language, host, query_string,
+    country, region, city, asn, as_org, browser, browser_version, os, device, is_bot)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+RETURNING ip, url_id, time_visited, referrer, user_agent, accept_language, host, query_string, country, region, city, asn, as_org, browser, browser_version, os, device, is_bot
 `
 
 type CreateVisitorParams struct {
-	Ip    string `json:"ip"`
-	UrlID int64  `json:"url_id"`
+	Ip             string    `json:"ip"`
+	UrlID          int64     `json:"url_id"`
+	TimeVisited    time.Time `json:"time_visited"`
+	Referrer       string    `json:"referrer"`
+	UserAgent      string    `json:"user_agent"`
+	AcceptLanguage string    `json:"accept_language"`
+	Host           string    `json:"host"`
+	QueryString    string    `json:"query_string"`
+	Country        string    `json:"country"`
+	Region         string    `json:"region"`
+	City           string    `json:"city"`
+	Asn            int64     `json:"asn"`
+	AsOrg          string    `json:"as_org"`
+	Browser        string    `json:"browser"`
+	BrowserVersion string    `json:"browser_version"`
+	Os             string    `json:"os"`
+	Device         string    `json:"device"`
+	IsBot          bool      `json:"is_bot"`
 }
 
 func (q *Queries) CreateVisitor(ctx context.Context, arg CreateVisitorParams) (Visitor, error) {
-	row := q.db.QueryRowContext(ctx, createVisitor, arg.Ip, arg.UrlID)
+	row := q.db.QueryRowContext(ctx, createVisitor,
+		arg.Ip,
+		arg.UrlID,
+		arg.TimeVisited,
+		arg.Referrer,
+		arg.UserAgent,
+		arg.AcceptLanguage,
+		arg.Host,
+		arg.QueryString,
+		arg.Country,
+		arg.Region,
+		arg.City,
+		arg.Asn,
+		arg.AsOrg,
+		arg.Browser,
+		arg.BrowserVersion,
+		arg.Os,
+		arg.Device,
+		arg.IsBot,
+	)
 	var i Visitor
-	err := row.Scan(&i.Ip, &i.UrlID, &i.TimeVisited)
+	err := row.Scan(
+		&i.Ip,
+		&i.UrlID,
+		&i.TimeVisited,
+		&i.Referrer,
+		&i.UserAgent,
+		&i.AcceptLanguage,
+		&i.Host,
+		&i.QueryString,
+		&i.Country,
+		&i.Region,
+		&i.City,
+		&i.Asn,
+		&i.AsOrg,
+		&i.Browser,
+		&i.BrowserVersion,
+		&i.Os,
+		&i.Device,
+		&i.IsBot,
+	)
 	return i, err
 }
 
+const createVisitors = `-- name: CreateVisitors :execrows
+INSERT INTO visitor(ip, url_id, time_visited, referrer, user_agent, accept_language, host, query_string,
+    country, region, city, asn, as_org, browser, browser_version, os, device, is_bot)
+SELECT v.ip, v.url_id, v.time_visited, v.referrer, v.user_agent, v.accept_language, v.host, v.query_string, v.country, v.region, v.city, v.asn, v.as_org, v.browser, v.browser_version, v.os, v.device, v.is_bot FROM (
+  SELECT
+      unnest($1::VARCHAR[]) AS ip,
+      unnest($2::BIGINT[]) AS url_id,
+      unnest($3::TIMESTAMPTZ[]) AS time_visited,
+      unnest($4::VARCHAR[]) AS referrer,
+      unnest($5::VARCHAR[]) AS user_agent,
+      unnest($6::VARCHAR[]) AS accept_language,
+      unnest($7::VARCHAR[]) AS host,
+      unnest($8::VARCHAR[]) AS query_string,
+      unnest($9::VARCHAR[]) AS country,
+      unnest($10::VARCHAR[]) AS region,
+      unnest($11::VARCHAR[]) AS city,
+      unnest($12::BIGINT[]) AS asn,
+      unnest($13::VARCHAR[]) AS as_org,
+      unnest($14::VARCHAR[]) AS browser,
+      unnest($15::VARCHAR[]) AS browser_version,
+      unnest($16::VARCHAR[]) AS os,
+      unnest($17::VARCHAR[]) AS device,
+      unnest($18::BOOLEAN[]) AS is_bot
+) AS v
+WHERE EXISTS (SELECT 1 FROM url u WHERE u.id = v.url_id)
+ON CONFLICT DO NOTHING
+`
+
+type CreateVisitorsParams struct {
+	Ips             []string    `json:"ips"`
+	UrlIds          []int64     `json:"url_ids"`
+	TimesVisited    []time.Time `json:"times_visited"`
+	Referrers       []string    `json:"referrers"`
+	UserAgents      []string    `json:"user_agents"`
+	AcceptLanguages []string    `json:"accept_languages"`
+	Hosts           []string    `json:"hosts"`
+	QueryStrings    []string    `json:"query_strings"`
+	Countries       []string    `json:"countries"`
+	Regions         []string    `json:"regions"`
+	Cities          []string    `json:"cities"`
+	Asns            []int64     `json:"asns"`
+	AsOrgs          []string    `json:"as_orgs"`
+	Browsers        []string    `json:"browsers"`
+	BrowserVersions []string    `json:"browser_versions"`
+	Oses            []string    `json:"oses"`
+	Devices         []string    `json:"devices"`
+	IsBots          []bool      `json:"is_bots"`
+}
+
+// Insert many visitors in one statement, used by the asynchronous visitor recorder. The i-th visitor is
+// made of the i-th element of each array. Visitors already recorded (same IP, URL and time) or whose URL
+// no longer exists are skipped, so that one bad visitor does not fail the whole batch
+func (q *Queries) CreateVisitors(ctx context.Context, arg CreateVisitorsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createVisitors,
+		pq.Array(arg.Ips),
+		pq.Array(arg.UrlIds),
+		pq.Array(arg.TimesVisited),
+		pq.Array(arg.Referrers),
+		pq.Array(arg.UserAgents),
+		pq.Array(arg.AcceptLanguages),
+		pq.Array(arg.Hosts),
+		pq.Array(arg.QueryStrings),
+		pq.Array(arg.Countries),
+		pq.Array(arg.Regions),
+		pq.Array(arg.Cities),
+		pq.Array(arg.Asns),
+		pq.Array(arg.AsOrgs),
+		pq.Array(arg.Browsers),
+		pq.Array(arg.BrowserVersions),
+		pq.Array(arg.Oses),
+		pq.Array(arg.Devices),
+		pq.Array(arg.IsBots),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteVisitor = `-- name: DeleteVisitor :exec
 DELETE FROM visitor WHERE ip = $1 AND url_id = $2 AND time_visited = $3
 `
@@ -43,29 +196,213 @@ func (q *Queries) DeleteVisitor(ctx context.Context, arg DeleteVisitorParams) er
 	return err
 }
 
+const exportVisitor = `-- name: ExportVisitor :many
+SELECT ip, time_visited, referrer, user_agent, accept_language, host, query_string,
+    country, region, city, asn, as_org, browser, browser_version, os, device, is_bot
+FROM visitor
+WHERE url_id = $1
+AND (time_visited, ip) > ($2::TIMESTAMPTZ, $3::VARCHAR)
+ORDER BY time_visited, ip
+LIMIT $4
+`
+
+type ExportVisitorParams struct {
+	UrlID     int64     `json:"url_id"`
+	AfterTime time.Time `json:"after_time"`
+	AfterIp   string    `json:"after_ip"`
+	Limit     int32     `json:"limit"`
+}
+
+type ExportVisitorRow struct {
+	Ip             string    `json:"ip"`
+	TimeVisited    time.Time `json:"time_visited"`
+	Referrer       string    `json:"referrer"`
+	UserAgent      string    `json:"user_agent"`
+	AcceptLanguage string    `json:"accept_language"`
+	Host           string    `json:"host"`
+	QueryString    string    `json:"query_string"`
+	Country        string    `json:"country"`
+	Region         string    `json:"region"`
+	City           string    `json:"city"`
+	Asn            int64     `json:"asn"`
+	AsOrg          string    `json:"as_org"`
+	Browser        string    `json:"browser"`
+	BrowserVersion string    `json:"browser_version"`
+	Os             string    `json:"os"`
+	Device         string    `json:"device"`
+	IsBot          bool      `json:"is_bot"`
+}
+
+// List visitors of an URL after the given (time_visited, ip), used to stream the whole history in chunks
+// without OFFSET
+func (q *Queries) ExportVisitor(ctx context.Context, arg ExportVisitorParams) ([]ExportVisitorRow, error) {
+	rows, err := q.db.QueryContext(ctx, exportVisitor,
+		arg.UrlID,
+		arg.AfterTime,
+		arg.AfterIp,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ExportVisitorRow{}
+	for rows.Next() {
+		var i ExportVisitorRow
+		if err := rows.Scan(
+			&i.Ip,
+			&i.TimeVisited,
+			&i.Referrer,
+			&i.UserAgent,
+			&i.AcceptLanguage,
+			&i.Host,
+			&i.QueryString,
+			&i.Country,
+			&i.Region,
+			&i.City,
+			&i.Asn,
+			&i.AsOrg,
+			&i.Browser,
+			&i.BrowserVersion,
+			&i.Os,
+			&i.Device,
+			&i.IsBot,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisitorSummary = `-- name: GetVisitorSummary :one
+SELECT COUNT(*) AS clicks, COUNT(DISTINCT ip) AS unique_visitors,
+    COUNT(*) FILTER (WHERE NOT is_bot) AS human_clicks,
+    COUNT(DISTINCT ip) FILTER (WHERE NOT is_bot) AS human_unique_visitors
+FROM visitor
+WHERE url_id = $1
+AND time_visited >= $2::TIMESTAMPTZ AND time_visited < $3::TIMESTAMPTZ
+`
+
+type GetVisitorSummaryParams struct {
+	UrlID    int64     `json:"url_id"`
+	FromTime time.Time `json:"from_time"`
+	ToTime   time.Time `json:"to_time"`
+}
+
+type GetVisitorSummaryRow struct {
+	Clicks              int64 `json:"clicks"`
+	UniqueVisitors      int64 `json:"unique_visitors"`
+	HumanClicks         int64 `json:"human_clicks"`
+	HumanUniqueVisitors int64 `json:"human_unique_visitors"`
+}
+
+// Count the clicks and unique IPs of an URL in [from_time, to_time), with and without bots
+func (q *Queries) GetVisitorSummary(ctx context.Context, arg GetVisitorSummaryParams) (GetVisitorSummaryRow, error) {
+	row := q.db.QueryRowContext(ctx, getVisitorSummary, arg.UrlID, arg.FromTime, arg.ToTime)
+	var i GetVisitorSummaryRow
+	err := row.Scan(
+		&i.Clicks,
+		&i.UniqueVisitors,
+		&i.HumanClicks,
+		&i.HumanUniqueVisitors,
+	)
+	return i, err
+}
+
 const listVisitor = `-- name: ListVisitor :many
-SELECT v.ip, v.time_visited, v.url_id, u.original_url FROM visitor v
+SELECT v.ip, v.time_visited, v.url_id, v.referrer, v.user_agent, v.accept_language, v.host, v.query_string,
+    v.country, v.region, v.city, v.asn, v.as_org, v.browser, v.browser_version, v.os, v.device,
+    v.is_bot, u.original_url, u.alias, u.code
+FROM visitor v
 JOIN url u ON u.id = v.url_id
-WHERE url_id = $1
-OFFSET $2
-LIMIT $3
+WHERE v.url_id = $1
+AND ($2::VARCHAR IS NULL OR v.referrer ILIKE $2)
+AND ($3::VARCHAR IS NULL OR v.user_agent ILIKE $3)
+AND ($4::VARCHAR IS NULL OR v.accept_language ILIKE $4)
+AND ($5::VARCHAR IS NULL OR v.host ILIKE $5)
+AND ($6::VARCHAR IS NULL OR v.query_string ILIKE $6)
+AND ($7::VARCHAR IS NULL OR v.country = upper($7))
+AND ($8::VARCHAR IS NULL OR lower(v.browser) = lower($8))
+AND ($9::VARCHAR IS NULL OR lower(v.os) = lower($9))
+AND ($10::VARCHAR IS NULL OR v.device = lower($10))
+AND ($11::BOOLEAN IS NULL OR v.is_bot = $11)
+AND ($12::TIMESTAMPTZ IS NULL OR v.time_visited >= $12)
+AND ($13::TIMESTAMPTZ IS NULL OR v.time_visited < $13)
+ORDER BY v.time_visited, v.ip
+OFFSET $14
+LIMIT $15
 `
 
 type ListVisitorParams struct {
-	UrlID  int64 `json:"url_id"`
-	Offset int32 `json:"offset"`
-	Limit  int32 `json:"limit"`
+	UrlID          int64          `json:"url_id"`
+	Referrer       sql.NullString `json:"referrer"`
+	UserAgent      sql.NullString `json:"user_agent"`
+	AcceptLanguage sql.NullString `json:"accept_language"`
+	Host           sql.NullString `json:"host"`
+	QueryString    sql.NullString `json:"query_string"`
+	Country        sql.NullString `json:"country"`
+	Browser        sql.NullString `json:"browser"`
+	Os             sql.NullString `json:"os"`
+	Device         sql.NullString `json:"device"`
+	IsBot          sql.NullBool   `json:"is_bot"`
+	FromTime       sql.NullTime   `json:"from_time"`
+	ToTime         sql.NullTime   `json:"to_time"`
+	Offset         int32          `json:"offset"`
+	Limit          int32          `json:"limit"`
 }
 
 type ListVisitorRow struct {
-	Ip          string    `json:"ip"`
-	TimeVisited time.Time `json:"time_visited"`
-	UrlID       int64     `json:"url_id"`
-	OriginalUrl string    `json:"original_url"`
+	Ip             string         `json:"ip"`
+	TimeVisited    time.Time      `json:"time_visited"`
+	UrlID          int64          `json:"url_id"`
+	Referrer       string         `json:"referrer"`
+	UserAgent      string         `json:"user_agent"`
+	AcceptLanguage string         `json:"accept_language"`
+	Host           string         `json:"host"`
+	QueryString    string         `json:"query_string"`
+	Country        string         `json:"country"`
+	Region         string         `json:"region"`
+	City           string         `json:"city"`
+	Asn            int64          `json:"asn"`
+	AsOrg          string         `json:"as_org"`
+	Browser        string         `json:"browser"`
+	BrowserVersion string         `json:"browser_version"`
+	Os             string         `json:"os"`
+	Device         string         `json:"device"`
+	IsBot          bool           `json:"is_bot"`
+	OriginalUrl    string         `json:"original_url"`
+	Alias          sql.NullString `json:"alias"`
+	Code           sql.NullString `json:"code"`
 }
 
+// List visitors of an URL, oldest first. Text filters are case-insensitive LIKE patterns, country, browser,
+// os and device filters are exact (case-insensitive), time filters select the visits in [from_time, to_time).
+// NULL filters are ignored
 func (q *Queries) ListVisitor(ctx context.Context, arg ListVisitorParams) ([]ListVisitorRow, error) {
-	rows, err := q.db.QueryContext(ctx, listVisitor, arg.UrlID, arg.Offset, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, listVisitor,
+		arg.UrlID,
+		arg.Referrer,
+		arg.UserAgent,
+		arg.AcceptLanguage,
+		arg.Host,
+		arg.QueryString,
+		arg.Country,
+		arg.Browser,
+		arg.Os,
+		arg.Device,
+		arg.IsBot,
+		arg.FromTime,
+		arg.ToTime,
+		arg.Offset,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +414,159 @@ func (q *Queries) ListVisitor(ctx context.Context, arg ListVisitorParams) ([]Lis
 			&i.Ip,
 			&i.TimeVisited,
 			&i.UrlID,
+			&i.Referrer,
+			&i.UserAgent,
+			&i.AcceptLanguage,
+			&i.Host,
+			&i.QueryString,
+			&i.Country,
+			&i.Region,
+			&i.City,
+			&i.Asn,
+			&i.AsOrg,
+			&i.Browser,
+			&i.BrowserVersion,
+			&i.Os,
+			&i.Device,
+			&i.IsBot,
 			&i.OriginalUrl,
+			&i.Alias,
+			&i.Code,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVisitorBreakdown = `-- name: ListVisitorBreakdown :many
+SELECT (CASE $1::TEXT
+        WHEN 'country' THEN country
+        WHEN 'browser' THEN browser
+        WHEN 'os' THEN os
+        WHEN 'device' THEN device
+        ELSE ''
+    END)::VARCHAR AS value,
+    COUNT(*) AS clicks,
+    COUNT(DISTINCT ip) AS unique_visitors,
+    COUNT(*) FILTER (WHERE NOT is_bot) AS human_clicks,
+    COUNT(DISTINCT ip) FILTER (WHERE NOT is_bot) AS human_unique_visitors
+FROM visitor
+WHERE url_id = $2
+AND time_visited >= $3::TIMESTAMPTZ AND time_visited < $4::TIMESTAMPTZ
+GROUP BY value
+ORDER BY clicks DESC, value
+`
+
+type ListVisitorBreakdownParams struct {
+	Dimension string    `json:"dimension"`
+	UrlID     int64     `json:"url_id"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+}
+
+type ListVisitorBreakdownRow struct {
+	Value               string `json:"value"`
+	Clicks              int64  `json:"clicks"`
+	UniqueVisitors      int64  `json:"unique_visitors"`
+	HumanClicks         int64  `json:"human_clicks"`
+	HumanUniqueVisitors int64  `json:"human_unique_visitors"`
+}
+
+// Count the clicks and unique IPs of an URL in [from_time, to_time) grouped by the given dimension
+// (country, browser, os or device), most clicks first. Unknown values are counted under an empty value
+func (q *Queries) ListVisitorBreakdown(ctx context.Context, arg ListVisitorBreakdownParams) ([]ListVisitorBreakdownRow, error) {
+	rows, err := q.db.QueryContext(ctx, listVisitorBreakdown,
+		arg.Dimension,
+		arg.UrlID,
+		arg.FromTime,
+		arg.ToTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListVisitorBreakdownRow{}
+	for rows.Next() {
+		var i ListVisitorBreakdownRow
+		if err := rows.Scan(
+			&i.Value,
+			&i.Clicks,
+			&i.UniqueVisitors,
+			&i.HumanClicks,
+			&i.HumanUniqueVisitors,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVisitorStats = `-- name: ListVisitorStats :many
+SELECT date_trunc($1::TEXT, time_visited, 'UTC')::TIMESTAMPTZ AS bucket_start,
+    COUNT(*) AS clicks,
+    COUNT(DISTINCT ip) AS unique_visitors,
+    COUNT(*) FILTER (WHERE NOT is_bot) AS human_clicks,
+    COUNT(DISTINCT ip) FILTER (WHERE NOT is_bot) AS human_unique_visitors
+FROM visitor
+WHERE url_id = $2
+AND time_visited >= $3::TIMESTAMPTZ AND time_visited < $4::TIMESTAMPTZ
+GROUP BY bucket_start
+ORDER BY bucket_start
+`
+
+type ListVisitorStatsParams struct {
+	Bucket   string    `json:"bucket"`
+	UrlID    int64     `json:"url_id"`
+	FromTime time.Time `json:"from_time"`
+	ToTime   time.Time `json:"to_time"`
+}
+
+type ListVisitorStatsRow struct {
+	BucketStart         time.Time `json:"bucket_start"`
+	Clicks              int64     `json:"clicks"`
+	UniqueVisitors      int64     `json:"unique_visitors"`
+	HumanClicks         int64     `json:"human_clicks"`
+	HumanUniqueVisitors int64     `json:"human_unique_visitors"`
+}
+
+// Count the clicks and unique IPs of an URL in [from_time, to_time), bucketed by the given interval
+// (hour, day, week or month). Buckets are computed in UTC, empty buckets are not returned. Human counts
+// exclude the visits flagged as bot
+func (q *Queries) ListVisitorStats(ctx context.Context, arg ListVisitorStatsParams) ([]ListVisitorStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listVisitorStats,
+		arg.Bucket,
+		arg.UrlID,
+		arg.FromTime,
+		arg.ToTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListVisitorStatsRow{}
+	for rows.Next() {
+		var i ListVisitorStatsRow
+		if err := rows.Scan(
+			&i.BucketStart,
+			&i.Clicks,
+			&i.UniqueVisitors,
+			&i.HumanClicks,
+			&i.HumanUniqueVisitors,
 		); err != nil {
 			return nil, err
 		}
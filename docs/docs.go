@@ -15,9 +15,14 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/urls": {
+        "/api/cache": {
             "get": {
-                "description": "Retrieves a paginated list of all shortened URLs in the system.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the size and the hit/miss counters of the in-process cache of redirect lookups,\nsince the server started. Negative hits are lookups of unknown codes answered by the cache.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,9 +30,49 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "urls"
+                    "cache"
                 ],
-                "summary": "List registered URLs",
+                "summary": "Get redirect cache counters",
+                "responses": {
+                    "200": {
+                        "description": "Cache counters",
+                        "schema": {
+                            "$ref": "#/definitions/api.cacheStatsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin permission required",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of API keys, including the revoked ones. Raw keys are never returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api_keys"
+                ],
+                "summary": "List API keys",
                 "parameters": [
                     {
                         "maximum": 100,
@@ -49,11 +94,11 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of shortened URLs",
+                        "description": "List of API keys",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/api.listURLResponse"
+                                "$ref": "#/definitions/api.apiKeyResponse"
                             }
                         }
                     },
@@ -63,6 +108,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResp"
                         }
                     },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin permission required",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -72,7 +129,12 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Takes an original URL, validates it, and stores it in the database.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Generates a new API key for a user. The raw key is only returned in this response, store it safely.",
                 "consumes": [
                     "application/json"
                 ],
@@ -80,29 +142,47 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "urls"
+                    "api_keys"
                 ],
-                "summary": "Create a shortened URL",
+                "summary": "Create an API key",
                 "parameters": [
                     {
-                        "description": "Original URL request",
+                        "description": "API key request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.createShortenURLRequest"
+                            "$ref": "#/definitions/api.createAPIKeyRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Shortened URL created successfully",
+                        "description": "API key created successfully",
                         "schema": {
-                            "$ref": "#/definitions/api.createShortenURLResponse"
+                            "$ref": "#/definitions/api.apiKeyResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid input or URL already exists",
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin permission required",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResp"
                         }
@@ -116,9 +196,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/urls/count": {
-            "get": {
-                "description": "Returns the total number of shortened URLs stored in the system (useful for pagination).",
+        "/api/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revokes an API key, any request using this key will be rejected afterward.",
                 "consumes": [
                     "application/json"
                 ],
@@ -126,14 +211,44 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "urls"
+                    "api_keys"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get total URLs",
                 "responses": {
-                    "200": {
-                        "description": "Total number of URLs",
+                    "204": {
+                        "description": "API key revoked successfully"
+                    },
+                    "400": {
+                        "description": "Invalid API key ID",
                         "schema": {
-                            "$ref": "#/definitions/api.countURLResp"
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin permission required",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found or already revoked",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
                         }
                     },
                     "500": {
@@ -145,9 +260,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/urls/{id}/visitors": {
+        "/api/recorder": {
             "get": {
-                "description": "Retrieves a paginated list of visitors who accessed the given shortened URL.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the queue length and the counters of the asynchronous visitor recorder since the\nserver started. Dropped visitors were not recorded because the queue was full, failed\nvisitors were lost because their batch could not be inserted.",
                 "consumes": [
                     "application/json"
                 ],
@@ -157,15 +277,48 @@ const docTemplate = `{
                 "tags": [
                     "visitors"
                 ],
-                "summary": "List visitors for a shortened URL",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Shortened URL ID (base62 code)",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                "summary": "Get visitor recorder counters",
+                "responses": {
+                    "200": {
+                        "description": "Recorder counters",
+                        "schema": {
+                            "$ref": "#/definitions/api.recorderStatsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
                     },
+                    "403": {
+                        "description": "Admin permission required",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of the caller's shortened URLs (admin sees all URLs in the system).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "List registered URLs",
+                "parameters": [
                     {
                         "maximum": 100,
                         "minimum": 1,
@@ -186,11 +339,11 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of visitors",
+                        "description": "List of shortened URLs",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/api.listVisitorResponse"
+                                "$ref": "#/definitions/api.listURLResponse"
                             }
                         }
                     },
@@ -200,8 +353,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResp"
                         }
                     },
-                    "404": {
-                        "description": "URL ID not found",
+                    "401": {
+                        "description": "Missing or invalid API key",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResp"
                         }
@@ -213,11 +366,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/{code}": {
-            "get": {
-                "description": "Redirects a visitor from the shortened URL code to the original URL and records the visit.",
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Takes an original URL, validates it, and stores it in the database.\nAn optional alias can be provided to use as the shorten code instead of the ID.\nThe URL can also expire at a given time (expires_at) or after a number of visits (max_clicks).\nredirect_type is the status of the redirect: 301 or 308 are permanent and cached by browsers,\n302 or 307 are temporary, so that a change of destination reaches every visitor.\nWith a password, visitors must enter it in a form before being redirected.\nWith preview, the page /{code}+ shows the destination instead of redirecting. With\ninterstitial_seconds, visitors see a page counting down before being redirected.\nIf the caller already shortened the same URL with the same settings, the existing shorten URL\nis returned with status 200, unless allow_duplicate is true.\nURLs are validated and stored in their canonical form (lowercase host, punycode, no default port).\nURLs pointing to blocked domains, private addresses or this service itself are rejected.",
                 "consumes": [
                     "application/json"
                 ],
@@ -227,29 +383,55 @@ const docTemplate = `{
                 "tags": [
                     "urls"
                 ],
-                "summary": "Redirect to original URL",
+                "summary": "Create a shortened URL",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Shortened URL code",
-                        "name": "code",
-                        "in": "path",
-                        "required": true
+                        "description": "Original URL request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.createShortenURLRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "301": {
-                        "description": "Redirected successfully",
+                    "200": {
+                        "description": "Shortened URL already exists",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.createShortenURLResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Shortened URL created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/api.createShortenURLResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid code or URL not found",
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "409": {
+                        "description": "Alias already taken",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResp"
                         }
                     },
+                    "422": {
+                        "description": "URL rejected by the validation pipeline",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResp"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -258,41 +440,1700 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.ErrorResp": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                }
-            }
         },
-        "api.countURLResp": {
-            "type": "object",
-            "properties": {
-                "total_urls": {
-                    "type": "integer"
+        "/api/urls/batch": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Takes a list of create requests, either as a JSON array or as a NDJSON stream (one request per line),\nand creates them in one transaction. Each URL is validated the same way as POST /api/urls and gets its\nown result: the status code it would have with POST /api/urls, and either the shorten URL or the error.\nA rejected URL does not prevent the other URLs from being created.\nThe whole batch counts as a single request for the rate limiter.",
+                "consumes": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Create shortened URLs in batch",
+                "parameters": [
+                    {
+                        "description": "List of original URL requests",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.createShortenURLRequest"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Result of each URL, in the request order",
+                        "schema": {
+                            "$ref": "#/definitions/api.batchCreateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "413": {
+                        "description": "Too many URLs in the batch",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/count": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the total number of the caller's shortened URLs (admin counts all URLs, useful for pagination).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Get total URLs",
+                "responses": {
+                    "200": {
+                        "description": "Total number of URLs",
+                        "schema": {
+                            "$ref": "#/definitions/api.countURLResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Exports all the caller's shortened URLs (admin exports all URLs in the system) as CSV or NDJSON.\nUnlike GET /api/urls, the export is not paginated: the URLs are streamed from the database.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Export URLs",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Exported URLs, one per line",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.exportURLRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid format",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/import": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates shortened URLs from a CSV file with a header row, of at most MAX_BATCH_SIZE rows and 10 MiB.\nRecognized columns: url (or original_url, long_url), alias, expires_at (RFC 3339), max_clicks,\nfallback_url, redirect_type, title (as in Bitly exports), preview and interstitial_seconds,\nother columns are ignored.\nThe CSV export of this service can be imported back.\nBitly-style exports are accepted too: without alias column, the back half of the bitlink\n(or custom bitlink) column is used as alias, so the existing short links keep working.\nEach row gets its own result, the same way as POST /api/urls/batch.",
+                "consumes": [
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Import URLs from CSV",
+                "parameters": [
+                    {
+                        "description": "CSV file",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Result of each row, in the file order",
+                        "schema": {
+                            "$ref": "#/definitions/api.batchCreateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid CSV file",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "413": {
+                        "description": "Too many rows or too large file",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Soft deletes a shortened URL: visiting it answers 410 Gone, while its visitor history is kept.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Delete a shortened URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shortened URL ID (base62 code or alias)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Shortened URL deleted successfully"
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "URL ID not found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Changes the destination and settings of a shortened URL. Absent fields are kept unchanged,\nnull fields are cleared. The url field cannot be cleared.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Update a shortened URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shortened URL ID (base62 code or alias)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.updateShortenURLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Shortened URL updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/api.urlResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "URL ID not found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "409": {
+                        "description": "Alias already taken",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "422": {
+                        "description": "URL rejected by the validation pipeline",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{id}/qr": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Renders the shortened URL (with its alias if it has one) as a QR code, in PNG or SVG.\nThe same image is publicly available at /{code}.png and /{code}.svg.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Get the QR code of a shortened URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID (Base62 code) or alias of the URL",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "png",
+                            "svg"
+                        ],
+                        "type": "string",
+                        "default": "png",
+                        "description": "Image format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 2048,
+                        "minimum": 64,
+                        "type": "integer",
+                        "default": 256,
+                        "description": "Width and height of the image in pixels",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "L",
+                            "M",
+                            "Q",
+                            "H"
+                        ],
+                        "type": "string",
+                        "default": "M",
+                        "description": "Error correction level",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 32,
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 4,
+                        "description": "Quiet zone around the code, in modules",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "000000",
+                        "description": "Foreground color, RRGGBB or RRGGBBAA",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "ffffff",
+                        "description": "Background color, RRGGBB or RRGGBBAA",
+                        "name": "bg",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR code image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid options",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{id}/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Counts the clicks and unique IP addresses of a shortened URL in the time range [from, to),\nbucketed by hour, day, week (starting on Monday) or month. Buckets are computed in UTC, and\nbuckets without clicks are included, so the result can be used for charts directly.\nWithout from, the range covers the last 24 hours, 30 days, 12 weeks or 12 months, depending\non the interval. Clicks are also broken down by country, resolved from the visitor IP by GeoIP.\nHuman counts exclude bot visits (crawlers, link unfurlers, HEAD and prefetch requests).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "visitors"
+                ],
+                "summary": "Get click statistics of a shortened URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shortened URL code or alias",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "hour",
+                            "day",
+                            "week",
+                            "month"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "Bucket interval",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Start of the time range (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "End of the time range (RFC 3339), default to now",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click statistics",
+                        "schema": {
+                            "$ref": "#/definitions/api.statsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{id}/stats/{dimension}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Counts the clicks and unique IP addresses of a shortened URL in the time range [from, to),\ngrouped by country, browser family, OS family or device type (desktop, mobile, tablet or bot),\nmost clicks first. Browser, OS and device are parsed from the User-Agent header of each visit.\nWithout from, the range covers the last 30 days. Human counts exclude bot visits.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "visitors"
+                ],
+                "summary": "Break down the clicks of a shortened URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shortened URL code or alias",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "country",
+                            "browser",
+                            "os",
+                            "device"
+                        ],
+                        "type": "string",
+                        "description": "Dimension to group the clicks by",
+                        "name": "dimension",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Start of the time range (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "End of the time range (RFC 3339), default to now",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click breakdown",
+                        "schema": {
+                            "$ref": "#/definitions/api.breakdownResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{id}/visitors": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of visitors who accessed the given shortened URL, oldest first.\nVisitors can be filtered by request metadata (case-insensitive, matching any part of the value)\nand by visit time.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "visitors"
+                ],
+                "summary": "List visitors for a shortened URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shortened URL ID (base62 code or alias)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Page index (starting from 1)",
+                        "name": "page_index",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only visitors whose Referer header contains this value",
+                        "name": "referrer",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only visitors whose User-Agent header contains this value",
+                        "name": "user_agent",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only visitors whose Accept-Language header contains this value",
+                        "name": "accept_language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only visitors who requested a host containing this value",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only visitors whose query string contains this value",
+                        "name": "query_string",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only visitors from this country (ISO 3166-1 alpha-2 code)",
+                        "name": "country",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only visitors using this browser family, e.g. Chrome",
+                        "name": "browser",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only visitors using this OS family, e.g. Android",
+                        "name": "os",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "desktop",
+                            "mobile",
+                            "tablet",
+                            "bot"
+                        ],
+                        "type": "string",
+                        "description": "Only visitors using this device type",
+                        "name": "device",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only bot visits (true) or human visits (false)",
+                        "name": "bot",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Only visits at or after this time (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Only visits before this time (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of visitors",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.listVisitorResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters or filters",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "URL ID not found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{id}/visitors/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Exports the whole visitor history of a shortened URL as CSV or NDJSON, oldest first.\nUnlike GET /api/urls/{id}/visitors, the export is not paginated: the visitors are streamed\nfrom the database.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "visitors"
+                ],
+                "summary": "Export visitors of a shortened URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shortened URL code or alias",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Exported visitors, one per line",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.exportVisitorRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid format",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of users.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List users",
+                "parameters": [
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Page index (starting from 1)",
+                        "name": "page_index",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of users",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.userResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin permission required",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a new user. Use POST /api/keys afterward to give this user an API key.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Create a user",
+                "parameters": [
+                    {
+                        "description": "User request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.createUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/api.userResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin permission required",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "409": {
+                        "description": "User name already taken",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/{code}": {
+            "get": {
+                "description": "Redirects a visitor from the shortened URL code to the original URL and records the visit.\nThe code is resolved as a custom alias first, then as a random code, then as an ID encoded\nby the code strategy of the server (sequential or obfuscated Base62).\nThe redirect status is the redirect type of the URL, or the default of the server. Permanent\nredirects can be cached by browsers for a day, temporary redirects are never cached.\nPassword-protected URLs answer with a password form (see POST /{code}) until the visitor\nhas entered the password. URLs with an interstitial page answer with a page counting down\nbefore redirecting.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Redirect to original URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shortened URL code or alias",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML password form of a password-protected URL, or interstitial page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "301": {
+                        "description": "Redirected successfully, the status is the redirect type of the URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "302": {
+                        "description": "Redirected successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "307": {
+                        "description": "Redirected successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "308": {
+                        "description": "Redirected successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid code or URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "410": {
+                        "description": "URL has been deleted, has expired or reached its maximum number of visits",
+                        "schema": {
+                            "$ref": "#/definitions/api.goneResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Checks the password entered in the form served by GET /{code}. If it matches, a signed\ncookie grants access to the URL for a while, and the visitor is sent back to GET /{code}\nto be redirected. The number of attempts is limited per URL to slow down guessing.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Enter the password of a protected URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shortened URL code or alias",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of the URL",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "303": {
+                        "description": "Password accepted, redirected to GET /{code}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid code or URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Wrong password, the form is served again",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "URL has been deleted or has expired",
+                        "schema": {
+                            "$ref": "#/definitions/api.goneResp"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many attempts for this URL, the form is served again",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/{code}+": {
+            "get": {
+                "description": "Shows the destination, title, creation date and number of clicks (bots excluded) of a\nshortened URL instead of redirecting, so that visitors can check where it leads. Only\navailable for the URLs with preview enabled, unless the server previews every URL.\nThe destination of a password-protected URL is hidden.\nPreviews are not recorded as visits.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Preview a shortened URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shortened URL code or alias, followed by +",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML preview page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid code or URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Preview not enabled for this URL",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "410": {
+                        "description": "URL has been deleted or has expired",
+                        "schema": {
+                            "$ref": "#/definitions/api.goneResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/{code}.{format}": {
+            "get": {
+                "description": "Renders the shortened URL of the code as a QR code, with the same options as\nGET /api/urls/{id}/qr.\nPassword-protected URLs have a QR code too, which leads to their password form.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Get the QR code of a short code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shortened URL code or alias",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "png",
+                            "svg"
+                        ],
+                        "type": "string",
+                        "description": "Image format",
+                        "name": "format",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 2048,
+                        "minimum": 64,
+                        "type": "integer",
+                        "default": 256,
+                        "description": "Width and height of the image in pixels",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "L",
+                            "M",
+                            "Q",
+                            "H"
+                        ],
+                        "type": "string",
+                        "default": "M",
+                        "description": "Error correction level",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 32,
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 4,
+                        "description": "Quiet zone around the code, in modules",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "000000",
+                        "description": "Foreground color, RRGGBB or RRGGBBAA",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "ffffff",
+                        "description": "Background color, RRGGBB or RRGGBBAA",
+                        "name": "bg",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR code image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid options, invalid code or URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    },
+                    "410": {
+                        "description": "URL has been deleted or has expired",
+                        "schema": {
+                            "$ref": "#/definitions/api.goneResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResp"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.ErrorResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.ValidationErrorResp": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.apiKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.batchCreateResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "existing": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.batchItemResult"
+                    }
+                }
+            }
+        },
+        "api.batchItemResult": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "shorten_url": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.breakdownResponse": {
+            "type": "object",
+            "properties": {
+                "dimension": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.statsGroup"
+                    }
+                },
+                "human_clicks": {
+                    "type": "integer"
+                },
+                "human_unique_visitors": {
+                    "type": "integer"
+                },
+                "shorten": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total_clicks": {
+                    "type": "integer"
+                },
+                "unique_visitors": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.cacheStatsResponse": {
+            "type": "object",
+            "properties": {
+                "capacity": {
+                    "type": "integer"
+                },
+                "evictions": {
+                    "type": "integer"
+                },
+                "hit_ratio": {
+                    "description": "Share of the lookups answered by the cache, 0 if no lookup yet",
+                    "type": "number"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                },
+                "negative_hits": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.countURLResp": {
+            "type": "object",
+            "properties": {
+                "total_urls": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.createAPIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "user_id"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
         "api.createShortenURLRequest": {
             "type": "object",
             "required": [
-                "url"
+                "url"
+            ],
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "allow_duplicate": {
+                    "description": "By default, the existing shorten URL with the same original URL and settings is returned.\nSet this to true to always create a new, independent shorten URL",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "fallback_url": {
+                    "type": "string"
+                },
+                "interstitial_seconds": {
+                    "description": "Seconds the interstitial page counts down before the redirect, the visitors are redirected directly if not set",
+                    "type": "integer",
+                    "maximum": 30
+                },
+                "max_clicks": {
+                    "type": "integer"
+                },
+                "password": {
+                    "description": "Password visitors must enter before being redirected, the URL is public if empty",
+                    "type": "string",
+                    "maxLength": 72
+                },
+                "preview": {
+                    "description": "Allow visitors to see the preview page (/{code}+) of the URL instead of being redirected",
+                    "type": "boolean"
+                },
+                "redirect_type": {
+                    "description": "HTTP status used to redirect the visitors, the default of the server if not set",
+                    "type": "integer",
+                    "enum": [
+                        301,
+                        302,
+                        307,
+                        308
+                    ]
+                },
+                "title": {
+                    "description": "Title shown on the preview and interstitial pages",
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.createShortenURLResponse": {
+            "type": "object",
+            "properties": {
+                "shorten_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.createUserRequest": {
+            "type": "object",
+            "required": [
+                "name"
             ],
             "properties": {
-                "url": {
+                "is_admin": {
+                    "type": "boolean"
+                },
+                "name": {
                     "type": "string"
                 }
             }
         },
-        "api.createShortenURLResponse": {
+        "api.exportURLRecord": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "fallback_url": {
+                    "type": "string"
+                },
+                "human_visitors": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "interstitial_seconds": {
+                    "type": "integer"
+                },
+                "max_clicks": {
+                    "type": "integer"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "preview": {
+                    "type": "boolean"
+                },
+                "redirect_type": {
+                    "type": "integer"
+                },
+                "shorten_url": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "total_visitors": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.exportVisitorRecord": {
             "type": "object",
             "properties": {
+                "accept_language": {
+                    "type": "string"
+                },
+                "as_org": {
+                    "type": "string"
+                },
+                "asn": {
+                    "type": "integer"
+                },
+                "browser": {
+                    "type": "string"
+                },
+                "browser_version": {
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "country": {
+                    "type": "string"
+                },
+                "device": {
+                    "type": "string"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "is_bot": {
+                    "type": "boolean"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "query_string": {
+                    "type": "string"
+                },
+                "referrer": {
+                    "type": "string"
+                },
+                "region": {
+                    "type": "string"
+                },
                 "shorten_url": {
                     "type": "string"
+                },
+                "time_visited": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.goneResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "fallback_url": {
+                    "type": "string"
                 }
             }
         },
@@ -302,12 +2143,38 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
+                "expires_at": {
+                    "type": "string"
+                },
+                "human_visitor": {
+                    "description": "Visits not flagged as bot",
+                    "type": "integer"
+                },
+                "interstitial_seconds": {
+                    "type": "integer"
+                },
+                "max_clicks": {
+                    "type": "integer"
+                },
                 "original": {
                     "type": "string"
                 },
+                "password_protected": {
+                    "type": "boolean"
+                },
+                "preview": {
+                    "type": "boolean"
+                },
+                "redirect_type": {
+                    "description": "Not set if the URL uses the server default",
+                    "type": "integer"
+                },
                 "shorten": {
                     "type": "string"
                 },
+                "title": {
+                    "type": "string"
+                },
                 "total_visitor": {
                     "type": "integer"
                 }
@@ -316,20 +2183,281 @@ const docTemplate = `{
         "api.listVisitorResponse": {
             "type": "object",
             "properties": {
+                "accept_language": {
+                    "type": "string"
+                },
+                "as_org": {
+                    "type": "string"
+                },
+                "asn": {
+                    "type": "integer"
+                },
+                "browser": {
+                    "type": "string"
+                },
+                "browser_version": {
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "country": {
+                    "type": "string"
+                },
+                "device": {
+                    "type": "string"
+                },
+                "host": {
+                    "type": "string"
+                },
                 "ip": {
                     "type": "string"
                 },
+                "is_bot": {
+                    "type": "boolean"
+                },
                 "original": {
                     "type": "string"
                 },
+                "os": {
+                    "type": "string"
+                },
+                "query_string": {
+                    "type": "string"
+                },
+                "referrer": {
+                    "type": "string"
+                },
+                "region": {
+                    "type": "string"
+                },
                 "shorten": {
                     "type": "string"
                 },
                 "time_visited": {
                     "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.recorderStatsResponse": {
+            "type": "object",
+            "properties": {
+                "async": {
+                    "description": "False if visitors are recorded synchronously, other counters are 0",
+                    "type": "boolean"
+                },
+                "capacity": {
+                    "type": "integer"
+                },
+                "dropped": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "queued": {
+                    "type": "integer"
+                },
+                "recorded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.statsBucket": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "human_clicks": {
+                    "type": "integer"
+                },
+                "human_unique_visitors": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "unique_visitors": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.statsGroup": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "human_clicks": {
+                    "type": "integer"
+                },
+                "human_unique_visitors": {
+                    "type": "integer"
+                },
+                "unique_visitors": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.statsResponse": {
+            "type": "object",
+            "properties": {
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.statsBucket"
+                    }
+                },
+                "countries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.statsGroup"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "human_clicks": {
+                    "type": "integer"
+                },
+                "human_unique_visitors": {
+                    "type": "integer"
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "shorten": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total_clicks": {
+                    "type": "integer"
+                },
+                "unique_visitors": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.updateShortenURLRequest": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "fallback_url": {
+                    "type": "string"
+                },
+                "interstitial_seconds": {
+                    "description": "Null to redirect the visitors directly",
+                    "type": "integer",
+                    "maximum": 30
+                },
+                "max_clicks": {
+                    "type": "integer"
+                },
+                "password": {
+                    "description": "Null or empty to remove the password. Changing the password revokes the access of the visitors",
+                    "type": "string"
+                },
+                "preview": {
+                    "description": "Null to disable the preview page",
+                    "type": "boolean"
+                },
+                "redirect_type": {
+                    "description": "Null to use the default of the server",
+                    "type": "integer",
+                    "enum": [
+                        301,
+                        302,
+                        307,
+                        308
+                    ]
+                },
+                "title": {
+                    "description": "Null or empty to remove the title",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.urlResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "fallback_url": {
+                    "type": "string"
+                },
+                "interstitial_seconds": {
+                    "type": "integer"
+                },
+                "max_clicks": {
+                    "type": "integer"
+                },
+                "original": {
+                    "type": "string"
+                },
+                "password_protected": {
+                    "type": "boolean"
+                },
+                "preview": {
+                    "type": "boolean"
+                },
+                "redirect_type": {
+                    "description": "Not set if the URL uses the server default",
+                    "type": "integer"
+                },
+                "shorten": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.userResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_admin": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        }
     }
 }`
 
@@ -3,22 +3,34 @@
 // @description This is the API for URL Shortener service
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 package main
 
 import (
-	"database/sql"
+	"context"
 	"log/slog"
 	"os"
-
-	_ "github.com/lib/pq"
+	"os/signal"
+	"syscall"
 
 	"github.com/danglnh07/URLShortener/api"
 	"github.com/danglnh07/URLShortener/service"
+	"github.com/danglnh07/URLShortener/store"
 )
 
 func main() {
-	// Initialize logger
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	// Initialize logger. Subcommands may write their output to stdout, so they log to stderr instead
+	command := "serve"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+	logOutput := os.Stdout
+	if command != "serve" {
+		logOutput = os.Stderr
+	}
+	logger := slog.New(slog.NewTextHandler(logOutput, nil))
 
 	// Load config
 	err := service.LoadConfig(".env", logger)
@@ -29,15 +41,54 @@ func main() {
 	config := service.GetConfig()
 
 	// Connect to database
-	conn, err := sql.Open(config.DbDriver, config.DbSource)
+	storage, err := store.Open(config.DbDriver, config.DbSource)
 	if err != nil {
 		logger.Error("Failed to connect to database", "error", err)
 		os.Exit(1)
 	}
+	defer storage.Close()
+
+	// Apply the schema migrations, on demand or at startup
+	if command == "migrate" {
+		if err := runMigrate(storage, os.Args[2:]); err != nil {
+			logger.Error("Command failed", "command", command, "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if config.AutoMigrate {
+		applied, err := store.MigrateUp(context.Background(), storage)
+		if err != nil {
+			logger.Error("Failed to migrate database", "error", err)
+			os.Exit(1)
+		}
+		for _, migration := range applied {
+			logger.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+		}
+	}
+
+	// Initialize server
+	server := api.NewServer(&config, storage, logger)
+
+	// Load the legacy codes, the code strategy must keep the codes of the existing URLs
+	if err := server.InitCodes(context.Background()); err != nil {
+		logger.Error("Invalid code strategy", "error", err)
+		os.Exit(1)
+	}
+
+	// Run the subcommand, if any
+	if command != "serve" {
+		if err := runCommand(server, os.Args[1:]); err != nil {
+			logger.Error("Command failed", "command", command, "error", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	// Initialize and start server
-	server := api.NewServer(&config, conn, logger)
-	err = server.Start()
+	// Start server, until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = server.Start(ctx)
 	if err != nil {
 		logger.Error("Server failed to start or unexpectedly shutdown", "error", err)
 		os.Exit(1)
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Alias must start with a letter or digit, followed by letters, digits, '-' or '_'
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{2,63}$`)
+
+// Reserved words that cannot be used as alias, since they would shadow other routes
+var reservedAliases = map[string]bool{
+	"api":     true,
+	"swagger": true,
+	"docs":    true,
+	"static":  true,
+	"health":  true,
+	"admin":   true,
+}
+
+// Check if an alias is valid to be used as a custom code for shorten URL
+func ValidateAlias(alias string) error {
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("alias must be 3 to 64 characters long, contain only letters, digits, " +
+			"'-' or '_', and start with a letter or digit")
+	}
+
+	if IsReservedAlias(alias) {
+		return fmt.Errorf("alias '%s' is reserved", alias)
+	}
+
+	return nil
+}
+
+// Check if an alias is one of the reserved words (case insensitive)
+func IsReservedAlias(alias string) bool {
+	return reservedAliases[strings.ToLower(alias)]
+}
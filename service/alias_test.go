@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAlias(t *testing.T) {
+	// Valid aliases
+	valid := []string{"spring-sale", "Summer_2025", "abc", "a1-b2_c3"}
+	for _, alias := range valid {
+		require.NoError(t, ValidateAlias(alias), alias)
+	}
+
+	// Invalid aliases: too short, too long, invalid characters, reserved words
+	invalid := []string{
+		"ab",
+		"-sale",
+		"spring sale",
+		"spring/sale",
+		"sale.png",
+		"api",
+		"Swagger",
+		string(make([]byte, 65)),
+	}
+	for _, alias := range invalid {
+		require.Error(t, ValidateAlias(alias), alias)
+	}
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	// Prefix of every API key, make it easy to recognize a key (for example, in secret scanners)
+	apiKeyPrefix = "usk_"
+
+	// Number of random bytes in an API key
+	apiKeyBytes = 32
+
+	// Number of characters of the key kept in plain text to help identify it
+	APIKeyDisplayLength = 12
+)
+
+// Generate a new random API key. The raw key is returned only once and should never be stored
+func GenerateAPIKey() (string, error) {
+	buffer := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(buffer), nil
+}
+
+// Hash an API key with SHA-256. Since the key is random with high entropy, a fast hash is enough
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
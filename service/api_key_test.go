@@ -0,0 +1,29 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	cases := 10
+	keys := make(map[string]bool)
+	for range cases {
+		key, err := GenerateAPIKey()
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(key, apiKeyPrefix))
+		require.Len(t, key, len(apiKeyPrefix)+apiKeyBytes*2)
+
+		// Keys should never repeat
+		require.False(t, keys[key])
+		keys[key] = true
+
+		// Hash is deterministic and 64 hex characters long
+		hash := HashAPIKey(key)
+		require.Len(t, hash, 64)
+		require.Equal(t, hash, HashAPIKey(key))
+		require.NotEqual(t, hash, HashAPIKey(key+"x"))
+	}
+}
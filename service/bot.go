@@ -0,0 +1,53 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+)
+
+// BotDetector decides if a visit comes from a bot (crawler, link unfurler, uptime checker...) instead of
+// a human, so that bot visits do not inflate the click counts
+type BotDetector struct {
+	patterns []string // Lowercased substrings of the user agents of additional bots
+}
+
+// Constructor method for BotDetector. The patterns are case-insensitive substrings of user agents to
+// treat as bots, in addition to the embedded user agent rules
+func NewBotDetector(patterns []string) *BotDetector {
+	detector := &BotDetector{}
+	for _, pattern := range patterns {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			detector.patterns = append(detector.patterns, pattern)
+		}
+	}
+	return detector
+}
+
+// Check if the request comes from a bot. A request is a bot visit if:
+//   - It is a HEAD request, which link checkers use to follow the redirect without loading the page
+//   - It is a prefetch or preview request (Purpose, Sec-Purpose, X-Purpose or X-Moz header)
+//   - Its user agent is a bot by the embedded rules, or contains one of the configured patterns
+func (detector *BotDetector) IsBot(r *http.Request, agent UserAgentInfo) bool {
+	if r.Method == http.MethodHead {
+		return true
+	}
+
+	for _, header := range []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"} {
+		value := strings.ToLower(r.Header.Get(header))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+
+	if agent.Device == DeviceBot {
+		return true
+	}
+
+	ua := strings.ToLower(r.UserAgent())
+	for _, pattern := range detector.patterns {
+		if strings.Contains(ua, pattern) {
+			return true
+		}
+	}
+	return false
+}
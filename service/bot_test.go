@@ -0,0 +1,41 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBotDetector(t *testing.T) {
+	detector := NewBotDetector([]string{" Pingdom ", ""})
+	chrome := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
+		"Chrome/124.0.0.0 Safari/537.36"
+
+	// Helper to classify a request with the given method, user agent and extra header
+	isBot := func(method, ua string, header ...string) bool {
+		r := httptest.NewRequest(method, "/abc", nil)
+		r.Header.Set("User-Agent", ua)
+		if len(header) == 2 {
+			r.Header.Set(header[0], header[1])
+		}
+		return detector.IsBot(r, ParseUserAgent(ua))
+	}
+
+	// Human visit
+	require.False(t, isBot(http.MethodGet, chrome))
+
+	// Crawlers and link unfurlers
+	require.True(t, isBot(http.MethodGet, "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"))
+	require.True(t, isBot(http.MethodGet, "Twitterbot/1.0"))
+	require.True(t, isBot(http.MethodGet, ""))
+
+	// HEAD and prefetch requests, even from a browser
+	require.True(t, isBot(http.MethodHead, chrome))
+	require.True(t, isBot(http.MethodGet, chrome, "Sec-Purpose", "prefetch;prerender"))
+	require.True(t, isBot(http.MethodGet, chrome, "X-Purpose", "preview"))
+
+	// Configured patterns are case-insensitive
+	require.True(t, isBot(http.MethodGet, "pingdom.com_uptime_check (+http://www.pingdom.com/)"))
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Counters of a cache, since it was created
+type CacheStats struct {
+	Size         int    // Number of entries currently in the cache, including negative entries
+	Capacity     int    // Maximum number of entries
+	Hits         uint64 // Lookups answered with a cached value
+	NegativeHits uint64 // Lookups answered with a cached "not found"
+	Misses       uint64 // Lookups not found in the cache, or expired
+	Evictions    uint64 // Entries removed to make room for new entries
+}
+
+// An entry of the cache. A negative entry records that the key does not exist
+type cacheEntry[V any] struct {
+	key     string
+	value   V
+	found   bool
+	expires time.Time
+}
+
+// Cache is a bounded in-memory cache with least recently used eviction and a time to live for each
+// entry. Keys that are known to not exist can be cached too (negative caching), usually with a shorter
+// time to live. It is safe for concurrent use.
+//
+// A value read from the source may be stale by the time it is stored, if the source changed and the key
+// was deleted in between. To not store it back, the caller gets the version of the cache before reading
+// the source, and the value is only stored if no key was deleted since that version
+type Cache[V any] struct {
+	mu          sync.Mutex
+	version     uint64 // Incremented by every Delete
+	capacity    int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	items       map[string]*list.Element
+	order       *list.List // Most recently used entries first
+	stats       CacheStats
+	now         func() time.Time
+}
+
+// Constructor method for Cache
+func NewCache[V any](capacity int, ttl, negativeTTL time.Duration) *Cache[V] {
+	return &Cache[V]{
+		capacity:    capacity,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		items:       make(map[string]*list.Element),
+		order:       list.New(),
+		stats:       CacheStats{Capacity: capacity},
+		now:         time.Now,
+	}
+}
+
+// Look up a key. The second result reports whether the key exists, and the third result whether the
+// cache knows the answer at all. If it is false, the caller must look up the source and fill the cache
+func (cache *Cache[V]) Get(key string) (V, bool, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var zero V
+	element, ok := cache.items[key]
+	if !ok {
+		cache.stats.Misses++
+		return zero, false, false
+	}
+
+	entry := element.Value.(*cacheEntry[V])
+	if !cache.now().Before(entry.expires) {
+		cache.removeElement(element)
+		cache.stats.Misses++
+		return zero, false, false
+	}
+
+	cache.order.MoveToFront(element)
+	if !entry.found {
+		cache.stats.NegativeHits++
+		return zero, false, true
+	}
+	cache.stats.Hits++
+	return entry.value, true, true
+}
+
+// Get the current version of the cache, to get before reading the source of a key
+func (cache *Cache[V]) Version() uint64 {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	return cache.version
+}
+
+// Store the value of a key, read from the source at the given version
+func (cache *Cache[V]) Set(key string, value V, version uint64) {
+	cache.set(&cacheEntry[V]{key: key, value: value, found: true, expires: cache.now().Add(cache.ttl)}, version)
+}
+
+// Record that a key does not exist, according to the source at the given version
+func (cache *Cache[V]) SetMissing(key string, version uint64) {
+	cache.set(&cacheEntry[V]{key: key, expires: cache.now().Add(cache.negativeTTL)}, version)
+}
+
+// Remove the keys from the cache, so that the next lookups go to the source. The lookups in progress
+// are not stored either
+func (cache *Cache[V]) Delete(keys ...string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.version++
+	for _, key := range keys {
+		if element, ok := cache.items[key]; ok {
+			cache.removeElement(element)
+		}
+	}
+}
+
+// Get the counters of the cache
+func (cache *Cache[V]) Stats() CacheStats {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	stats := cache.stats
+	stats.Size = cache.order.Len()
+	return stats
+}
+
+// Helper method to insert or replace an entry, evicting the least recently used entries if full.
+// A cache without capacity stores nothing, and neither does a cache with keys deleted since version
+func (cache *Cache[V]) set(entry *cacheEntry[V], version uint64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.capacity <= 0 || cache.version != version {
+		return
+	}
+
+	if element, ok := cache.items[entry.key]; ok {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	for cache.order.Len() >= cache.capacity {
+		cache.removeElement(cache.order.Back())
+		cache.stats.Evictions++
+	}
+	cache.items[entry.key] = cache.order.PushFront(entry)
+}
+
+// Helper method to remove an element, the lock must be held
+func (cache *Cache[V]) removeElement(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.items, element.Value.(*cacheEntry[V]).key)
+}
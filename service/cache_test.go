@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	cache := NewCache[int](2, time.Minute, 10*time.Second)
+	cache.now = func() time.Time { return now }
+
+	// Unknown key is a miss
+	_, _, ok := cache.Get("a")
+	require.False(t, ok)
+
+	// Cached value and cached "not found"
+	cache.Set("a", 1, cache.Version())
+	cache.SetMissing("b", cache.Version())
+	value, found, ok := cache.Get("a")
+	require.True(t, ok)
+	require.True(t, found)
+	require.Equal(t, 1, value)
+
+	_, found, ok = cache.Get("b")
+	require.True(t, ok)
+	require.False(t, found)
+
+	// The least recently used entry is evicted when full
+	cache.Get("a")
+	cache.Set("c", 3, cache.Version())
+	_, _, ok = cache.Get("b")
+	require.False(t, ok)
+	_, _, ok = cache.Get("a")
+	require.True(t, ok)
+
+	// Negative entries expire sooner than values
+	cache.SetMissing("b", cache.Version())
+	now = now.Add(30 * time.Second)
+	_, _, ok = cache.Get("b")
+	require.False(t, ok)
+	_, _, ok = cache.Get("a")
+	require.True(t, ok)
+
+	now = now.Add(time.Minute)
+	_, _, ok = cache.Get("a")
+	require.False(t, ok)
+
+	// Deleted keys go back to the source
+	cache.Set("a", 2, cache.Version())
+	cache.Delete("a", "unknown")
+	_, _, ok = cache.Get("a")
+	require.False(t, ok)
+
+	// Values read from the source before a deletion are not stored, they may be stale
+	version := cache.Version()
+	cache.Delete("b")
+	cache.Set("a", 3, version)
+	cache.SetMissing("b", version)
+	_, _, ok = cache.Get("a")
+	require.False(t, ok)
+	_, _, ok = cache.Get("b")
+	require.False(t, ok)
+
+	require.Equal(t, CacheStats{
+		Size:         0,
+		Capacity:     2,
+		Hits:         4,
+		NegativeHits: 1,
+		Misses:       7,
+		Evictions:    2,
+	}, cache.Stats())
+}
@@ -3,8 +3,11 @@ package service
 import (
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -15,13 +18,97 @@ type Config struct {
 	// Server config
 	BaseURL string
 
-	// Database config
+	// Database config. The driver is postgres, sqlite or memory, the source is the connection string for
+	// postgres and the database file for sqlite
 	DbDriver string
 	DbSource string
 
-	// Rate limiter config
-	MaxRequest int
-	RefillRate time.Duration
+	// Apply the pending schema migrations at startup
+	AutoMigrate bool
+
+	// Rate limiter config. Every client (IP address, then API key once authenticated) has its own token bucket,
+	// MaxRequest/RefillRate apply to the management API and RedirectMaxRequest/RedirectRefillRate to the
+	// redirects. At most RateLimitMaxClients buckets are kept for each of them
+	MaxRequest          int
+	RefillRate          time.Duration
+	RedirectMaxRequest  int
+	RedirectRefillRate  time.Duration
+	RateLimitMaxClients int
+
+	// Trust the X-Forwarded-For header for the client IP address, only if the server is behind a proxy
+	TrustForwardedFor bool
+
+	// Redirect status of the URLs without their own redirect type: 301, 302, 307 or 308
+	DefaultRedirectType int
+
+	// Authentication config: the bootstrap admin API key, used to create the other API keys
+	AdminAPIKey string
+
+	// Password-protected URL config: the secret signing the access cookies (random on every start if empty),
+	// how long a visitor keeps access after entering the password, and the password attempts allowed
+	// per URL: up to max attempts in a row, then one more every attempt refill
+	LinkCookieSecret      string
+	LinkAccessTTL         time.Duration
+	PasswordMaxAttempts   int
+	PasswordAttemptRefill time.Duration
+
+	// Serve the preview page (/{code}+) of every URL, not only the URLs with preview enabled
+	PreviewAllURLs bool
+
+	// Short code config of the URLs without alias: the strategy (sequential, obfuscated or random), the
+	// minimum length of the codes (the default of the strategy if 0), and the secret of the obfuscated codes.
+	// The codes derived from the IDs change with these settings, so the server refuses to start if they differ
+	// from the settings of the existing URLs, see Server.InitCodes
+	CodeStrategy  string
+	CodeMinLength int
+	CodeSecret    string
+
+	// URL validation config
+	AllowedSchemes  []string // Schemes allowed for original URLs
+	SortQueryParams bool     // Sort query parameters of original URLs, for better duplicate detection
+
+	// Destination policy config
+	BlockedDomains           []string // Domains (and their subdomains) that cannot be shortened
+	AllowedDomains           []string // If not empty, only these domains (and their subdomains) can be shortened
+	AllowPrivateDestinations bool     // Allow URLs pointing to private, loopback or link-local addresses
+	ResolveDestinations      bool     // Resolve host names to check if they point to private addresses
+
+	// Maximum number of URLs in a batch create request
+	MaxBatchSize int
+
+	// GeoIP config: MaxMind-format (mmdb) database files, checked for changes every reload interval
+	GeoIPDatabases      []string
+	GeoIPReloadInterval time.Duration
+
+	// Bot detection config
+	BotUserAgents   []string // Substrings of user agents treated as bots, in addition to the built-in rules
+	RecordBotVisits bool     // Record the visits of bots (flagged as bot), otherwise they are not stored
+
+	// Redirect cache config: maximum number of cached codes (0 disables the cache), and how long a found or
+	// unknown code is cached
+	URLCacheSize        int
+	URLCacheTTL         time.Duration
+	URLCacheNegativeTTL time.Duration
+
+	// Asynchronous visitor recording config. If the queue size is 0, visitors are recorded synchronously
+	VisitorQueueSize     int
+	VisitorBatchSize     int
+	VisitorFlushInterval time.Duration
+	VisitorWorkers       int
+}
+
+// Default maximum number of URLs in a batch create request
+const DefaultMaxBatchSize = 1000
+
+// HTTP statuses that can be used to redirect the visitors of an URL. 301 and 308 are permanent and cached
+// by browsers, 302 and 307 are temporary
+var RedirectTypes = []int{
+	http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect,
+}
+
+// Check if the status is a redirect type
+func IsRedirectType(status int) bool {
+	return slices.Contains(RedirectTypes, status)
 }
 
 var config Config
@@ -33,18 +120,19 @@ func LoadConfig(path string, logger *slog.Logger) error {
 	if err != nil {
 		logger.Warn("Found no .env file. Start using default configuration", "error", err)
 
-		// This value is necessary to connect to database, cannot really have a default value
-		if os.Getenv("DB_SOURCE") == "" {
+		// This value is necessary to connect to PostgreSQL, cannot really have a default value
+		if getEnvDriver() == "postgres" && os.Getenv("DB_SOURCE") == "" {
 			logger.Error("Found no value for DB_SOURCE")
 			return fmt.Errorf("no value for DB_SOURCE, cannot connect to database")
 		}
 
 		config = Config{
-			BaseURL:    "localhost:8080",
-			DbDriver:   "postgres",
-			DbSource:   os.Getenv("DB_SOURCE"),
-			MaxRequest: 100,
-			RefillRate: 10 * time.Second,
+			BaseURL:     "localhost:8080",
+			DbDriver:    "postgres",
+			DbSource:    os.Getenv("DB_SOURCE"),
+			MaxRequest:  100,
+			RefillRate:  10 * time.Second,
+			AdminAPIKey: os.Getenv("ADMIN_API_KEY"),
 		}
 	}
 
@@ -62,16 +150,176 @@ func LoadConfig(path string, logger *slog.Logger) error {
 		refileRate = 10
 	}
 
+	// The redirects use the same limits as the management API unless configured
+	redirectMaxRequest := getEnvInt("REDIRECT_MAX_REQUEST", maxRequest, logger)
+	redirectRefillRate := getEnvInt("REDIRECT_REFILL_RATE", refileRate, logger)
+
+	// The default redirect type must be a redirect status
+	defaultRedirectType := getEnvInt("DEFAULT_REDIRECT_TYPE", http.StatusMovedPermanently, logger)
+	if !IsRedirectType(defaultRedirectType) {
+		logger.Warn("Invalid value for DEFAULT_REDIRECT_TYPE. Start using default value",
+			"value", defaultRedirectType)
+		defaultRedirectType = http.StatusMovedPermanently
+	}
+
+	// The codes of the existing URLs depend on the code strategy, so a wrong value cannot fall back to a default
+	codeStrategy := strings.ToLower(strings.TrimSpace(os.Getenv("CODE_STRATEGY")))
+	if !IsCodeStrategy(codeStrategy) {
+		logger.Error("Invalid value for CODE_STRATEGY", "value", codeStrategy)
+		return fmt.Errorf("invalid code strategy %q, must be sequential, obfuscated or random", codeStrategy)
+	}
+	if codeStrategy == CodeStrategyObfuscated && os.Getenv("CODE_SECRET") == "" {
+		logger.Error("Found no value for CODE_SECRET")
+		return fmt.Errorf("no value for CODE_SECRET, required by the obfuscated code strategy")
+	}
+
+	// The admin API key is optional, but without it, no API key can be created
+	if os.Getenv("ADMIN_API_KEY") == "" {
+		logger.Warn("Found no value for ADMIN_API_KEY. The API can only be accessed with existing API keys")
+	}
+
+	// Without a fixed secret, visitors must enter the password of protected URLs again after a restart
+	if os.Getenv("LINK_COOKIE_SECRET") == "" {
+		logger.Warn("Found no value for LINK_COOKIE_SECRET. Access to password-protected URLs is lost on restart")
+	}
+
 	config = Config{
-		BaseURL:    os.Getenv("BASE_URL"),
-		DbDriver:   os.Getenv("DB_DRIVER"),
-		DbSource:   os.Getenv("DB_SOURCE"),
-		MaxRequest: maxRequest,
-		RefillRate: time.Duration(refileRate) * time.Second,
+		BaseURL:         os.Getenv("BASE_URL"),
+		DbDriver:        getEnvDriver(),
+		DbSource:        os.Getenv("DB_SOURCE"),
+		AutoMigrate:     getEnvBool("AUTO_MIGRATE", true, logger),
+		MaxRequest:      maxRequest,
+		RefillRate:      time.Duration(refileRate) * time.Second,
+		AdminAPIKey:     os.Getenv("ADMIN_API_KEY"),
+		AllowedSchemes:  getEnvList("ALLOWED_SCHEMES", []string{"http", "https"}),
+		SortQueryParams: getEnvBool("SORT_QUERY_PARAMS", false, logger),
+
+		RedirectMaxRequest:  redirectMaxRequest,
+		RedirectRefillRate:  time.Duration(redirectRefillRate) * time.Second,
+		RateLimitMaxClients: getEnvInt("RATE_LIMIT_MAX_CLIENTS", 100000, logger),
+		TrustForwardedFor:   getEnvBool("TRUST_FORWARDED_FOR", false, logger),
+		DefaultRedirectType: defaultRedirectType,
+
+		LinkCookieSecret:      os.Getenv("LINK_COOKIE_SECRET"),
+		LinkAccessTTL:         time.Duration(getEnvInt("LINK_ACCESS_TTL", 86400, logger)) * time.Second,
+		PasswordMaxAttempts:   getEnvInt("PASSWORD_MAX_ATTEMPTS", 5, logger),
+		PasswordAttemptRefill: time.Duration(getEnvInt("PASSWORD_ATTEMPT_REFILL", 60, logger)) * time.Second,
+
+		PreviewAllURLs: getEnvBool("PREVIEW_ALL_URLS", false, logger),
+
+		CodeStrategy:  codeStrategy,
+		CodeMinLength: getEnvNonNegativeInt("CODE_MIN_LENGTH", 0, logger),
+		CodeSecret:    os.Getenv("CODE_SECRET"),
+
+		BlockedDomains:           getEnvList("BLOCKED_DOMAINS", nil),
+		AllowedDomains:           getEnvList("ALLOWED_DOMAINS", nil),
+		AllowPrivateDestinations: getEnvBool("ALLOW_PRIVATE_DESTINATIONS", false, logger),
+		ResolveDestinations:      getEnvBool("RESOLVE_DESTINATIONS", true, logger),
+
+		MaxBatchSize: getEnvInt("MAX_BATCH_SIZE", DefaultMaxBatchSize, logger),
+
+		GeoIPDatabases:      getEnvPaths("GEOIP_DATABASES"),
+		GeoIPReloadInterval: time.Duration(getEnvInt("GEOIP_RELOAD_INTERVAL", 60, logger)) * time.Second,
+
+		BotUserAgents:   getEnvList("BOT_USER_AGENTS", nil),
+		RecordBotVisits: getEnvBool("RECORD_BOT_VISITS", true, logger),
+
+		URLCacheSize:        getEnvNonNegativeInt("URL_CACHE_SIZE", 10000, logger),
+		URLCacheTTL:         time.Duration(getEnvInt("URL_CACHE_TTL", 60, logger)) * time.Second,
+		URLCacheNegativeTTL: time.Duration(getEnvInt("URL_CACHE_NEGATIVE_TTL", 10, logger)) * time.Second,
+
+		VisitorQueueSize:     getEnvNonNegativeInt("VISITOR_QUEUE_SIZE", 10000, logger),
+		VisitorBatchSize:     getEnvInt("VISITOR_BATCH_SIZE", 500, logger),
+		VisitorFlushInterval: time.Duration(getEnvInt("VISITOR_FLUSH_INTERVAL", 1000, logger)) * time.Millisecond,
+		VisitorWorkers:       getEnvInt("VISITOR_WORKERS", 2, logger),
 	}
 	return err
 }
 
+// Helper to get the database driver: postgres (default), sqlite or memory
+func getEnvDriver() string {
+	if driver := strings.ToLower(strings.TrimSpace(os.Getenv("DB_DRIVER"))); driver != "" {
+		return driver
+	}
+	return "postgres"
+}
+
+// Helper to get a comma separated list from environment variable, values are trimmed and lowercased
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Helper to get a comma separated list of file paths from environment variable. Unlike getEnvList,
+// the paths are not lowercased
+func getEnvPaths(key string) []string {
+	paths := []string{}
+	for _, path := range strings.Split(os.Getenv(key), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Helper to get a boolean from environment variable
+func getEnvBool(key string, defaultValue bool, logger *slog.Logger) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Invalid value for %s. Start using default value", key), "error", err)
+		return defaultValue
+	}
+	return result
+}
+
+// Helper to get a positive integer from environment variable
+func getEnvInt(key string, defaultValue int, logger *slog.Logger) int {
+	return getEnvIntMin(key, defaultValue, 1, logger)
+}
+
+// Helper to get a non-negative integer from environment variable, for the settings where 0 has a meaning
+func getEnvNonNegativeInt(key string, defaultValue int, logger *slog.Logger) int {
+	return getEnvIntMin(key, defaultValue, 0, logger)
+}
+
+// Helper to get an integer of at least minValue from environment variable
+func getEnvIntMin(key string, defaultValue, minValue int, logger *slog.Logger) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil || result < minValue {
+		logger.Warn(fmt.Sprintf("Invalid value for %s. Start using default value", key), "value", value)
+		return defaultValue
+	}
+	return result
+}
+
+// Get the URL normalization options from config
+func (config *Config) NormalizeOptions() NormalizeOptions {
+	return NormalizeOptions{
+		AllowedSchemes: config.AllowedSchemes,
+		SortQuery:      config.SortQueryParams,
+	}
+}
+
 // Method to get the configuration
 func GetConfig() Config {
 	return config
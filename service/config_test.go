@@ -0,0 +1,40 @@
+package service
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	t.Setenv("DB_DRIVER", "memory")
+	t.Setenv("MAX_REQUEST", "100")
+	t.Setenv("REFILL_RATE", "10")
+
+	// Defaults
+	require.NoError(t, LoadConfig("missing.env", logger))
+	config := GetConfig()
+	require.Equal(t, 10000, config.URLCacheSize)
+	require.Equal(t, 10000, config.VisitorQueueSize)
+	require.False(t, config.TrustForwardedFor)
+
+	// 0 disables the cache and records the visitors synchronously
+	t.Setenv("URL_CACHE_SIZE", "0")
+	t.Setenv("VISITOR_QUEUE_SIZE", "0")
+	t.Setenv("VISITOR_WORKERS", "0")
+	require.NoError(t, LoadConfig("missing.env", logger))
+	config = GetConfig()
+	require.Equal(t, 0, config.URLCacheSize)
+	require.Equal(t, 0, config.VisitorQueueSize)
+	require.Equal(t, 2, config.VisitorWorkers)
+
+	// Invalid values fall back to the default
+	t.Setenv("URL_CACHE_SIZE", "-1")
+	t.Setenv("VISITOR_QUEUE_SIZE", "many")
+	require.NoError(t, LoadConfig("missing.env", logger))
+	config = GetConfig()
+	require.Equal(t, 10000, config.URLCacheSize)
+	require.Equal(t, 10000, config.VisitorQueueSize)
+}
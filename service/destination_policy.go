@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Error codes of URLError returned by the destination policy
+const (
+	URLErrorBlockedDomain    = "blocked_domain"
+	URLErrorDomainNotAllowed = "domain_not_allowed"
+	URLErrorPrivateAddress   = "private_address"
+	URLErrorSelfReference    = "self_reference"
+)
+
+// Resolver resolves a host name into IP addresses. *net.Resolver satisfies this interface, a fake
+// resolver can be used in tests to avoid network access
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Shared address space (RFC 6598), not covered by net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// DestinationPolicy decides which destinations can be shortened, to avoid the service being used
+// as an open redirector toward internal or malicious hosts
+type DestinationPolicy struct {
+	blocklist    []string // Blocked domains, including their subdomains
+	allowlist    []string // If not empty, only these domains (and their subdomains) are allowed
+	allowPrivate bool     // Allow private, loopback and link-local addresses
+	selfHost     string   // Host of this service, to avoid redirect loops
+	resolver     Resolver // If nil, host names are not resolved
+}
+
+// Constructor method for DestinationPolicy. Pass a nil resolver to skip DNS resolution
+func NewDestinationPolicy(config *Config, resolver Resolver) *DestinationPolicy {
+	selfHost := config.BaseURL
+	if host, _, err := net.SplitHostPort(selfHost); err == nil {
+		selfHost = host
+	}
+
+	return &DestinationPolicy{
+		blocklist:    normalizeDomains(config.BlockedDomains),
+		allowlist:    normalizeDomains(config.AllowedDomains),
+		allowPrivate: config.AllowPrivateDestinations,
+		selfHost:     strings.ToLower(strings.TrimSuffix(selfHost, ".")),
+		resolver:     resolver,
+	}
+}
+
+// Check if the destination URL is allowed by the policy. The URL should already be normalized by
+// NormalizeURL. Hosts that cannot be resolved are not rejected, since they cannot be reached either
+func (policy *DestinationPolicy) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return &URLError{URLErrorMalformed, "url is malformed"}
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+
+	// Redirecting to ourselves can create redirect loops
+	if policy.selfHost != "" && host == policy.selfHost {
+		return &URLError{URLErrorSelfReference, "url must not point to this service"}
+	}
+
+	// Check the domain lists. IP literals never match any domain
+	ip := parseHostIP(host)
+	if ip == nil {
+		for _, domain := range policy.blocklist {
+			if matchDomain(host, domain) {
+				return &URLError{URLErrorBlockedDomain, fmt.Sprintf("url host '%s' is blocked", host)}
+			}
+		}
+	}
+	if len(policy.allowlist) > 0 {
+		allowed := false
+		for _, domain := range policy.allowlist {
+			if ip == nil && matchDomain(host, domain) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return &URLError{URLErrorDomainNotAllowed, fmt.Sprintf("url host '%s' is not allowed", host)}
+		}
+	}
+
+	if policy.allowPrivate {
+		return nil
+	}
+
+	// Reject IP literals and local host names pointing to private networks
+	privateErr := &URLError{URLErrorPrivateAddress, "url must not point to a private or local address"}
+	if ip != nil {
+		if isPrivateIP(ip) {
+			return privateErr
+		}
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return privateErr
+	}
+
+	// Reject host names resolving to private networks
+	if policy.resolver == nil {
+		return nil
+	}
+	addrs, err := policy.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if isPrivateIP(addr.IP) {
+			return privateErr
+		}
+	}
+
+	return nil
+}
+
+// Check if the IP address is private, loopback, link-local, multicast or unspecified
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// Parse the host as an IP address. Beside the standard notation, browsers also accept IPv4 written
+// in decimal, octal or hexadecimal parts (e.g. "2130706433" or "0x7f.1" for 127.0.0.1), so these
+// forms are parsed too. Return nil if the host is a host name
+func parseHostIP(host string) net.IP {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip
+	}
+
+	// Following the URL standard, the host is an IPv4 address if its last part is a number
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return nil
+	}
+	numbers := make([]uint64, len(parts))
+	for i, part := range parts {
+		number, err := parseIPv4Number(part)
+		if err != nil {
+			return nil
+		}
+		numbers[i] = number
+	}
+
+	// All parts but the last one are a single byte, the last one fills the remaining bytes
+	var address uint64
+	for i, number := range numbers[:len(numbers)-1] {
+		if number > 255 {
+			return nil
+		}
+		address |= number << (8 * (3 - i))
+	}
+	last := numbers[len(numbers)-1]
+	if last >= 1<<(8*(5-len(numbers))) {
+		return nil
+	}
+	address |= last
+
+	return net.IPv4(byte(address>>24), byte(address>>16), byte(address>>8), byte(address))
+}
+
+// Parse a part of an IPv4 address, which can be decimal, octal (leading 0) or hexadecimal (leading 0x)
+func parseIPv4Number(part string) (uint64, error) {
+	base := 10
+	switch {
+	case strings.HasPrefix(part, "0x"):
+		part, base = part[2:], 16
+		if part == "" {
+			return 0, nil
+		}
+	case len(part) > 1 && strings.HasPrefix(part, "0"):
+		part, base = part[1:], 8
+	}
+	return strconv.ParseUint(part, base, 32)
+}
+
+// Check if the host is the domain or one of its subdomains
+func matchDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// Lowercase the domains and convert them into punycode, so they can be compared with normalized hosts
+func normalizeDomains(domains []string) []string {
+	result := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+			domain = ascii
+		}
+		if domain != "" {
+			result = append(result, domain)
+		}
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Fake resolver, so that the test does not need network access
+type fakeResolver map[string][]string
+
+func (resolver fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := resolver[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return addrs, nil
+}
+
+func TestDestinationPolicy(t *testing.T) {
+	resolver := fakeResolver{
+		"www.youtube.com":      {"142.250.66.78"},
+		"internal.example.com": {"10.0.0.5"},
+		"mixed.example.com":    {"93.184.216.34", "127.0.0.1"},
+	}
+	policy := NewDestinationPolicy(&Config{
+		BaseURL:        "short.example.org:8080",
+		BlockedDomains: []string{"evil.com", " .Phishing.NET "},
+	}, resolver)
+
+	// Allowed destinations
+	allowed := []string{
+		"https://www.youtube.com/watch?v=abc",
+		"https://unresolvable.example.com/", // Cannot be resolved, so it cannot be reached either
+		"https://93.184.216.34/",
+		"https://notevil.com/",
+	}
+	for _, url := range allowed {
+		require.NoError(t, policy.Check(context.Background(), url), url)
+	}
+
+	// Rejected destinations and their error code
+	rejected := map[string]string{
+		"https://evil.com/":                   URLErrorBlockedDomain,
+		"https://login.evil.com/":             URLErrorBlockedDomain,
+		"https://phishing.net/":               URLErrorBlockedDomain,
+		"http://127.0.0.1/admin":              URLErrorPrivateAddress,
+		"http://[::1]/":                       URLErrorPrivateAddress,
+		"http://169.254.169.254/latest/":      URLErrorPrivateAddress,
+		"http://192.168.1.1/":                 URLErrorPrivateAddress,
+		"http://100.64.0.1/":                  URLErrorPrivateAddress,
+		"http://0.0.0.0/":                     URLErrorPrivateAddress,
+		"http://2130706433/":                  URLErrorPrivateAddress, // 127.0.0.1 in decimal
+		"http://0x7f.1/":                      URLErrorPrivateAddress, // 127.0.0.1 in hexadecimal
+		"http://0177.0.0.1/":                  URLErrorPrivateAddress, // 127.0.0.1 in octal
+		"http://localhost:3000/":              URLErrorPrivateAddress,
+		"http://internal.example.com/":        URLErrorPrivateAddress,
+		"http://mixed.example.com/":           URLErrorPrivateAddress,
+		"http://short.example.org:8080/abc":   URLErrorSelfReference,
+		"https://short.example.org/other-url": URLErrorSelfReference,
+	}
+	for url, code := range rejected {
+		err := policy.Check(context.Background(), url)
+		require.Error(t, err, url)
+
+		var urlErr *URLError
+		require.True(t, errors.As(err, &urlErr), url)
+		require.Equal(t, code, urlErr.Code, url)
+	}
+}
+
+func TestDestinationPolicyAllowlist(t *testing.T) {
+	policy := NewDestinationPolicy(&Config{
+		BaseURL:                  "localhost:8080",
+		AllowedDomains:           []string{"youtube.com"},
+		AllowPrivateDestinations: true,
+	}, nil)
+
+	require.NoError(t, policy.Check(context.Background(), "https://www.youtube.com/watch?v=abc"))
+	require.NoError(t, policy.Check(context.Background(), "https://youtube.com/"))
+
+	// Other domains and IP literals are not in the allowlist
+	for _, url := range []string{"https://example.com/", "https://notyoutube.com/", "http://10.0.0.5/"} {
+		err := policy.Check(context.Background(), url)
+		var urlErr *URLError
+		require.True(t, errors.As(err, &urlErr), url)
+		require.Equal(t, URLErrorDomainNotAllowed, urlErr.Code, url)
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Geographic location and network of an IP address. Empty fields are unknown
+type GeoLocation struct {
+	Country string // ISO 3166-1 alpha-2 country code, e.g. "VN"
+	Region  string // Name of the first level subdivision, e.g. "Ho Chi Minh"
+	City    string // Name of the city
+	ASN     uint32 // Autonomous system number of the network, 0 if unknown
+	ASOrg   string // Organization of the autonomous system
+}
+
+// Record of a MaxMind-format database. The field names follow the GeoIP2/GeoLite2 City, Country and
+// ASN databases, so any of them (or a database combining them) can be used
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ASN   uint32 `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+// A database file, reloaded when the file changes
+type geoDatabase struct {
+	path    string
+	reader  *maxminddb.Reader // nil if the file has not been loaded yet
+	modTime time.Time         // Modification time of the file at the last load attempt
+	missing bool              // The file was missing at the last load attempt
+}
+
+// GeoIP resolves IP addresses to their location using local MaxMind-format (mmdb) database files, so
+// that no external API is needed. The files are reloaded when they change on disk
+type GeoIP struct {
+	mu        sync.RWMutex
+	databases []*geoDatabase
+	logger    *slog.Logger
+}
+
+// Constructor method for GeoIP. Files that cannot be loaded yet are skipped, and loaded later once
+// they are valid
+func NewGeoIP(paths []string, logger *slog.Logger) *GeoIP {
+	geoip := &GeoIP{logger: logger}
+	for _, path := range paths {
+		geoip.databases = append(geoip.databases, &geoDatabase{path: path})
+	}
+	geoip.Reload()
+	return geoip
+}
+
+// Reload the database files that have changed since the last load attempt. The files are read into
+// memory instead of being memory-mapped, so they can be safely overwritten. If a file is invalid (for
+// example while it is being written), the previous version is kept
+func (geoip *GeoIP) Reload() {
+	for _, database := range geoip.databases {
+		info, err := os.Stat(database.path)
+		if err != nil {
+			if !database.missing {
+				geoip.logger.Warn("Failed to read GeoIP database", "path", database.path, "error", err)
+			}
+			database.missing = true
+			continue
+		}
+		database.missing = false
+		if info.ModTime().Equal(database.modTime) {
+			continue
+		}
+		database.modTime = info.ModTime()
+
+		data, err := os.ReadFile(database.path)
+		if err != nil {
+			geoip.logger.Warn("Failed to read GeoIP database", "path", database.path, "error", err)
+			continue
+		}
+		reader, err := maxminddb.FromBytes(data)
+		if err != nil {
+			geoip.logger.Warn("Failed to load GeoIP database", "path", database.path, "error", err)
+			continue
+		}
+
+		geoip.mu.Lock()
+		database.reader = reader
+		geoip.mu.Unlock()
+		geoip.logger.Info("Load GeoIP database successfully", "path", database.path,
+			"type", reader.Metadata.DatabaseType)
+	}
+}
+
+// Check the database files for changes every interval, until the context is canceled
+func (geoip *GeoIP) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			geoip.Reload()
+		}
+	}
+}
+
+// Look up the location of an IP address. When several databases are used, the first database knowing
+// a field wins. Return an empty location if the IP address is invalid or unknown
+func (geoip *GeoIP) Lookup(ip string) GeoLocation {
+	location := GeoLocation{}
+	if geoip == nil {
+		return location
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return location
+	}
+
+	geoip.mu.RLock()
+	defer geoip.mu.RUnlock()
+	for _, database := range geoip.databases {
+		if database.reader == nil {
+			continue
+		}
+
+		var record geoRecord
+		if err := database.reader.Lookup(parsed, &record); err != nil {
+			continue
+		}
+
+		if location.Country == "" && len(record.Country.ISOCode) == 2 {
+			location.Country = record.Country.ISOCode
+		}
+		if location.Region == "" && len(record.Subdivisions) > 0 {
+			location.Region = record.Subdivisions[0].Names["en"]
+		}
+		if location.City == "" {
+			location.City = record.City.Names["en"]
+		}
+		if location.ASN == 0 {
+			location.ASN = record.ASN
+			location.ASOrg = record.ASOrg
+		}
+	}
+	return location
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper to write a GeoIP database file with a single network
+func writeTestGeoDatabase(t *testing.T, path, network string, record mmdbtype.Map) {
+	writer, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: "Test-City", RecordSize: 24})
+	require.NoError(t, err)
+
+	_, ipNet, err := net.ParseCIDR(network)
+	require.NoError(t, err)
+	require.NoError(t, writer.Insert(ipNet, record))
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = writer.WriteTo(file)
+	require.NoError(t, err)
+}
+
+func TestGeoIP(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	cityPath := filepath.Join(dir, "city.mmdb")
+	asnPath := filepath.Join(dir, "asn.mmdb")
+
+	writeTestGeoDatabase(t, cityPath, "1.2.3.0/24", mmdbtype.Map{
+		"country": mmdbtype.Map{"iso_code": mmdbtype.String("VN")},
+		"subdivisions": mmdbtype.Slice{
+			mmdbtype.Map{"names": mmdbtype.Map{"en": mmdbtype.String("Ho Chi Minh")}},
+		},
+		"city": mmdbtype.Map{"names": mmdbtype.Map{"en": mmdbtype.String("Ho Chi Minh City")}},
+	})
+
+	// The ASN database does not exist yet
+	geoip := NewGeoIP([]string{cityPath, asnPath}, logger)
+
+	location := geoip.Lookup("1.2.3.4")
+	require.Equal(t, GeoLocation{Country: "VN", Region: "Ho Chi Minh", City: "Ho Chi Minh City"}, location)
+
+	// Unknown and invalid IP addresses have no location
+	require.Equal(t, GeoLocation{}, geoip.Lookup("5.6.7.8"))
+	require.Equal(t, GeoLocation{}, geoip.Lookup("not an ip"))
+
+	// The ASN database is picked up on reload, and merged with the city database
+	writeTestGeoDatabase(t, asnPath, "1.2.0.0/16", mmdbtype.Map{
+		"autonomous_system_number":       mmdbtype.Uint32(7552),
+		"autonomous_system_organization": mmdbtype.String("Viettel Group"),
+	})
+	geoip.Reload()
+
+	location = geoip.Lookup("1.2.3.4")
+	require.Equal(t, "VN", location.Country)
+	require.Equal(t, uint32(7552), location.ASN)
+	require.Equal(t, "Viettel Group", location.ASOrg)
+
+	// A changed file is reloaded
+	writeTestGeoDatabase(t, cityPath, "1.2.3.0/24", mmdbtype.Map{
+		"country": mmdbtype.Map{"iso_code": mmdbtype.String("JP")},
+	})
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(cityPath, future, future))
+	geoip.Reload()
+	require.Equal(t, "JP", geoip.Lookup("1.2.3.4").Country)
+
+	// An invalid file keeps the previous version
+	require.NoError(t, os.WriteFile(cityPath, []byte("garbage"), 0o644))
+	future = future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(cityPath, future, future))
+	geoip.Reload()
+	require.Equal(t, "JP", geoip.Lookup("1.2.3.4").Country)
+
+	// Nil GeoIP (disabled) has no location
+	var disabled *GeoIP
+	require.Equal(t, GeoLocation{}, disabled.Lookup("1.2.3.4"))
+}
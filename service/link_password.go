@@ -0,0 +1,58 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Maximum length of a link password in bytes, bcrypt ignores anything longer
+const MaxLinkPasswordLength = 72
+
+// Hash the password of a link with bcrypt. Unlike API keys, passwords are chosen by people and can be
+// guessed, so a slow hash is required
+func HashLinkPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Check if the password matches the hash of a link
+func CheckLinkPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Sign a token granting access to a password-protected link until the given time. The token is bound to
+// the password hash, so that changing the password revokes the tokens already given
+func SignLinkAccess(secret []byte, urlID int64, passwordHash string, expires time.Time) string {
+	expiresAt := strconv.FormatInt(expires.Unix(), 10)
+	return expiresAt + "." + linkAccessMAC(secret, urlID, passwordHash, expiresAt)
+}
+
+// Verify a token given by SignLinkAccess for the link, and that it has not expired
+func VerifyLinkAccess(secret []byte, urlID int64, passwordHash, token string, now time.Time) bool {
+	expiresAt, mac, found := strings.Cut(token, ".")
+	if !found {
+		return false
+	}
+	expires, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return false
+	}
+	return hmac.Equal([]byte(mac), []byte(linkAccessMAC(secret, urlID, passwordHash, expiresAt)))
+}
+
+// Helper function to compute the HMAC-SHA256 of a link access token
+func linkAccessMAC(secret []byte, urlID int64, passwordHash, expiresAt string) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d|%s|%s", urlID, expiresAt, passwordHash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
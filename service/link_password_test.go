@@ -0,0 +1,39 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkPassword(t *testing.T) {
+	hash, err := HashLinkPassword("secret")
+	require.NoError(t, err)
+	require.NotEqual(t, "secret", hash)
+	require.True(t, CheckLinkPassword(hash, "secret"))
+	require.False(t, CheckLinkPassword(hash, "Secret"))
+
+	// Passwords longer than bcrypt supports are rejected
+	_, err = HashLinkPassword(strings.Repeat("a", MaxLinkPasswordLength+1))
+	require.Error(t, err)
+}
+
+func TestLinkAccess(t *testing.T) {
+	secret := []byte("test-secret")
+	now := time.Now()
+	token := SignLinkAccess(secret, 1, "hash", now.Add(time.Hour))
+
+	require.True(t, VerifyLinkAccess(secret, 1, "hash", token, now))
+
+	// The token is bound to the link, the password, the secret and the expiration time
+	require.False(t, VerifyLinkAccess(secret, 2, "hash", token, now))
+	require.False(t, VerifyLinkAccess(secret, 1, "new-hash", token, now))
+	require.False(t, VerifyLinkAccess([]byte("other-secret"), 1, "hash", token, now))
+	require.False(t, VerifyLinkAccess(secret, 1, "hash", token, now.Add(time.Hour)))
+
+	expiresAt, mac, _ := strings.Cut(token, ".")
+	require.False(t, VerifyLinkAccess(secret, 1, "hash", "9"+expiresAt+"."+mac, now))
+	require.False(t, VerifyLinkAccess(secret, 1, "hash", "invalid", now))
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Image formats of the QR codes
+const (
+	QRFormatPNG = "png"
+	QRFormatSVG = "svg"
+)
+
+// Limits of the QR code options
+const (
+	MinQRSize   = 64
+	MaxQRSize   = 2048
+	MaxQRMargin = 32
+)
+
+// Error correction levels of the QR codes, by the share of the code that can be damaged and still be read:
+// L (7%), M (15%), Q (25%) and H (30%)
+var qrLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options of a QR code image
+type QROptions struct {
+	Format     string      // png or svg
+	Size       int         // Width and height of the image, in pixels
+	Level      string      // Error correction level: L, M, Q or H
+	Margin     int         // Quiet zone around the code, in modules. Readers expect at least 4
+	Foreground color.NRGBA // Color of the dark modules
+	Background color.NRGBA // Color of the light modules and of the margin, can be transparent
+}
+
+// Get the default options: a 256 pixels black on white PNG, with level M and the standard margin
+func DefaultQROptions() QROptions {
+	return QROptions{
+		Format:     QRFormatPNG,
+		Size:       256,
+		Level:      "M",
+		Margin:     4,
+		Foreground: color.NRGBA{A: 0xff},
+		Background: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// Check if the options are valid
+func (options QROptions) Validate() error {
+	if options.Format != QRFormatPNG && options.Format != QRFormatSVG {
+		return fmt.Errorf("format must be %s or %s", QRFormatPNG, QRFormatSVG)
+	}
+	if options.Size < MinQRSize || options.Size > MaxQRSize {
+		return fmt.Errorf("size must be between %d and %d", MinQRSize, MaxQRSize)
+	}
+	if _, ok := qrLevels[options.Level]; !ok {
+		return fmt.Errorf("level must be L, M, Q or H")
+	}
+	if options.Margin < 0 || options.Margin > MaxQRMargin {
+		return fmt.Errorf("margin must be between 0 and %d", MaxQRMargin)
+	}
+	return nil
+}
+
+// Parse a hexadecimal color: RRGGBB, or RRGGBBAA with transparency. The leading '#' is optional
+func ParseQRColor(value string) (color.NRGBA, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(value, "#"))
+	if err != nil || (len(raw) != 3 && len(raw) != 4) {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q, must be RRGGBB or RRGGBBAA", value)
+	}
+	if len(raw) == 3 {
+		raw = append(raw, 0xff)
+	}
+	return color.NRGBA{R: raw[0], G: raw[1], B: raw[2], A: raw[3]}, nil
+}
+
+// Render the content as a QR code image, in the format of the options
+func RenderQRCode(content string, options QROptions) ([]byte, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	code, err := qrcode.New(content, qrLevels[options.Level])
+	if err != nil {
+		return nil, err
+	}
+	code.DisableBorder = true
+	modules := code.Bitmap()
+
+	// Every module must be at least one pixel wide
+	side := len(modules) + 2*options.Margin
+	if side > options.Size {
+		return nil, fmt.Errorf("size must be at least %d to fit this code", side)
+	}
+
+	if options.Format == QRFormatSVG {
+		return renderQRSVG(modules, options), nil
+	}
+	return renderQRPNG(modules, options)
+}
+
+// Helper function to render the modules as PNG. Modules have a whole number of pixels, so that the code
+// stays sharp, and the pixels left over are added to the margin
+func renderQRPNG(modules [][]bool, options QROptions) ([]byte, error) {
+	side := len(modules) + 2*options.Margin
+	scale := options.Size / side
+	offset := (options.Size-scale*side)/2 + options.Margin*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, options.Size, options.Size),
+		color.Palette{options.Background, options.Foreground})
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for py := offset + y*scale; py < offset+(y+1)*scale; py++ {
+				for px := offset + x*scale; px < offset+(x+1)*scale; px++ {
+					img.SetColorIndex(px, py, 1)
+				}
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Helper function to render the modules as SVG, in a viewBox of one unit per module. Each run of dark
+// modules in a row is drawn as one rectangle of a single path
+func renderQRSVG(modules [][]bool, options QROptions) []byte {
+	side := len(modules) + 2*options.Margin
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" `+
+		`shape-rendering="crispEdges">`, options.Size, options.Size, side, side)
+	fmt.Fprintf(&buffer, `<rect width="%d" height="%d"%s/>`, side, side, svgFill(options.Background))
+
+	buffer.WriteString(`<path` + svgFill(options.Foreground) + ` d="`)
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buffer, "M%d,%dh%dv1h-%dz", start+options.Margin, y+options.Margin, x-start, x-start)
+		}
+	}
+	buffer.WriteString(`"/></svg>`)
+	return buffer.Bytes()
+}
+
+// Helper function to get the SVG fill attributes of a color
+func svgFill(c color.NRGBA) string {
+	fill := fmt.Sprintf(` fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A != 0xff {
+		fill += fmt.Sprintf(` fill-opacity="%.3g"`, float64(c.A)/0xff)
+	}
+	return fill
+}
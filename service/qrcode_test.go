@@ -0,0 +1,86 @@
+package service
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQRColor(t *testing.T) {
+	c, err := ParseQRColor("#ff8000")
+	require.NoError(t, err)
+	require.Equal(t, color.NRGBA{R: 0xff, G: 0x80, A: 0xff}, c)
+
+	c, err = ParseQRColor("00000000")
+	require.NoError(t, err)
+	require.Equal(t, color.NRGBA{}, c)
+
+	for _, value := range []string{"", "fff", "red", "#12345", "#gggggg"} {
+		_, err := ParseQRColor(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestRenderQRCode(t *testing.T) {
+	content := "http://localhost:8080/abc"
+	options := DefaultQROptions()
+	options.Foreground = color.NRGBA{R: 0xff, A: 0xff}
+
+	// PNG of the requested size, with the corners in the margin and the top left finder pattern dark
+	data, err := RenderQRCode(content, options)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 256, img.Bounds().Dx())
+	require.Equal(t, 256, img.Bounds().Dy())
+
+	r, g, b, _ := img.At(0, 0).RGBA()
+	require.Equal(t, []uint32{0xffff, 0xffff, 0xffff}, []uint32{r, g, b})
+
+	// The first colored pixel on the diagonal is the top left finder pattern, after the margin
+	first := 0
+	for ; first < 128; first++ {
+		if r, g, b, _ := img.At(first, first).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff {
+			require.Equal(t, []uint32{0xffff, 0, 0}, []uint32{r, g, b})
+			break
+		}
+	}
+	require.Greater(t, first, 4*5)
+	require.Less(t, first, 128)
+
+	// SVG with a transparent background
+	options.Format = QRFormatSVG
+	options.Background = color.NRGBA{}
+	options.Margin = 0
+	data, err = RenderQRCode(content, options)
+	require.NoError(t, err)
+	svg := string(data)
+	require.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256"`))
+	require.Contains(t, svg, `fill="#000000" fill-opacity="0"`)
+	require.Contains(t, svg, `<path fill="#ff0000" d="M0,0h7v1h-7z`)
+
+	// Invalid options
+	for _, change := range []func(*QROptions){
+		func(o *QROptions) { o.Format = "gif" },
+		func(o *QROptions) { o.Size = MinQRSize - 1 },
+		func(o *QROptions) { o.Size = MaxQRSize + 1 },
+		func(o *QROptions) { o.Level = "X" },
+		func(o *QROptions) { o.Margin = -1 },
+	} {
+		options := DefaultQROptions()
+		change(&options)
+		_, err := RenderQRCode(content, options)
+		require.Error(t, err)
+	}
+
+	// The code must fit in the image
+	options = DefaultQROptions()
+	options.Size = MinQRSize
+	options.Margin = MaxQRMargin
+	_, err = RenderQRCode(content, options)
+	require.ErrorContains(t, err, "size must be at least")
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Result of a rate limiter check, used to fill the RateLimit-* and Retry-After headers
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int           // Maximum number of requests in a burst
+	Remaining  int           // Requests left in the bucket after this one
+	Reset      time.Duration // Time until the bucket is full again
+	RetryAfter time.Duration // Time until the next token, 0 if the request is allowed
+}
+
+// Token bucket of a single client
+type rateBucket struct {
+	key        string
+	tokens     int
+	lastRefill time.Time
+	lastSeen   time.Time
+}
+
+// RateLimiter is a token bucket rate limiter keeping one bucket per client key (e.g. IP address or
+// API key), so that one client cannot use up the requests of the others. Every bucket holds up to limit
+// tokens and gets one token back every refill rate. A bucket not used for long enough to be full again
+// is evicted, since it is the same as a new bucket, and at most maxClients buckets are kept: the least
+// recently used bucket is evicted first. It is safe for concurrent use
+type RateLimiter struct {
+	mu         sync.Mutex
+	limit      int
+	refillRate time.Duration
+	maxClients int
+	buckets    map[string]*list.Element
+	order      *list.List // Most recently used buckets first
+	now        func() time.Time
+}
+
+// Constructor method for RateLimiter. A limiter without limit or refill rate allows every request
+func NewRateLimiter(limit int, refillRate time.Duration, maxClients int) *RateLimiter {
+	return &RateLimiter{
+		limit:      limit,
+		refillRate: refillRate,
+		maxClients: maxClients,
+		buckets:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// Check if a request of the client can pass on, consuming a token of its bucket if so
+func (limiter *RateLimiter) Allow(key string) RateLimitResult {
+	if limiter.limit <= 0 || limiter.refillRate <= 0 {
+		return RateLimitResult{Allowed: true}
+	}
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+	limiter.evictIdle(now)
+
+	var bucket *rateBucket
+	if element, ok := limiter.buckets[key]; ok {
+		limiter.order.MoveToFront(element)
+		bucket = element.Value.(*rateBucket)
+	} else {
+		for limiter.maxClients > 0 && limiter.order.Len() >= limiter.maxClients {
+			limiter.removeElement(limiter.order.Back())
+		}
+		bucket = &rateBucket{key: key, tokens: limiter.limit, lastRefill: now}
+		limiter.buckets[key] = limiter.order.PushFront(bucket)
+	}
+	bucket.lastSeen = now
+
+	// Refill the bucket, keeping the time already spent toward the next token
+	if refill := int(now.Sub(bucket.lastRefill) / limiter.refillRate); refill > 0 {
+		bucket.tokens += refill
+		bucket.lastRefill = bucket.lastRefill.Add(time.Duration(refill) * limiter.refillRate)
+	}
+	if bucket.tokens >= limiter.limit {
+		bucket.tokens = limiter.limit
+		bucket.lastRefill = now
+	}
+
+	result := RateLimitResult{Limit: limiter.limit}
+	if bucket.tokens > 0 {
+		bucket.tokens--
+		result.Allowed = true
+	}
+
+	// The next token comes one refill rate after the last refill, the other tokens follow one by one
+	nextToken := limiter.refillRate - now.Sub(bucket.lastRefill)
+	result.Remaining = bucket.tokens
+	if bucket.tokens < limiter.limit {
+		result.Reset = nextToken + time.Duration(limiter.limit-bucket.tokens-1)*limiter.refillRate
+	}
+	if !result.Allowed {
+		result.RetryAfter = nextToken
+	}
+	return result
+}
+
+// Get the number of buckets currently kept
+func (limiter *RateLimiter) Size() int {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	return limiter.order.Len()
+}
+
+// Helper method to evict the buckets unused for long enough to be full again, the lock must be held
+func (limiter *RateLimiter) evictIdle(now time.Time) {
+	idle := time.Duration(limiter.limit) * limiter.refillRate
+	for element := limiter.order.Back(); element != nil; element = limiter.order.Back() {
+		if now.Sub(element.Value.(*rateBucket).lastSeen) < idle {
+			return
+		}
+		limiter.removeElement(element)
+	}
+}
+
+// Helper method to remove a bucket, the lock must be held
+func (limiter *RateLimiter) removeElement(element *list.Element) {
+	limiter.order.Remove(element)
+	delete(limiter.buckets, element.Value.(*rateBucket).key)
+}
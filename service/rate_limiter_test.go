@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(3, 10*time.Second, 2)
+	limiter.now = func() time.Time { return now }
+
+	// A client can use up its bucket, then must wait for the next token
+	for i := range 3 {
+		result := limiter.Allow("a")
+		require.True(t, result.Allowed)
+		require.Equal(t, 3, result.Limit)
+		require.Equal(t, 2-i, result.Remaining)
+	}
+	result := limiter.Allow("a")
+	require.False(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, 10*time.Second, result.RetryAfter)
+	require.Equal(t, 30*time.Second, result.Reset)
+
+	// Other clients have their own bucket
+	require.True(t, limiter.Allow("b").Allowed)
+
+	// One token comes back every refill rate, the time already spent is kept
+	now = now.Add(15 * time.Second)
+	result = limiter.Allow("a")
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, 25*time.Second, result.Reset)
+
+	result = limiter.Allow("a")
+	require.False(t, result.Allowed)
+	require.Equal(t, 5*time.Second, result.RetryAfter)
+
+	now = now.Add(5 * time.Second)
+	require.True(t, limiter.Allow("a").Allowed)
+
+	// The least recently used bucket is evicted when full: b gets a new bucket
+	require.True(t, limiter.Allow("c").Allowed)
+	require.Equal(t, 2, limiter.Size())
+	require.Equal(t, 2, limiter.Allow("b").Remaining)
+
+	// Buckets unused for long enough to be full again are evicted
+	now = now.Add(30 * time.Second)
+	require.Equal(t, 2, limiter.Allow("d").Remaining)
+	require.Equal(t, 1, limiter.Size())
+
+	// A limiter without limit allows everything
+	unlimited := NewRateLimiter(0, 0, 0)
+	for i := range 10 {
+		require.True(t, unlimited.Allow(fmt.Sprint(i)).Allowed)
+	}
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Strategies of the short codes of the URLs without alias
+const (
+	CodeStrategySequential = "sequential" // Base62 encoded ID, easy to enumerate
+	CodeStrategyObfuscated = "obfuscated" // Base62 encoded ID, shuffled by a keyed permutation
+	CodeStrategyRandom     = "random"     // Random code of fixed length, stored with the URL
+)
+
+// Default minimum length of the short codes of each strategy
+var defaultCodeMinLength = map[string]int{
+	CodeStrategySequential: 1,
+	CodeStrategyObfuscated: 6,
+	CodeStrategyRandom:     8,
+}
+
+// Limits of the minimum length of the short codes. Random codes shorter than MinRandomCodeLength would
+// collide too often, and be easy to guess
+const (
+	MaxCodeLength       = 16
+	MinRandomCodeLength = 6
+)
+
+// Prefix of the generated codes. Aliases cannot start with it, so that an alias never takes the code of
+// another URL, and any Base62 word can still be an alias
+const CodePrefix = "_"
+
+// IDs from 2^62 are encoded as is by the obfuscated strategy, a BIGSERIAL never gets that far
+const maxObfuscatedBits = 62
+
+// Generator of the short codes of the URLs without alias
+type CodeGenerator interface {
+	// Get the code of the URL with the given ID
+	Encode(id int64) string
+
+	// Get the ID of a code made by Encode, false if the code cannot be one
+	Decode(code string) (int64, bool)
+
+	// Get a new random code to store with the URL, empty if the code is derived from the ID
+	NewCode() string
+
+	// Get the scheme of the codes derived from the IDs. The URLs without stored code can only be found as
+	// long as the scheme does not change
+	Scheme() string
+}
+
+// Check if the strategy is one of the code strategies, empty meaning the default
+func IsCodeStrategy(strategy string) bool {
+	_, ok := defaultCodeMinLength[strategy]
+	return ok || strategy == ""
+}
+
+// Create the code generator of the config. The strategy and the secret must have been validated by LoadConfig,
+// an unknown strategy falls back to sequential
+func NewCodeGenerator(config *Config) CodeGenerator {
+	strategy := config.CodeStrategy
+	if _, ok := defaultCodeMinLength[strategy]; !ok {
+		strategy = CodeStrategySequential
+	}
+	minLength := config.CodeMinLength
+	if minLength <= 0 {
+		minLength = defaultCodeMinLength[strategy]
+	}
+	minLength = min(minLength, MaxCodeLength)
+
+	switch strategy {
+	case CodeStrategyObfuscated:
+		return newObfuscatedCodes([]byte(config.CodeSecret), minLength)
+	case CodeStrategyRandom:
+		// The URLs created before switching to random codes keep their sequential code
+		return randomCodes{sequentialCodes: sequentialCodes{minLength: 1}, length: max(minLength, MinRandomCodeLength)}
+	default:
+		return sequentialCodes{minLength: minLength}
+	}
+}
+
+// Sequential codes: the Base62 encoded ID, left padded with zeros up to the minimum length. The minimum
+// length does not count the prefix
+type sequentialCodes struct {
+	minLength int
+}
+
+func (codes sequentialCodes) Encode(id int64) string {
+	code := EncodeBase62(id)
+	if len(code) < codes.minLength {
+		code = strings.Repeat(string(base62chars[0]), codes.minLength-len(code)) + code
+	}
+	return CodePrefix + code
+}
+
+func (codes sequentialCodes) Decode(code string) (int64, bool) {
+	digits, found := strings.CutPrefix(code, CodePrefix)
+	if !found {
+		return 0, false
+	}
+	id, ok := parseBase62(digits)
+	// Only the canonical code of an ID is accepted, so that every URL has a single code
+	if !ok || codes.Encode(id) != code {
+		return 0, false
+	}
+	return id, true
+}
+
+func (codes sequentialCodes) NewCode() string {
+	return ""
+}
+
+func (codes sequentialCodes) Scheme() string {
+	return fmt.Sprintf("%s:%d", CodeStrategySequential, codes.minLength)
+}
+
+// Random codes: codes are drawn at random and stored with the URL, the ID cannot be guessed from them.
+// The codes of the URLs without stored code are sequential
+type randomCodes struct {
+	sequentialCodes
+	length int
+}
+
+func (codes randomCodes) NewCode() string {
+	// Reject the bytes above the largest multiple of 62, so that every character is equally likely
+	const limit = 256 - 256%base
+	code := make([]byte, 0, codes.length)
+	buffer := make([]byte, codes.length*2)
+	for len(code) < codes.length {
+		rand.Read(buffer) // Never fails
+		for _, b := range buffer {
+			if b < limit && len(code) < codes.length {
+				code = append(code, base62chars[b%base])
+			}
+		}
+	}
+	return CodePrefix + string(code)
+}
+
+// Obfuscated codes: the ID is encrypted by a keyed permutation (a Feistel cipher) before being Base62
+// encoded, so that consecutive IDs get unrelated codes, and only the key holder can map codes to IDs.
+//
+// To keep the codes short, the IDs are split in ranges of growing bit widths: [0, 2^w0), [2^w0, 2^(w0+2)),
+// and so on, w0 being the largest width whose codes fit in the minimum length. Each range is permuted
+// onto itself, by cycle walking the permutation of [0, 2^w) until the result falls back into the range
+type obfuscatedCodes struct {
+	sequentialCodes
+	key      []byte
+	minWidth int
+}
+
+// Number of rounds of the Feistel cipher, 4 rounds make a strong pseudorandom permutation
+const feistelRounds = 4
+
+func newObfuscatedCodes(secret []byte, minLength int) obfuscatedCodes {
+	// Largest even width whose values all have at most minLength Base62 digits
+	minWidth, limit := 2, int64(1)
+	for range minLength {
+		if limit > (1<<maxObfuscatedBits)/base {
+			limit = 1 << maxObfuscatedBits
+			break
+		}
+		limit *= base
+	}
+	for minWidth+2 <= maxObfuscatedBits && int64(1)<<(minWidth+2) <= limit {
+		minWidth += 2
+	}
+
+	// Derive the key of the permutation, so that the secret can be shared with other uses
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("short code permutation"))
+	return obfuscatedCodes{
+		sequentialCodes: sequentialCodes{minLength: minLength},
+		key:             mac.Sum(nil),
+		minWidth:        minWidth,
+	}
+}
+
+func (codes obfuscatedCodes) Encode(id int64) string {
+	return codes.sequentialCodes.Encode(codes.permute(id, false))
+}
+
+func (codes obfuscatedCodes) Decode(code string) (int64, bool) {
+	value, ok := codes.sequentialCodes.Decode(code)
+	if !ok {
+		return 0, false
+	}
+	return codes.permute(value, true), true
+}
+
+// The scheme includes a fingerprint of the key, so that a changed secret is noticed without storing the key
+func (codes obfuscatedCodes) Scheme() string {
+	mac := hmac.New(sha256.New, codes.key)
+	mac.Write([]byte("short code scheme"))
+	return fmt.Sprintf("%s:%d:%x", CodeStrategyObfuscated, codes.minLength, mac.Sum(nil)[:8])
+}
+
+// Helper method to apply the permutation of the range of the value, or its inverse
+func (codes obfuscatedCodes) permute(value int64, inverse bool) int64 {
+	if value < 0 || value >= 1<<maxObfuscatedBits {
+		return value
+	}
+
+	// Find the range of the value
+	width, low := codes.minWidth, int64(0)
+	for value >= int64(1)<<width {
+		low = int64(1) << width
+		width += 2
+	}
+
+	// Cycle walk until the result is in the range. It terminates, since the cycle of the value goes
+	// back to the value itself
+	result := uint64(value)
+	for {
+		result = codes.feistel(result, width, inverse)
+		if int64(result) >= low {
+			return int64(result)
+		}
+	}
+}
+
+// Helper method to apply the Feistel cipher (or its inverse) on a value of the given even bit width
+func (codes obfuscatedCodes) feistel(value uint64, width int, inverse bool) uint64 {
+	half := uint(width / 2)
+	mask := uint64(1)<<half - 1
+	left, right := value>>half, value&mask
+
+	for i := range feistelRounds {
+		if inverse {
+			round := feistelRounds - 1 - i
+			left, right = right^codes.round(round, width, left)&mask, left
+		} else {
+			left, right = right, left^codes.round(i, width, right)&mask
+		}
+	}
+	return left<<half | right
+}
+
+// Helper method to compute the round function of the Feistel cipher, a keyed hash of the half value
+func (codes obfuscatedCodes) round(round, width int, half uint64) uint64 {
+	var input [10]byte
+	input[0], input[1] = byte(round), byte(width)
+	binary.BigEndian.PutUint64(input[2:], half)
+
+	mac := hmac.New(sha256.New, codes.key)
+	mac.Write(input[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequentialCodes(t *testing.T) {
+	codes := NewCodeGenerator(&Config{})
+	require.Equal(t, "_1", codes.Encode(1))
+	require.Equal(t, "_10", codes.Encode(62))
+	require.Empty(t, codes.NewCode())
+
+	id, ok := codes.Decode("_10")
+	require.True(t, ok)
+	require.Equal(t, int64(62), id)
+
+	// Padded up to the minimum length, only the canonical code of an ID is accepted
+	codes = NewCodeGenerator(&Config{CodeMinLength: 4})
+	require.Equal(t, "_0001", codes.Encode(1))
+	require.Equal(t, "_12345", codes.Encode(DecodeBase62("12345")))
+	for _, code := range []string{"", "_", "0001", "_1", "_01", "_00001", "_ab-c", "_zzzzzzzzzzzz"} {
+		_, ok := codes.Decode(code)
+		require.False(t, ok, code)
+	}
+
+	id, ok = codes.Decode(codes.Encode(math.MaxInt64))
+	require.True(t, ok)
+	require.Equal(t, int64(math.MaxInt64), id)
+}
+
+func TestObfuscatedCodes(t *testing.T) {
+	config := &Config{CodeStrategy: CodeStrategyObfuscated, CodeSecret: "secret"}
+	codes := NewCodeGenerator(config)
+	require.Empty(t, codes.NewCode())
+
+	// Consecutive IDs get unrelated codes of the minimum length
+	seen := map[string]bool{}
+	for id := int64(1); id <= 1000; id++ {
+		code := codes.Encode(id)
+		require.Len(t, code, 7)
+		require.False(t, seen[code], code)
+		seen[code] = true
+
+		decoded, ok := codes.Decode(code)
+		require.True(t, ok)
+		require.Equal(t, id, decoded)
+	}
+	require.NotEqual(t, "_000002", codes.Encode(2))
+
+	// Every range of IDs is permuted onto itself, so codes stay short
+	for _, id := range []int64{1<<34 - 1, 1 << 34, 1<<36 - 1, 1 << 36, 1<<62 - 1, 1 << 62, math.MaxInt64} {
+		code := codes.Encode(id)
+		decoded, ok := codes.Decode(code)
+		require.True(t, ok)
+		require.Equal(t, id, decoded, code)
+	}
+	require.Len(t, codes.Encode(1<<34-1), 7)
+	require.Len(t, codes.Encode(1<<34), 8)
+
+	// The codes depend on the secret
+	other := NewCodeGenerator(&Config{CodeStrategy: CodeStrategyObfuscated, CodeSecret: "other"})
+	require.NotEqual(t, codes.Encode(1), other.Encode(1))
+
+	// Short minimum lengths still make a permutation
+	codes = NewCodeGenerator(&Config{CodeStrategy: CodeStrategyObfuscated, CodeSecret: "secret", CodeMinLength: 1})
+	seen = map[string]bool{}
+	for id := int64(0); id < 5000; id++ {
+		code := codes.Encode(id)
+		require.False(t, seen[code], code)
+		seen[code] = true
+
+		decoded, ok := codes.Decode(code)
+		require.True(t, ok)
+		require.Equal(t, id, decoded)
+	}
+}
+
+func TestRandomCodes(t *testing.T) {
+	codes := NewCodeGenerator(&Config{CodeStrategy: CodeStrategyRandom})
+
+	seen := map[string]bool{}
+	for range 1000 {
+		code := codes.NewCode()
+		require.Len(t, code, 9)
+		require.Empty(t, strings.Trim(strings.TrimPrefix(code, CodePrefix), base62chars))
+		require.False(t, seen[code], code)
+		seen[code] = true
+	}
+
+	// Random codes cannot be too short
+	codes = NewCodeGenerator(&Config{CodeStrategy: CodeStrategyRandom, CodeMinLength: 2})
+	require.Len(t, codes.NewCode(), len(CodePrefix)+MinRandomCodeLength)
+
+	// The URLs without random code keep their sequential code
+	require.Equal(t, "_10", codes.Encode(62))
+	require.Equal(t, NewCodeGenerator(&Config{}).Scheme(), codes.Scheme())
+}
+
+func TestCodePrefix(t *testing.T) {
+	// Generated codes never look like an alias
+	for _, config := range []Config{{}, {CodeStrategy: CodeStrategyObfuscated, CodeSecret: "secret"}} {
+		require.Error(t, ValidateAlias(NewCodeGenerator(&config).Encode(1_000_000)))
+	}
+	require.Error(t, ValidateAlias(NewCodeGenerator(&Config{CodeStrategy: CodeStrategyRandom}).NewCode()))
+}
+
+func TestCodeScheme(t *testing.T) {
+	schemes := map[string]bool{}
+	for _, config := range []Config{
+		{},
+		{CodeMinLength: 4},
+		{CodeStrategy: CodeStrategyObfuscated, CodeSecret: "secret"},
+		{CodeStrategy: CodeStrategyObfuscated, CodeSecret: "other"},
+		{CodeStrategy: CodeStrategyObfuscated, CodeSecret: "secret", CodeMinLength: 8},
+	} {
+		scheme := NewCodeGenerator(&config).Scheme()
+		require.False(t, schemes[scheme], scheme)
+		schemes[scheme] = true
+	}
+	require.Equal(t, "sequential:1", NewCodeGenerator(&Config{}).Scheme())
+}
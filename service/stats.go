@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// Intervals of the click statistics, named after the date_trunc fields of Postgres
+const (
+	IntervalHour  = "hour"
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// Maximum number of buckets in a statistics response, to keep the response size reasonable
+const MaxStatsBuckets = 1000
+
+// Check if the interval is supported
+func IsValidInterval(interval string) bool {
+	switch interval {
+	case IntervalHour, IntervalDay, IntervalWeek, IntervalMonth:
+		return true
+	default:
+		return false
+	}
+}
+
+// Truncate the time to the start of its bucket in UTC, the same way as date_trunc in Postgres.
+// Weeks start on Monday
+func TruncateTime(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case IntervalHour:
+		return t.Truncate(time.Hour)
+	case IntervalDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case IntervalWeek:
+		// time.Weekday starts on Sunday (0), shift it so that Monday is 0
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return t
+	}
+}
+
+// Get the start of the bucket following the bucket starting at start
+func NextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalHour:
+		return start.Add(time.Hour)
+	case IntervalDay:
+		return start.AddDate(0, 0, 1)
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// List the start of every bucket overlapping [from, to). Return an error if there are more than
+// MaxStatsBuckets buckets
+func ListBuckets(from, to time.Time, interval string) ([]time.Time, error) {
+	buckets := []time.Time{}
+	for start := TruncateTime(from, interval); start.Before(to); start = NextBucket(start, interval) {
+		if len(buckets) == MaxStatsBuckets {
+			return nil, fmt.Errorf("the time range contains more than %d %ss, use a shorter range or a larger interval",
+				MaxStatsBuckets, interval)
+		}
+		buckets = append(buckets, start)
+	}
+	return buckets, nil
+}
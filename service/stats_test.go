@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateTime(t *testing.T) {
+	// Wednesday
+	moment := time.Date(2025, time.March, 12, 15, 42, 7, 0, time.UTC)
+
+	cases := map[string]time.Time{
+		IntervalHour:  time.Date(2025, time.March, 12, 15, 0, 0, 0, time.UTC),
+		IntervalDay:   time.Date(2025, time.March, 12, 0, 0, 0, 0, time.UTC),
+		IntervalWeek:  time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC),
+		IntervalMonth: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for interval, expected := range cases {
+		require.True(t, IsValidInterval(interval))
+		require.Equal(t, expected, TruncateTime(moment, interval), interval)
+	}
+	require.False(t, IsValidInterval("year"))
+
+	// Time in other time zones are truncated in UTC
+	local := time.Date(2025, time.March, 13, 1, 0, 0, 0, time.FixedZone("UTC+7", 7*60*60))
+	require.Equal(t, time.Date(2025, time.March, 12, 0, 0, 0, 0, time.UTC), TruncateTime(local, IntervalDay))
+
+	// Sunday belongs to the week starting on the previous Monday
+	sunday := time.Date(2025, time.March, 16, 23, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC), TruncateTime(sunday, IntervalWeek))
+}
+
+func TestListBuckets(t *testing.T) {
+	from := time.Date(2025, time.January, 30, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	buckets, err := ListBuckets(from, to, IntervalMonth)
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{
+		time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+	}, buckets)
+
+	buckets, err = ListBuckets(from, from.Add(3*time.Hour), IntervalHour)
+	require.NoError(t, err)
+	require.Len(t, buckets, 3)
+
+	// Empty range has no bucket
+	buckets, err = ListBuckets(from, from, IntervalHour)
+	require.NoError(t, err)
+	require.Empty(t, buckets)
+
+	// Too many buckets
+	_, err = ListBuckets(from, from.AddDate(1, 0, 0), IntervalHour)
+	require.Error(t, err)
+}
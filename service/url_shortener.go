@@ -1,6 +1,10 @@
 package service
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strings"
+)
 
 const (
 	base62chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
@@ -45,7 +49,29 @@ func DecodeBase62(str string) int64 {
 	return num
 }
 
-// Method to quickly generate the shorten URL
-func GenerateShortenURL(config *Config, id int64) string {
-	return fmt.Sprintf("http://%s/%s", config.BaseURL, EncodeBase62(id))
+// Method to quickly generate the shorten URL from a short code
+func GenerateShortenURL(config *Config, code string) string {
+	return fmt.Sprintf("http://%s/%s", config.BaseURL, code)
+}
+
+// Method to quickly generate the shorten URL from a custom alias
+func GenerateAliasURL(config *Config, alias string) string {
+	return fmt.Sprintf("http://%s/%s", config.BaseURL, alias)
+}
+
+// Parse a Base62 string strictly: only Base62 characters, and no overflow
+func parseBase62(str string) (int64, bool) {
+	if str == "" {
+		return 0, false
+	}
+
+	var num int64
+	for i := 0; i < len(str); i++ {
+		digit := strings.IndexByte(base62chars, str[i])
+		if digit < 0 || num > (math.MaxInt64-int64(digit))/base {
+			return 0, false
+		}
+		num = num*base + int64(digit)
+	}
+	return num, true
 }
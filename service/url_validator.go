@@ -0,0 +1,174 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	// Maximum length of an URL, most browsers do not handle longer URLs well
+	MaxURLLength = 2048
+)
+
+// Schemes allowed by default, if the config does not specify any
+var defaultAllowedSchemes = []string{"http", "https"}
+
+// Default port of each scheme, which is stripped from the canonical form
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+}
+
+// Error codes of URLError, so that client can handle each case programmatically
+const (
+	URLErrorEmpty         = "empty"
+	URLErrorTooLong       = "too_long"
+	URLErrorMalformed     = "malformed"
+	URLErrorNotAbsolute   = "not_absolute"
+	URLErrorInvalidScheme = "invalid_scheme"
+	URLErrorMissingHost   = "missing_host"
+	URLErrorInvalidHost   = "invalid_host"
+	URLErrorInvalidPort   = "invalid_port"
+	URLErrorUserInfo      = "user_info"
+)
+
+// URLError describes why an URL is rejected by the validation pipeline
+type URLError struct {
+	Code    string
+	Message string
+}
+
+func (err *URLError) Error() string {
+	return err.Message
+}
+
+// Options for the normalization pipeline
+type NormalizeOptions struct {
+	AllowedSchemes []string // Lowercase schemes, default to http and https if empty
+	SortQuery      bool     // Sort the query parameters by key
+}
+
+// Validate and normalize an URL into its canonical form:
+//   - the URL must be absolute, with an allowed scheme and a host
+//   - user info (user:password@) is rejected, since it is often used to disguise the real host
+//   - scheme and host are lowercased, internationalized domain names are converted to punycode
+//   - default ports are stripped, an empty path becomes "/"
+//   - query parameters are optionally sorted by key
+func NormalizeURL(raw string, opts NormalizeOptions) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", &URLError{URLErrorEmpty, "url should not be empty"}
+	}
+	if len(raw) > MaxURLLength {
+		return "", &URLError{URLErrorTooLong, fmt.Sprintf("url must not be longer than %d characters", MaxURLLength)}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", &URLError{URLErrorMalformed, "url is malformed"}
+	}
+
+	// Scheme
+	if u.Scheme == "" {
+		return "", &URLError{URLErrorNotAbsolute, "url must be absolute, including the scheme (e.g. https://)"}
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	allowed := opts.AllowedSchemes
+	if len(allowed) == 0 {
+		allowed = defaultAllowedSchemes
+	}
+	if !slices.Contains(allowed, u.Scheme) {
+		return "", &URLError{URLErrorInvalidScheme,
+			fmt.Sprintf("url scheme '%s' is not allowed, allowed schemes: %s", u.Scheme, strings.Join(allowed, ", "))}
+	}
+
+	// Opaque URL (like "http:example.com") has no host
+	if u.Opaque != "" || u.Host == "" {
+		return "", &URLError{URLErrorMissingHost, "url must have a host"}
+	}
+	if u.User != nil {
+		return "", &URLError{URLErrorUserInfo, "url must not contain user information"}
+	}
+
+	// Host
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+
+	// Port
+	port := u.Port()
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return "", &URLError{URLErrorInvalidPort, "url port is invalid"}
+		}
+		if defaultPorts[u.Scheme] == port {
+			port = ""
+		}
+	}
+	if strings.Contains(host, ":") {
+		// IPv6 literal
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host = host + ":" + port
+	}
+	u.Host = host
+
+	// Path
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+
+	// Query
+	if opts.SortQuery {
+		u.RawQuery = sortQuery(u.RawQuery)
+	}
+
+	return u.String(), nil
+}
+
+// Lowercase the host and convert internationalized domain name into punycode
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", &URLError{URLErrorMissingHost, "url must have a host"}
+	}
+
+	// IP literal is kept as is
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", &URLError{URLErrorInvalidHost, fmt.Sprintf("url host '%s' is invalid", host)}
+	}
+	return ascii, nil
+}
+
+// Sort the query parameters by key, keeping the original encoding and the order of repeated keys
+func sortQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	params := slices.DeleteFunc(strings.Split(rawQuery, "&"), func(param string) bool {
+		return param == ""
+	})
+	sort.SliceStable(params, func(i, j int) bool {
+		keyI, _, _ := strings.Cut(params[i], "=")
+		keyJ, _, _ := strings.Cut(params[j], "=")
+		return keyI < keyJ
+	})
+	return strings.Join(params, "&")
+}
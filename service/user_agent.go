@@ -0,0 +1,117 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Device types of a user agent
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Browser and OS name of a user agent that no rule matches
+const UnknownUserAgent = "Other"
+
+// Maximum length of a captured version, longer versions are truncated
+const maxUserAgentVersionLength = 32
+
+// Browser, OS and device type parsed from a User-Agent header
+type UserAgentInfo struct {
+	Browser        string // Browser family, e.g. "Chrome", or the bot name for bots
+	BrowserVersion string // Full browser version, empty if unknown
+	OS             string // OS family, e.g. "Android"
+	Device         string // One of DeviceDesktop, DeviceMobile, DeviceTablet or DeviceBot
+}
+
+// A rule matching a user agent. The first capturing group of the pattern, if any, is the version
+type userAgentRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	regex   *regexp.Regexp
+}
+
+// Rules of each part of the user agent, tried in order until one matches. More specific rules must
+// come first, e.g. Edge before Chrome, since the Edge user agent also contains "Chrome/"
+type userAgentRules struct {
+	Bots     []userAgentRule `json:"bots"`
+	Browsers []userAgentRule `json:"browsers"`
+	OS       []userAgentRule `json:"os"`
+	Devices  []userAgentRule `json:"devices"`
+}
+
+//go:embed user_agent_rules.json
+var userAgentRulesJSON []byte
+
+// Rules compiled from the embedded rule set
+var uaRules = mustLoadUserAgentRules(userAgentRulesJSON)
+
+// Helper function to compile the rule set. Since the rule set is embedded, an invalid rule is a
+// programming error
+func mustLoadUserAgentRules(data []byte) userAgentRules {
+	var rules userAgentRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		panic("invalid user agent rules: " + err.Error())
+	}
+	for _, group := range [][]userAgentRule{rules.Bots, rules.Browsers, rules.OS, rules.Devices} {
+		for i := range group {
+			group[i].regex = regexp.MustCompile(group[i].Pattern)
+		}
+	}
+	return rules
+}
+
+// Helper function to find the first matching rule. Return the rule name and captured version
+func matchUserAgentRules(rules []userAgentRule, ua string) (string, string, bool) {
+	for _, rule := range rules {
+		match := rule.regex.FindStringSubmatch(ua)
+		if match == nil {
+			continue
+		}
+
+		version := ""
+		if len(match) > 1 {
+			version = match[1]
+		}
+		if len(version) > maxUserAgentVersionLength {
+			version = version[:maxUserAgentVersionLength]
+		}
+		return rule.Name, version, true
+	}
+	return "", "", false
+}
+
+// Parse a User-Agent header into browser, OS and device type using the embedded rule set.
+// An empty user agent is treated as a bot, since browsers always send one
+func ParseUserAgent(ua string) UserAgentInfo {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return UserAgentInfo{Browser: UnknownUserAgent, OS: UnknownUserAgent, Device: DeviceBot}
+	}
+
+	info := UserAgentInfo{Browser: UnknownUserAgent, OS: UnknownUserAgent, Device: DeviceDesktop}
+	if os, _, ok := matchUserAgentRules(uaRules.OS, ua); ok {
+		info.OS = os
+	}
+
+	if bot, version, ok := matchUserAgentRules(uaRules.Bots, ua); ok {
+		info.Browser = bot
+		info.BrowserVersion = version
+		info.Device = DeviceBot
+		return info
+	}
+
+	if browser, version, ok := matchUserAgentRules(uaRules.Browsers, ua); ok {
+		info.Browser = browser
+		info.BrowserVersion = version
+	}
+	if device, _, ok := matchUserAgentRules(uaRules.Devices, ua); ok {
+		info.Device = device
+	}
+	return info
+}
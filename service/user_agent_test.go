@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want UserAgentInfo
+	}{
+		{
+			ua: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
+				"Chrome/124.0.0.0 Safari/537.36",
+			want: UserAgentInfo{Browser: "Chrome", BrowserVersion: "124.0.0.0", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			ua: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
+				"Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.51",
+			want: UserAgentInfo{Browser: "Edge", BrowserVersion: "124.0.2478.51", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:125.0) Gecko/20100101 Firefox/125.0",
+			want: UserAgentInfo{Browser: "Firefox", BrowserVersion: "125.0", OS: "macOS", Device: DeviceDesktop},
+		},
+		{
+			ua: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) " +
+				"Version/17.4 Mobile/15E148 Safari/604.1",
+			want: UserAgentInfo{Browser: "Safari", BrowserVersion: "17.4", OS: "iOS", Device: DeviceMobile},
+		},
+		{
+			ua: "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) " +
+				"Version/16.6 Mobile/15E148 Safari/604.1",
+			want: UserAgentInfo{Browser: "Safari", BrowserVersion: "16.6", OS: "iPadOS", Device: DeviceTablet},
+		},
+		{
+			ua: "Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) " +
+				"SamsungBrowser/24.0 Chrome/117.0.0.0 Mobile Safari/537.36",
+			want: UserAgentInfo{Browser: "Samsung Internet", BrowserVersion: "24.0", OS: "Android", Device: DeviceMobile},
+		},
+		{
+			ua: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) " +
+				"Chrome/124.0.0.0 Safari/537.36",
+			want: UserAgentInfo{Browser: "Chrome", BrowserVersion: "124.0.0.0", OS: "Android", Device: DeviceTablet},
+		},
+		{
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: UserAgentInfo{Browser: "Googlebot", BrowserVersion: "2.1", OS: UnknownUserAgent, Device: DeviceBot},
+		},
+		{
+			ua:   "curl/8.5.0",
+			want: UserAgentInfo{Browser: "curl", BrowserVersion: "8.5.0", OS: UnknownUserAgent, Device: DeviceBot},
+		},
+		{
+			ua:   "",
+			want: UserAgentInfo{Browser: UnknownUserAgent, OS: UnknownUserAgent, Device: DeviceBot},
+		},
+		{
+			ua:   "SomethingElse/1.0",
+			want: UserAgentInfo{Browser: UnknownUserAgent, OS: UnknownUserAgent, Device: DeviceDesktop},
+		},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.want, ParseUserAgent(test.ua), test.ua)
+	}
+}
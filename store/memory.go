@@ -0,0 +1,804 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+)
+
+// Primary key of a visitor
+type visitorKey struct {
+	ip    string
+	urlID int64
+	time  int64 // Unix microseconds
+}
+
+// Data of the in-memory store. Records are kept in insertion order, which is also the ID order
+type memoryData struct {
+	urls         []db.Url
+	visitors     []db.Visitor
+	visitorKeys  map[visitorKey]bool
+	users        []db.User
+	apiKeys      []db.ApiKey
+	settings     map[string]string
+	nextURLID    int64
+	nextUserID   int64
+	nextAPIKeyID int64
+}
+
+// MemoryStore is a Store keeping everything in memory, for tests and throwaway deployments. The data
+// is lost when the process exits. Transactions hold an exclusive lock on the whole store and keep an
+// undo log to roll back their changes
+type MemoryStore struct {
+	mu   *sync.Mutex
+	data *memoryData
+	undo *[]func() // Undo log of the transaction, nil outside of a transaction
+}
+
+// Constructor method for MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mu: &sync.Mutex{},
+		data: &memoryData{
+			visitorKeys:  map[visitorKey]bool{},
+			settings:     map[string]string{},
+			nextURLID:    1,
+			nextUserID:   1,
+			nextAPIKeyID: 1,
+		},
+	}
+}
+
+func (store *MemoryStore) ExecTx(ctx context.Context, fn func(tx Store) error) error {
+	if store.undo == nil {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+	}
+
+	undo := []func(){}
+	if err := fn(&MemoryStore{mu: store.mu, data: store.data, undo: &undo}); err != nil {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+		return err
+	}
+
+	// A nested transaction hands its changes over to the parent transaction, which may still roll back
+	if store.undo != nil {
+		*store.undo = append(*store.undo, undo...)
+	}
+	return nil
+}
+
+func (store *MemoryStore) Close() error {
+	return nil
+}
+
+// Helper method to lock the store for a single query. Inside a transaction, the lock is already held
+func (store *MemoryStore) lock() func() {
+	if store.undo != nil {
+		return func() {}
+	}
+	store.mu.Lock()
+	return store.mu.Unlock
+}
+
+// Helper method to record how to undo a change, if inside a transaction
+func (store *MemoryStore) onRollback(fn func()) {
+	if store.undo != nil {
+		*store.undo = append(*store.undo, fn)
+	}
+}
+
+// Helper function to append a record, undone by truncating the list
+func memoryAppend[T any](store *MemoryStore, list *[]T, item T) {
+	n := len(*list)
+	*list = append(*list, item)
+	store.onRollback(func() { *list = (*list)[:n] })
+}
+
+// Helper function to replace a record, undone by restoring the previous record
+func memorySet[T any](store *MemoryStore, list *[]T, i int, item T) {
+	previous := (*list)[i]
+	(*list)[i] = item
+	store.onRollback(func() { (*list)[i] = previous })
+}
+
+// Helper function to remove the records matching the predicate into a new list, undone by restoring
+// the previous list. Return the number of removed records
+func memoryDelete[T any](store *MemoryStore, list *[]T, remove func(T) bool) int64 {
+	previous := *list
+	kept := make([]T, 0, len(previous))
+	for _, item := range previous {
+		if !remove(item) {
+			kept = append(kept, item)
+		}
+	}
+	*list = kept
+	store.onRollback(func() { *list = previous })
+	return int64(len(previous) - len(kept))
+}
+
+// Current time, with the precision of PostgreSQL
+func memoryNow() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// Helper function to round a nullable time to the precision of PostgreSQL
+func memoryNullTime(t sql.NullTime) sql.NullTime {
+	if t.Valid {
+		t.Time = t.Time.Truncate(time.Microsecond)
+	}
+	return t
+}
+
+// Helper function to get a page of a list, like OFFSET and LIMIT
+func memoryPage[T any](list []T, offset, limit int32) []T {
+	start := min(max(int(offset), 0), len(list))
+	end := min(start+max(int(limit), 0), len(list))
+	return slices.Clone(list[start:end])
+}
+
+// Convert a (I)LIKE pattern into a case-insensitive regular expression. Backslash escapes the next
+// character, like the default escape character of PostgreSQL
+func likePattern(pattern string) *regexp.Regexp {
+	var builder strings.Builder
+	builder.WriteString("(?is)^")
+	escaped := false
+	for _, char := range pattern {
+		switch {
+		case escaped:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+			escaped = false
+		case char == '\\':
+			escaped = true
+		case char == '%':
+			builder.WriteString(".*")
+		case char == '_':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	builder.WriteString("$")
+	return regexp.MustCompile(builder.String())
+}
+
+// Helper method to find the index of an URL by ID, -1 if not found
+func (store *MemoryStore) findURL(id int64) int {
+	return slices.IndexFunc(store.data.urls, func(url db.Url) bool { return url.ID == id })
+}
+
+// Helper method to check the unique alias of an URL, ignoring the URL with the given ID
+func (store *MemoryStore) checkAlias(alias sql.NullString, id int64) error {
+	if !alias.Valid {
+		return nil
+	}
+	for _, url := range store.data.urls {
+		if url.ID != id && url.Alias == alias {
+			return uniqueViolation("url_alias_key")
+		}
+	}
+	return nil
+}
+
+// Helper method to check the unique random code of a new URL
+func (store *MemoryStore) checkCode(code sql.NullString) error {
+	if code.Valid && slices.ContainsFunc(store.data.urls, func(url db.Url) bool { return url.Code == code }) {
+		return uniqueViolation("url_code_key")
+	}
+	return nil
+}
+
+// Helper function to check the redirect type of an URL, NULL or a redirect status
+func checkRedirectType(redirectType sql.NullInt32) error {
+	if redirectType.Valid && !slices.Contains([]int32{301, 302, 307, 308}, redirectType.Int32) {
+		return checkViolation("url", "url_redirect_type_check")
+	}
+	return nil
+}
+
+// Helper method to count the visitors of each URL, with and without bots
+func (store *MemoryStore) countVisitors() (map[int64]int64, map[int64]int64) {
+	total, human := map[int64]int64{}, map[int64]int64{}
+	for _, visitor := range store.data.visitors {
+		total[visitor.UrlID]++
+		if !visitor.IsBot {
+			human[visitor.UrlID]++
+		}
+	}
+	return total, human
+}
+
+// Helper method to list the visits of an URL in [from, to)
+func (store *MemoryStore) listVisits(urlID int64, from, to time.Time) []visit {
+	visits := []visit{}
+	for _, visitor := range store.data.visitors {
+		if visitor.UrlID != urlID || visitor.TimeVisited.Before(from) || !visitor.TimeVisited.Before(to) {
+			continue
+		}
+		visits = append(visits, visit{
+			ip:      visitor.Ip,
+			time:    visitor.TimeVisited,
+			isBot:   visitor.IsBot,
+			country: visitor.Country,
+			browser: visitor.Browser,
+			os:      visitor.Os,
+			device:  visitor.Device,
+		})
+	}
+	return visits
+}
+
+// Helper method to insert a visitor, checking the primary and foreign keys
+func (store *MemoryStore) insertVisitor(arg db.CreateVisitorParams) (db.Visitor, error) {
+	if store.findURL(arg.UrlID) < 0 {
+		return db.Visitor{}, foreignKeyViolation("visitor", "visitor_url_id_fkey")
+	}
+
+	visitor := db.Visitor(arg)
+	visitor.TimeVisited = visitor.TimeVisited.Truncate(time.Microsecond)
+	key := visitorKey{ip: visitor.Ip, urlID: visitor.UrlID, time: visitor.TimeVisited.UnixMicro()}
+	if store.data.visitorKeys[key] {
+		return db.Visitor{}, uniqueViolation("visitor_pkey")
+	}
+
+	store.data.visitorKeys[key] = true
+	store.onRollback(func() { delete(store.data.visitorKeys, key) })
+	memoryAppend(store, &store.data.visitors, visitor)
+	return visitor, nil
+}
+
+// Helper method to remove visitors, keeping the primary key index in sync
+func (store *MemoryStore) deleteVisitors(remove func(db.Visitor) bool) {
+	memoryDelete(store, &store.data.visitors, func(visitor db.Visitor) bool {
+		if remove(visitor) {
+			key := visitorKey{ip: visitor.Ip, urlID: visitor.UrlID, time: visitor.TimeVisited.UnixMicro()}
+			delete(store.data.visitorKeys, key)
+			store.onRollback(func() { store.data.visitorKeys[key] = true })
+			return true
+		}
+		return false
+	})
+}
+
+// URL queries
+
+func (store *MemoryStore) CreateURL(ctx context.Context, arg db.CreateURLParams) (db.Url, error) {
+	defer store.lock()()
+
+	if err := store.checkAlias(arg.Alias, 0); err != nil {
+		return db.Url{}, err
+	}
+	if err := store.checkCode(arg.Code); err != nil {
+		return db.Url{}, err
+	}
+	if err := checkRedirectType(arg.RedirectType); err != nil {
+		return db.Url{}, err
+	}
+	if arg.OwnerID.Valid && !slices.ContainsFunc(store.data.users, func(user db.User) bool {
+		return user.ID == arg.OwnerID.Int64
+	}) {
+		return db.Url{}, foreignKeyViolation("url", "url_owner_id_fkey")
+	}
+
+	url := db.Url{
+		ID:                  store.data.nextURLID,
+		OriginalUrl:         arg.OriginalUrl,
+		Alias:               arg.Alias,
+		ExpiresAt:           memoryNullTime(arg.ExpiresAt),
+		MaxClicks:           arg.MaxClicks,
+		FallbackUrl:         arg.FallbackUrl,
+		OwnerID:             arg.OwnerID,
+		TimeCreated:         memoryNow(),
+		RedirectType:        arg.RedirectType,
+		PasswordHash:        arg.PasswordHash,
+		Title:               arg.Title,
+		Preview:             arg.Preview,
+		InterstitialSeconds: arg.InterstitialSeconds,
+		Code:                arg.Code,
+	}
+	store.data.nextURLID++
+	memoryAppend(store, &store.data.urls, url)
+	return url, nil
+}
+
+func (store *MemoryStore) GetURL(ctx context.Context, id int64) (db.Url, error) {
+	defer store.lock()()
+
+	if i := store.findURL(id); i >= 0 {
+		return store.data.urls[i], nil
+	}
+	return db.Url{}, sql.ErrNoRows
+}
+
+func (store *MemoryStore) GetURLByAlias(ctx context.Context, alias sql.NullString) (db.Url, error) {
+	defer store.lock()()
+
+	for _, url := range store.data.urls {
+		if alias.Valid && url.Alias == alias {
+			return url, nil
+		}
+	}
+	return db.Url{}, sql.ErrNoRows
+}
+
+func (store *MemoryStore) GetURLByCode(ctx context.Context, code sql.NullString) (db.Url, error) {
+	defer store.lock()()
+
+	for _, url := range store.data.urls {
+		if code.Valid && url.Code == code {
+			return url, nil
+		}
+	}
+	return db.Url{}, sql.ErrNoRows
+}
+
+func (store *MemoryStore) ListURLByOriginal(ctx context.Context, arg db.ListURLByOriginalParams) ([]db.Url, error) {
+	defer store.lock()()
+
+	urls := []db.Url{}
+	for _, url := range store.data.urls {
+		if url.OriginalUrl == arg.OriginalUrl && url.OwnerID == arg.OwnerID && !url.TimeDeleted.Valid {
+			urls = append(urls, url)
+		}
+	}
+	return urls, nil
+}
+
+func (store *MemoryStore) ListURL(ctx context.Context, arg db.ListURLParams) ([]db.ListURLRow, error) {
+	defer store.lock()()
+
+	total, human := store.countVisitors()
+	rows := []db.ListURLRow{}
+	for _, url := range store.data.urls {
+		if url.TimeDeleted.Valid || (arg.OwnerID.Valid && url.OwnerID != arg.OwnerID) {
+			continue
+		}
+		rows = append(rows, db.ListURLRow{
+			ID:                  url.ID,
+			OriginalUrl:         url.OriginalUrl,
+			Alias:               url.Alias,
+			ExpiresAt:           url.ExpiresAt,
+			MaxClicks:           url.MaxClicks,
+			FallbackUrl:         url.FallbackUrl,
+			OwnerID:             url.OwnerID,
+			TimeCreated:         url.TimeCreated,
+			TimeDeleted:         url.TimeDeleted,
+			RedirectType:        url.RedirectType,
+			PasswordHash:        url.PasswordHash,
+			Title:               url.Title,
+			Preview:             url.Preview,
+			InterstitialSeconds: url.InterstitialSeconds,
+			Code:                url.Code,
+			TotalVisitors:       total[url.ID],
+			HumanVisitors:       human[url.ID],
+		})
+	}
+	return memoryPage(rows, arg.Offset, arg.Limit), nil
+}
+
+func (store *MemoryStore) ExportURL(ctx context.Context, arg db.ExportURLParams) ([]db.ExportURLRow, error) {
+	rows, err := store.ListURL(ctx, db.ListURLParams{OwnerID: arg.OwnerID, Limit: math.MaxInt32})
+	if err != nil {
+		return nil, err
+	}
+
+	exported := []db.ExportURLRow{}
+	for _, row := range rows {
+		if row.ID > arg.AfterID && len(exported) < int(arg.Limit) {
+			exported = append(exported, db.ExportURLRow(row))
+		}
+	}
+	return exported, nil
+}
+
+func (store *MemoryStore) CountURL(ctx context.Context, ownerID sql.NullInt64) (int64, error) {
+	defer store.lock()()
+
+	count := int64(0)
+	for _, url := range store.data.urls {
+		if !url.TimeDeleted.Valid && (!ownerID.Valid || url.OwnerID == ownerID) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (store *MemoryStore) CountURLWithoutCode(ctx context.Context) (int64, error) {
+	defer store.lock()()
+
+	count := int64(0)
+	for _, url := range store.data.urls {
+		if !url.Code.Valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (store *MemoryStore) UpdateURL(ctx context.Context, arg db.UpdateURLParams) (db.Url, error) {
+	defer store.lock()()
+
+	i := store.findURL(arg.ID)
+	if i < 0 || store.data.urls[i].TimeDeleted.Valid {
+		return db.Url{}, sql.ErrNoRows
+	}
+	if err := store.checkAlias(arg.Alias, arg.ID); err != nil {
+		return db.Url{}, err
+	}
+	if err := checkRedirectType(arg.RedirectType); err != nil {
+		return db.Url{}, err
+	}
+
+	url := store.data.urls[i]
+	url.OriginalUrl = arg.OriginalUrl
+	url.Alias = arg.Alias
+	url.ExpiresAt = memoryNullTime(arg.ExpiresAt)
+	url.MaxClicks = arg.MaxClicks
+	url.FallbackUrl = arg.FallbackUrl
+	url.RedirectType = arg.RedirectType
+	url.PasswordHash = arg.PasswordHash
+	url.Title = arg.Title
+	url.Preview = arg.Preview
+	url.InterstitialSeconds = arg.InterstitialSeconds
+	memorySet(store, &store.data.urls, i, url)
+	return url, nil
+}
+
+func (store *MemoryStore) SoftDeleteURL(ctx context.Context, id int64) (int64, error) {
+	defer store.lock()()
+
+	i := store.findURL(id)
+	if i < 0 || store.data.urls[i].TimeDeleted.Valid {
+		return 0, nil
+	}
+
+	url := store.data.urls[i]
+	url.TimeDeleted = sql.NullTime{Time: memoryNow(), Valid: true}
+	memorySet(store, &store.data.urls, i, url)
+	return 1, nil
+}
+
+func (store *MemoryStore) DeleteURL(ctx context.Context, id int64) error {
+	defer store.lock()()
+
+	memoryDelete(store, &store.data.urls, func(url db.Url) bool { return url.ID == id })
+
+	// Visitors are deleted on cascade
+	store.deleteVisitors(func(visitor db.Visitor) bool { return visitor.UrlID == id })
+	return nil
+}
+
+// Visitor queries
+
+func (store *MemoryStore) CreateVisitor(ctx context.Context, arg db.CreateVisitorParams) (db.Visitor, error) {
+	defer store.lock()()
+
+	return store.insertVisitor(arg)
+}
+
+func (store *MemoryStore) CreateVisitors(ctx context.Context, arg db.CreateVisitorsParams) (int64, error) {
+	defer store.lock()()
+
+	// Like ON CONFLICT DO NOTHING, visitors that cannot be inserted are skipped
+	count := int64(0)
+	for _, visitor := range splitVisitors(arg) {
+		if _, err := store.insertVisitor(visitor); err == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (store *MemoryStore) CountVisitorForUpdate(ctx context.Context, id int64) (int64, error) {
+	defer store.lock()()
+
+	if store.findURL(id) < 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	_, human := store.countVisitors()
+	return human[id], nil
+}
+
+func (store *MemoryStore) ListVisitor(ctx context.Context, arg db.ListVisitorParams) ([]db.ListVisitorRow, error) {
+	defer store.lock()()
+
+	i := store.findURL(arg.UrlID)
+	if i < 0 {
+		return []db.ListVisitorRow{}, nil
+	}
+	url := store.data.urls[i]
+
+	// Helpers to check a filter like the SQL query, a NULL filter matches everything
+	like := func(filter sql.NullString, value string) bool {
+		return !filter.Valid || likePattern(filter.String).MatchString(value)
+	}
+	equal := func(filter sql.NullString, value string) bool {
+		return !filter.Valid || value == filter.String
+	}
+	equalFold := func(filter sql.NullString, value string) bool {
+		return !filter.Valid || strings.EqualFold(value, filter.String)
+	}
+	country := sql.NullString{String: strings.ToUpper(arg.Country.String), Valid: arg.Country.Valid}
+	device := sql.NullString{String: strings.ToLower(arg.Device.String), Valid: arg.Device.Valid}
+
+	rows := []db.ListVisitorRow{}
+	for _, visitor := range store.data.visitors {
+		if visitor.UrlID != arg.UrlID ||
+			!like(arg.Referrer, visitor.Referrer) ||
+			!like(arg.UserAgent, visitor.UserAgent) ||
+			!like(arg.AcceptLanguage, visitor.AcceptLanguage) ||
+			!like(arg.Host, visitor.Host) ||
+			!like(arg.QueryString, visitor.QueryString) ||
+			!equal(country, visitor.Country) ||
+			!equalFold(arg.Browser, visitor.Browser) ||
+			!equalFold(arg.Os, visitor.Os) ||
+			!equal(device, visitor.Device) ||
+			(arg.IsBot.Valid && visitor.IsBot != arg.IsBot.Bool) ||
+			(arg.FromTime.Valid && visitor.TimeVisited.Before(arg.FromTime.Time)) ||
+			(arg.ToTime.Valid && !visitor.TimeVisited.Before(arg.ToTime.Time)) {
+			continue
+		}
+
+		rows = append(rows, db.ListVisitorRow{
+			Ip:             visitor.Ip,
+			TimeVisited:    visitor.TimeVisited,
+			UrlID:          visitor.UrlID,
+			Referrer:       visitor.Referrer,
+			UserAgent:      visitor.UserAgent,
+			AcceptLanguage: visitor.AcceptLanguage,
+			Host:           visitor.Host,
+			QueryString:    visitor.QueryString,
+			Country:        visitor.Country,
+			Region:         visitor.Region,
+			City:           visitor.City,
+			Asn:            visitor.Asn,
+			AsOrg:          visitor.AsOrg,
+			Browser:        visitor.Browser,
+			BrowserVersion: visitor.BrowserVersion,
+			Os:             visitor.Os,
+			Device:         visitor.Device,
+			IsBot:          visitor.IsBot,
+			OriginalUrl:    url.OriginalUrl,
+			Alias:          url.Alias,
+			Code:           url.Code,
+		})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if !rows[i].TimeVisited.Equal(rows[j].TimeVisited) {
+			return rows[i].TimeVisited.Before(rows[j].TimeVisited)
+		}
+		return rows[i].Ip < rows[j].Ip
+	})
+	return memoryPage(rows, arg.Offset, arg.Limit), nil
+}
+
+func (store *MemoryStore) ExportVisitor(ctx context.Context, arg db.ExportVisitorParams) ([]db.ExportVisitorRow, error) {
+	rows, err := store.ListVisitor(ctx, db.ListVisitorParams{UrlID: arg.UrlID, Limit: math.MaxInt32})
+	if err != nil {
+		return nil, err
+	}
+
+	exported := []db.ExportVisitorRow{}
+	for _, row := range rows {
+		after := row.TimeVisited.After(arg.AfterTime) || (row.TimeVisited.Equal(arg.AfterTime) && row.Ip > arg.AfterIp)
+		if !after || len(exported) >= int(arg.Limit) {
+			continue
+		}
+		exported = append(exported, db.ExportVisitorRow{
+			Ip:             row.Ip,
+			TimeVisited:    row.TimeVisited,
+			Referrer:       row.Referrer,
+			UserAgent:      row.UserAgent,
+			AcceptLanguage: row.AcceptLanguage,
+			Host:           row.Host,
+			QueryString:    row.QueryString,
+			Country:        row.Country,
+			Region:         row.Region,
+			City:           row.City,
+			Asn:            row.Asn,
+			AsOrg:          row.AsOrg,
+			Browser:        row.Browser,
+			BrowserVersion: row.BrowserVersion,
+			Os:             row.Os,
+			Device:         row.Device,
+			IsBot:          row.IsBot,
+		})
+	}
+	return exported, nil
+}
+
+func (store *MemoryStore) ListVisitorStats(ctx context.Context, arg db.ListVisitorStatsParams) ([]db.ListVisitorStatsRow, error) {
+	defer store.lock()()
+
+	return visitStats(store.listVisits(arg.UrlID, arg.FromTime, arg.ToTime), arg.Bucket), nil
+}
+
+func (store *MemoryStore) ListVisitorBreakdown(
+	ctx context.Context, arg db.ListVisitorBreakdownParams,
+) ([]db.ListVisitorBreakdownRow, error) {
+	defer store.lock()()
+
+	return visitBreakdown(store.listVisits(arg.UrlID, arg.FromTime, arg.ToTime), arg.Dimension), nil
+}
+
+func (store *MemoryStore) GetVisitorSummary(ctx context.Context, arg db.GetVisitorSummaryParams) (db.GetVisitorSummaryRow, error) {
+	defer store.lock()()
+
+	return visitSummary(store.listVisits(arg.UrlID, arg.FromTime, arg.ToTime)), nil
+}
+
+func (store *MemoryStore) DeleteVisitor(ctx context.Context, arg db.DeleteVisitorParams) error {
+	defer store.lock()()
+
+	store.deleteVisitors(func(visitor db.Visitor) bool {
+		return visitor.Ip == arg.Ip && visitor.UrlID == arg.UrlID && visitor.TimeVisited.Equal(arg.TimeVisited)
+	})
+	return nil
+}
+
+// User queries
+
+func (store *MemoryStore) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	defer store.lock()()
+
+	if slices.ContainsFunc(store.data.users, func(user db.User) bool { return user.Name == arg.Name }) {
+		return db.User{}, uniqueViolation("user_name_key")
+	}
+
+	user := db.User{
+		ID:          store.data.nextUserID,
+		Name:        arg.Name,
+		IsAdmin:     arg.IsAdmin,
+		TimeCreated: memoryNow(),
+	}
+	store.data.nextUserID++
+	memoryAppend(store, &store.data.users, user)
+	return user, nil
+}
+
+func (store *MemoryStore) GetUser(ctx context.Context, id int64) (db.User, error) {
+	defer store.lock()()
+
+	for _, user := range store.data.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return db.User{}, sql.ErrNoRows
+}
+
+func (store *MemoryStore) ListUser(ctx context.Context, arg db.ListUserParams) ([]db.User, error) {
+	defer store.lock()()
+
+	return memoryPage(store.data.users, arg.Offset, arg.Limit), nil
+}
+
+func (store *MemoryStore) DeleteUser(ctx context.Context, id int64) error {
+	defer store.lock()()
+
+	// URLs are not deleted on cascade, so a user owning URLs cannot be deleted
+	if slices.ContainsFunc(store.data.urls, func(url db.Url) bool { return url.OwnerID.Valid && url.OwnerID.Int64 == id }) {
+		return errors.New(`update or delete on table "user" violates foreign key constraint "url_owner_id_fkey" on table "url"`)
+	}
+
+	memoryDelete(store, &store.data.users, func(user db.User) bool { return user.ID == id })
+	memoryDelete(store, &store.data.apiKeys, func(apiKey db.ApiKey) bool { return apiKey.UserID == id })
+	return nil
+}
+
+// API key queries
+
+func (store *MemoryStore) CreateAPIKey(ctx context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+	defer store.lock()()
+
+	if slices.ContainsFunc(store.data.apiKeys, func(apiKey db.ApiKey) bool { return apiKey.KeyHash == arg.KeyHash }) {
+		return db.ApiKey{}, uniqueViolation("api_key_key_hash_key")
+	}
+	if !slices.ContainsFunc(store.data.users, func(user db.User) bool { return user.ID == arg.UserID }) {
+		return db.ApiKey{}, foreignKeyViolation("api_key", "api_key_user_id_fkey")
+	}
+
+	apiKey := db.ApiKey{
+		ID:          store.data.nextAPIKeyID,
+		Name:        arg.Name,
+		KeyHash:     arg.KeyHash,
+		Prefix:      arg.Prefix,
+		UserID:      arg.UserID,
+		TimeCreated: memoryNow(),
+	}
+	store.data.nextAPIKeyID++
+	memoryAppend(store, &store.data.apiKeys, apiKey)
+	return apiKey, nil
+}
+
+func (store *MemoryStore) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (db.GetActiveAPIKeyByHashRow, error) {
+	defer store.lock()()
+
+	for _, apiKey := range store.data.apiKeys {
+		if apiKey.KeyHash != keyHash || apiKey.TimeRevoked.Valid {
+			continue
+		}
+		for _, user := range store.data.users {
+			if user.ID == apiKey.UserID {
+				return db.GetActiveAPIKeyByHashRow{
+					ID:          apiKey.ID,
+					Name:        apiKey.Name,
+					KeyHash:     apiKey.KeyHash,
+					Prefix:      apiKey.Prefix,
+					UserID:      apiKey.UserID,
+					TimeCreated: apiKey.TimeCreated,
+					TimeRevoked: apiKey.TimeRevoked,
+					UserName:    user.Name,
+					IsAdmin:     user.IsAdmin,
+				}, nil
+			}
+		}
+	}
+	return db.GetActiveAPIKeyByHashRow{}, sql.ErrNoRows
+}
+
+func (store *MemoryStore) ListAPIKey(ctx context.Context, arg db.ListAPIKeyParams) ([]db.ApiKey, error) {
+	defer store.lock()()
+
+	return memoryPage(store.data.apiKeys, arg.Offset, arg.Limit), nil
+}
+
+func (store *MemoryStore) RevokeAPIKey(ctx context.Context, id int64) (int64, error) {
+	defer store.lock()()
+
+	i := slices.IndexFunc(store.data.apiKeys, func(apiKey db.ApiKey) bool { return apiKey.ID == id })
+	if i < 0 || store.data.apiKeys[i].TimeRevoked.Valid {
+		return 0, nil
+	}
+
+	apiKey := store.data.apiKeys[i]
+	apiKey.TimeRevoked = sql.NullTime{Time: memoryNow(), Valid: true}
+	memorySet(store, &store.data.apiKeys, i, apiKey)
+	return 1, nil
+}
+
+func (store *MemoryStore) DeleteAPIKey(ctx context.Context, id int64) error {
+	defer store.lock()()
+
+	memoryDelete(store, &store.data.apiKeys, func(apiKey db.ApiKey) bool { return apiKey.ID == id })
+	return nil
+}
+
+// Setting queries
+
+func (store *MemoryStore) GetSetting(ctx context.Context, name string) (string, error) {
+	defer store.lock()()
+
+	value, ok := store.data.settings[name]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return value, nil
+}
+
+func (store *MemoryStore) SetSetting(ctx context.Context, arg db.SetSettingParams) error {
+	defer store.lock()()
+
+	previous, ok := store.data.settings[arg.Name]
+	store.data.settings[arg.Name] = arg.Value
+	store.onRollback(func() {
+		if ok {
+			store.data.settings[arg.Name] = previous
+		} else {
+			delete(store.data.settings, arg.Name)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,255 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migration is a versioned change of the schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// State of a migration in the database
+type MigrationStatus struct {
+	Migration
+	Applied     bool
+	TimeApplied time.Time // Zero if not applied
+}
+
+// Name of a migration file, e.g. 000001_init.up.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load the migrations of the directory, sorted by version. Every migration must have both an up and a
+// down file
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration := migrations[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2]}
+			migrations[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	list := []Migration{}
+	for _, migration := range migrations {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both an up and a down file",
+				migration.Version, migration.Name)
+		}
+		list = append(list, *migration)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Database specific statements of the migrator
+type migrationDialect struct {
+	createTable   string
+	selectApplied string // Select the version and time of the applied migrations
+	insertVersion string // Insert the version, name and time of an applied migration
+	deleteVersion string
+	timeValue     func(t time.Time) any  // Value stored for a time
+	scanTime      func(t *time.Time) any // Destination to scan a stored time
+	lock          func(ctx context.Context, conn *sql.Conn) error
+	unlock        func(ctx context.Context, conn *sql.Conn) error
+}
+
+// Migrator applies and reverts the versioned migrations of a database. Migrations run on a single
+// connection holding a lock, so that only one instance migrates the database at a time, and each migration
+// is applied in its own transaction along with its version in the schema_migrations table
+type Migrator struct {
+	conn       *sql.DB
+	migrations []Migration
+	dialect    migrationDialect
+}
+
+// Migratable is implemented by the stores with a versioned schema. The in-memory store has none
+type Migratable interface {
+	Migrator() (*Migrator, error)
+}
+
+// Apply the pending migrations of the store, if it has a versioned schema
+func MigrateUp(ctx context.Context, store Store) ([]Migration, error) {
+	migratable, ok := store.(Migratable)
+	if !ok {
+		return nil, nil
+	}
+
+	migrator, err := migratable.Migrator()
+	if err != nil {
+		return nil, err
+	}
+	return migrator.Up(ctx)
+}
+
+// Apply all pending migrations, in version order. Return the applied migrations
+func (migrator *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied := []Migration{}
+	err := migrator.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := migrator.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			if status.Applied {
+				continue
+			}
+			err := migrator.apply(ctx, conn, status.Migration.Up, migrator.dialect.insertVersion,
+				status.Version, status.Name, migrator.dialect.timeValue(time.Now()))
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", status.Version, status.Name, err)
+			}
+			applied = append(applied, status.Migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Revert the last steps applied migrations, in reverse version order. Return the reverted migrations
+func (migrator *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	reverted := []Migration{}
+	err := migrator.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := migrator.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+			status := statuses[i]
+			if !status.Applied {
+				continue
+			}
+			err := migrator.apply(ctx, conn, status.Migration.Down, migrator.dialect.deleteVersion, status.Version)
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", status.Version, status.Name, err)
+			}
+			reverted = append(reverted, status.Migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Get the state of every migration, in version order
+func (migrator *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := migrator.withLock(ctx, func(conn *sql.Conn) error {
+		var err error
+		statuses, err = migrator.status(ctx, conn)
+		return err
+	})
+	return statuses, err
+}
+
+// Helper method to run a function on a dedicated connection holding the migration lock, after making
+// sure the schema_migrations table exists
+func (migrator *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := migrator.conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := migrator.dialect.lock(ctx, conn); err != nil {
+		return fmt.Errorf("failed to acquire the migration lock: %w", err)
+	}
+
+	err = func() error {
+		if _, err := conn.ExecContext(ctx, migrator.dialect.createTable); err != nil {
+			return fmt.Errorf("failed to create the schema_migrations table: %w", err)
+		}
+		return fn(conn)
+	}()
+
+	// The lock must be released even if the context is canceled, since the connection goes back to the pool
+	if unlockErr := migrator.dialect.unlock(context.Background(), conn); unlockErr != nil && err == nil {
+		err = fmt.Errorf("failed to release the migration lock: %w", unlockErr)
+	}
+	return err
+}
+
+// Helper method to get the state of every migration, holding the migration lock
+func (migrator *Migrator) status(ctx context.Context, conn *sql.Conn) ([]MigrationStatus, error) {
+	rows, err := conn.QueryContext(ctx, migrator.dialect.selectApplied)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var timeApplied time.Time
+		if err := rows.Scan(&version, migrator.dialect.scanTime(&timeApplied)); err != nil {
+			return nil, err
+		}
+		applied[version] = timeApplied
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrator.migrations))
+	for i, migration := range migrator.migrations {
+		timeApplied, ok := applied[migration.Version]
+		statuses[i] = MigrationStatus{Migration: migration, Applied: ok, TimeApplied: timeApplied}
+	}
+	return statuses, nil
+}
+
+// Helper method to run a migration script and record it in one transaction
+func (migrator *Migrator) apply(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/danglnh07/URLShortener/db/migration"
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(fstest.MapFS{
+		"000002_add_column.up.sql":   {Data: []byte("ALTER TABLE t ADD COLUMN c INTEGER;")},
+		"000002_add_column.down.sql": {Data: []byte("ALTER TABLE t DROP COLUMN c;")},
+		"000001_init.up.sql":         {Data: []byte("CREATE TABLE t (id INTEGER);")},
+		"000001_init.down.sql":       {Data: []byte("DROP TABLE t;")},
+		"README.md":                  {Data: []byte("Not a migration")},
+	})
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	require.Equal(t, Migration{
+		Version: 1, Name: "init", Up: "CREATE TABLE t (id INTEGER);", Down: "DROP TABLE t;",
+	}, migrations[0])
+	require.Equal(t, int64(2), migrations[1].Version)
+
+	// Every migration must be reversible
+	_, err = LoadMigrations(fstest.MapFS{"000001_init.up.sql": {Data: []byte("CREATE TABLE t (id INTEGER);")}})
+	require.ErrorContains(t, err, "must have both an up and a down file")
+
+	// The embedded migrations of both databases are valid and have the same versions
+	postgres, err := LoadMigrations(migration.FS)
+	require.NoError(t, err)
+	sqlite, err := (&SQLiteStore{}).Migrator()
+	require.NoError(t, err)
+	require.Equal(t, len(postgres), len(sqlite.migrations))
+	for i := range postgres {
+		require.Equal(t, postgres[i].Version, sqlite.migrations[i].Version)
+		require.Equal(t, postgres[i].Name, sqlite.migrations[i].Name)
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "migrate.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	migrator, err := store.Migrator()
+	require.NoError(t, err)
+
+	// All migrations are pending, then applied once
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+	require.False(t, statuses[0].Applied)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, len(statuses))
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	statuses, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	require.True(t, statuses[0].Applied)
+	require.False(t, statuses[0].TimeApplied.IsZero())
+
+	_, err = store.CreateUser(ctx, db.CreateUserParams{Name: "alice"})
+	require.NoError(t, err)
+
+	// Reverting every migration drops the schema
+	reverted, err := migrator.Down(ctx, len(statuses))
+	require.NoError(t, err)
+	require.Len(t, reverted, len(statuses))
+	require.Equal(t, statuses[0].Version, reverted[len(reverted)-1].Version)
+
+	_, err = store.CreateUser(ctx, db.CreateUserParams{Name: "alice"})
+	require.Error(t, err)
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, len(statuses))
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/danglnh07/URLShortener/db/migration"
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+)
+
+// PostgresStore is the Store backed by PostgreSQL, using the queries generated by sqlc
+type PostgresStore struct {
+	*db.Queries
+	conn  *sql.DB
+	tx    *sql.Tx // nil outside of a transaction
+	depth int     // Number of nested transactions (savepoints) inside tx
+}
+
+// Constructor method for PostgresStore
+func NewPostgresStore(conn *sql.DB) *PostgresStore {
+	return &PostgresStore{Queries: db.New(conn), conn: conn}
+}
+
+// Get the underlying database connection
+func (store *PostgresStore) Conn() *sql.DB {
+	return store.conn
+}
+
+func (store *PostgresStore) ExecTx(ctx context.Context, fn func(tx Store) error) error {
+	if store.tx != nil {
+		nested := &PostgresStore{Queries: store.Queries, conn: store.conn, tx: store.tx, depth: store.depth + 1}
+		return execSavepoint(ctx, store.tx, fmt.Sprintf("nested_%d", nested.depth), func() error {
+			return fn(nested)
+		})
+	}
+
+	return execTx(ctx, store.conn, func(tx *sql.Tx) error {
+		return fn(&PostgresStore{Queries: store.Queries.WithTx(tx), conn: store.conn, tx: tx})
+	})
+}
+
+func (store *PostgresStore) Close() error {
+	return store.conn.Close()
+}
+
+// Arbitrary key of the PostgreSQL advisory lock held while migrating, shared by all instances
+const migrationLockID = 7301124512
+
+// Statements of the migrator for PostgreSQL. The advisory lock belongs to the session, so it is held as
+// long as the migrator keeps its connection
+var postgresMigrationDialect = migrationDialect{
+	createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    time_applied TIMESTAMPTZ NOT NULL DEFAULT now()
+)`,
+	selectApplied: `SELECT version, time_applied FROM schema_migrations ORDER BY version`,
+	insertVersion: `INSERT INTO schema_migrations(version, name, time_applied) VALUES ($1, $2, $3)`,
+	deleteVersion: `DELETE FROM schema_migrations WHERE version = $1`,
+	timeValue:     func(t time.Time) any { return t },
+	scanTime:      func(t *time.Time) any { return t },
+	lock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID)
+		return err
+	},
+	unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID)
+		return err
+	},
+}
+
+// Get the migrator of the embedded PostgreSQL migrations
+func (store *PostgresStore) Migrator() (*Migrator, error) {
+	migrations, err := LoadMigrations(migration.FS)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{conn: store.conn, migrations: migrations, dialect: postgresMigrationDialect}, nil
+}
@@ -0,0 +1,717 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+)
+
+// Migrations of the SQLite schema, the counterpart of the PostgreSQL migrations
+//
+//go:embed sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// Methods shared by sql.DB and sql.Tx, like the DBTX interface generated by sqlc
+type sqliteDB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SQLiteStore is a Store backed by an embedded SQLite database file, for single node deployments that do
+// not want to run PostgreSQL. Writes are serialized through a single connection
+type SQLiteStore struct {
+	conn  *sql.DB
+	db    sqliteDB
+	tx    *sql.Tx // nil outside of a transaction
+	depth int     // Number of nested transactions (savepoints) inside tx
+}
+
+// Open the SQLite database file, created if it does not exist. The schema is created by the migrations
+func OpenSQLiteStore(source string) (*SQLiteStore, error) {
+	separator := "?"
+	if strings.Contains(source, "?") {
+		separator = "&"
+	}
+	conn, err := sql.Open("sqlite", source+separator+"_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows one writer at a time. A single connection avoids "database is locked" errors, and is
+	// required for in-memory databases, which are private to their connection
+	conn.SetMaxOpenConns(1)
+
+	return &SQLiteStore{conn: conn, db: conn}, nil
+}
+
+func (store *SQLiteStore) ExecTx(ctx context.Context, fn func(tx Store) error) error {
+	if store.tx != nil {
+		nested := &SQLiteStore{conn: store.conn, db: store.tx, tx: store.tx, depth: store.depth + 1}
+		return execSavepoint(ctx, store.tx, fmt.Sprintf("nested_%d", nested.depth), func() error {
+			return fn(nested)
+		})
+	}
+
+	return execTx(ctx, store.conn, func(tx *sql.Tx) error {
+		return fn(&SQLiteStore{conn: store.conn, db: tx, tx: tx})
+	})
+}
+
+func (store *SQLiteStore) Close() error {
+	return store.conn.Close()
+}
+
+// Statements of the migrator for SQLite. There is no lock: the migrator holds the only connection of this
+// process, and other processes wait for the write transactions thanks to the busy timeout
+var sqliteMigrationDialect = migrationDialect{
+	createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    time_applied INTEGER NOT NULL
+)`,
+	selectApplied: `SELECT version, time_applied FROM schema_migrations ORDER BY version`,
+	insertVersion: `INSERT INTO schema_migrations(version, name, time_applied) VALUES (?, ?, ?)`,
+	deleteVersion: `DELETE FROM schema_migrations WHERE version = ?`,
+	timeValue:     func(t time.Time) any { return t.UnixMicro() },
+	scanTime:      func(t *time.Time) any { return microTime{t} },
+	lock:          func(ctx context.Context, conn *sql.Conn) error { return nil },
+	unlock:        func(ctx context.Context, conn *sql.Conn) error { return nil },
+}
+
+// Get the migrator of the embedded SQLite migrations
+func (store *SQLiteStore) Migrator() (*Migrator, error) {
+	dir, err := fs.Sub(sqliteMigrations, "sqlite")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{conn: store.conn, migrations: migrations, dialect: sqliteMigrationDialect}, nil
+}
+
+// Error message of SQLite when a unique constraint is violated, e.g. "UNIQUE constraint failed: url.alias"
+var sqliteUniqueError = regexp.MustCompile(`UNIQUE constraint failed: ([^.]+)\.([^ ,)]+)(,)?`)
+
+// Convert a SQLite unique constraint error into the error of PostgreSQL: a single column constraint is
+// named <table>_<column>_key, and a multi column constraint is the primary key <table>_pkey
+func sqliteError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	match := sqliteUniqueError.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	if match[3] != "" {
+		return uniqueViolation(match[1] + "_pkey")
+	}
+	return uniqueViolation(match[1] + "_" + match[2] + "_key")
+}
+
+// Scanner of a time stored as Unix microseconds
+type microTime struct {
+	t *time.Time
+}
+
+func (m microTime) Scan(src any) error {
+	micros, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into time", src)
+	}
+	*m.t = time.UnixMicro(micros).UTC()
+	return nil
+}
+
+// Scanner of a nullable time stored as Unix microseconds
+type nullMicroTime struct {
+	t *sql.NullTime
+}
+
+func (m nullMicroTime) Scan(src any) error {
+	if src == nil {
+		*m.t = sql.NullTime{}
+		return nil
+	}
+	m.t.Valid = true
+	return microTime{&m.t.Time}.Scan(src)
+}
+
+// Helper function to get the value stored for a nullable time
+func nullMicros(t sql.NullTime) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.UnixMicro()
+}
+
+// Common interface of sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// Helper function to collect all the rows of a query
+func queryAll[T any](rows *sql.Rows, err error, scan func(row scanner) (T, error)) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// URL queries
+
+const sqliteURLColumns = `id, original_url, alias, expires_at, max_clicks, fallback_url, owner_id,
+    time_created, time_deleted, redirect_type, password_hash, title, preview, interstitial_seconds, code`
+
+func scanURL(row scanner, extra ...any) (db.Url, error) {
+	var url db.Url
+	err := row.Scan(append([]any{
+		&url.ID,
+		&url.OriginalUrl,
+		&url.Alias,
+		nullMicroTime{&url.ExpiresAt},
+		&url.MaxClicks,
+		&url.FallbackUrl,
+		&url.OwnerID,
+		microTime{&url.TimeCreated},
+		nullMicroTime{&url.TimeDeleted},
+		&url.RedirectType,
+		&url.PasswordHash,
+		&url.Title,
+		&url.Preview,
+		&url.InterstitialSeconds,
+		&url.Code,
+	}, extra...)...)
+	return url, err
+}
+
+func (store *SQLiteStore) CreateURL(ctx context.Context, arg db.CreateURLParams) (db.Url, error) {
+	row := store.db.QueryRowContext(ctx, `
+INSERT INTO url(original_url, alias, expires_at, max_clicks, fallback_url, owner_id, redirect_type,
+    password_hash, title, preview, interstitial_seconds, code, time_created)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING `+sqliteURLColumns,
+		arg.OriginalUrl, arg.Alias, nullMicros(arg.ExpiresAt), arg.MaxClicks, arg.FallbackUrl, arg.OwnerID,
+		arg.RedirectType, arg.PasswordHash, arg.Title, arg.Preview, arg.InterstitialSeconds, arg.Code,
+		time.Now().UnixMicro(),
+	)
+	url, err := scanURL(row)
+	return url, sqliteError(err)
+}
+
+func (store *SQLiteStore) GetURL(ctx context.Context, id int64) (db.Url, error) {
+	return scanURL(store.db.QueryRowContext(ctx, `SELECT `+sqliteURLColumns+` FROM url WHERE id = ?`, id))
+}
+
+func (store *SQLiteStore) GetURLByAlias(ctx context.Context, alias sql.NullString) (db.Url, error) {
+	return scanURL(store.db.QueryRowContext(ctx, `SELECT `+sqliteURLColumns+` FROM url WHERE alias = ?`, alias))
+}
+
+func (store *SQLiteStore) GetURLByCode(ctx context.Context, code sql.NullString) (db.Url, error) {
+	return scanURL(store.db.QueryRowContext(ctx, `SELECT `+sqliteURLColumns+` FROM url WHERE code = ?`, code))
+}
+
+func (store *SQLiteStore) ListURLByOriginal(ctx context.Context, arg db.ListURLByOriginalParams) ([]db.Url, error) {
+	rows, err := store.db.QueryContext(ctx, `
+SELECT `+sqliteURLColumns+` FROM url
+WHERE original_url = ? AND owner_id IS ? AND time_deleted IS NULL
+ORDER BY id`,
+		arg.OriginalUrl, arg.OwnerID,
+	)
+	return queryAll(rows, err, func(row scanner) (db.Url, error) { return scanURL(row) })
+}
+
+const sqliteListURL = `
+SELECT ` + sqliteURLColumns + `,
+    (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id) AS total_visitors,
+    (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id AND NOT v.is_bot) AS human_visitors
+FROM url u
+WHERE u.time_deleted IS NULL
+AND (?1 IS NULL OR u.owner_id = ?1)`
+
+func (store *SQLiteStore) ListURL(ctx context.Context, arg db.ListURLParams) ([]db.ListURLRow, error) {
+	rows, err := store.db.QueryContext(ctx, sqliteListURL+`
+ORDER BY u.id
+LIMIT ?3 OFFSET ?2`,
+		arg.OwnerID, arg.Offset, arg.Limit,
+	)
+	return queryAll(rows, err, func(row scanner) (db.ListURLRow, error) {
+		var total, human int64
+		url, err := scanURL(row, &total, &human)
+		return db.ListURLRow{
+			ID:                  url.ID,
+			OriginalUrl:         url.OriginalUrl,
+			Alias:               url.Alias,
+			ExpiresAt:           url.ExpiresAt,
+			MaxClicks:           url.MaxClicks,
+			FallbackUrl:         url.FallbackUrl,
+			OwnerID:             url.OwnerID,
+			TimeCreated:         url.TimeCreated,
+			TimeDeleted:         url.TimeDeleted,
+			RedirectType:        url.RedirectType,
+			PasswordHash:        url.PasswordHash,
+			Title:               url.Title,
+			Preview:             url.Preview,
+			InterstitialSeconds: url.InterstitialSeconds,
+			Code:                url.Code,
+			TotalVisitors:       total,
+			HumanVisitors:       human,
+		}, err
+	})
+}
+
+func (store *SQLiteStore) ExportURL(ctx context.Context, arg db.ExportURLParams) ([]db.ExportURLRow, error) {
+	rows, err := store.db.QueryContext(ctx, sqliteListURL+`
+AND u.id > ?2
+ORDER BY u.id
+LIMIT ?3`,
+		arg.OwnerID, arg.AfterID, arg.Limit,
+	)
+	return queryAll(rows, err, func(row scanner) (db.ExportURLRow, error) {
+		var total, human int64
+		url, err := scanURL(row, &total, &human)
+		return db.ExportURLRow{
+			ID:                  url.ID,
+			OriginalUrl:         url.OriginalUrl,
+			Alias:               url.Alias,
+			ExpiresAt:           url.ExpiresAt,
+			MaxClicks:           url.MaxClicks,
+			FallbackUrl:         url.FallbackUrl,
+			OwnerID:             url.OwnerID,
+			TimeCreated:         url.TimeCreated,
+			TimeDeleted:         url.TimeDeleted,
+			RedirectType:        url.RedirectType,
+			PasswordHash:        url.PasswordHash,
+			Title:               url.Title,
+			Preview:             url.Preview,
+			InterstitialSeconds: url.InterstitialSeconds,
+			Code:                url.Code,
+			TotalVisitors:       total,
+			HumanVisitors:       human,
+		}, err
+	})
+}
+
+func (store *SQLiteStore) CountURL(ctx context.Context, ownerID sql.NullInt64) (int64, error) {
+	var count int64
+	err := store.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM url
+WHERE time_deleted IS NULL
+AND (?1 IS NULL OR owner_id = ?1)`,
+		ownerID,
+	).Scan(&count)
+	return count, err
+}
+
+func (store *SQLiteStore) CountURLWithoutCode(ctx context.Context) (int64, error) {
+	var count int64
+	err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM url WHERE code IS NULL`).Scan(&count)
+	return count, err
+}
+
+func (store *SQLiteStore) UpdateURL(ctx context.Context, arg db.UpdateURLParams) (db.Url, error) {
+	row := store.db.QueryRowContext(ctx, `
+UPDATE url
+SET original_url = ?, alias = ?, expires_at = ?, max_clicks = ?, fallback_url = ?, redirect_type = ?,
+    password_hash = ?, title = ?, preview = ?, interstitial_seconds = ?
+WHERE id = ? AND time_deleted IS NULL
+RETURNING `+sqliteURLColumns,
+		arg.OriginalUrl, arg.Alias, nullMicros(arg.ExpiresAt), arg.MaxClicks, arg.FallbackUrl, arg.RedirectType,
+		arg.PasswordHash, arg.Title, arg.Preview, arg.InterstitialSeconds, arg.ID,
+	)
+	url, err := scanURL(row)
+	return url, sqliteError(err)
+}
+
+func (store *SQLiteStore) SoftDeleteURL(ctx context.Context, id int64) (int64, error) {
+	result, err := store.db.ExecContext(ctx, `
+UPDATE url SET time_deleted = ?
+WHERE id = ? AND time_deleted IS NULL`,
+		time.Now().UnixMicro(), id,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (store *SQLiteStore) DeleteURL(ctx context.Context, id int64) error {
+	_, err := store.db.ExecContext(ctx, `DELETE FROM url WHERE id = ?`, id)
+	return err
+}
+
+// Visitor queries
+
+const sqliteVisitorColumns = `ip, url_id, time_visited, referrer, user_agent, accept_language, host,
+    query_string, country, region, city, asn, as_org, browser, browser_version, os, device, is_bot`
+
+func visitorArgs(arg db.CreateVisitorParams) []any {
+	return []any{
+		arg.Ip, arg.UrlID, arg.TimeVisited.UnixMicro(), arg.Referrer, arg.UserAgent, arg.AcceptLanguage,
+		arg.Host, arg.QueryString, arg.Country, arg.Region, arg.City, arg.Asn, arg.AsOrg, arg.Browser,
+		arg.BrowserVersion, arg.Os, arg.Device, arg.IsBot,
+	}
+}
+
+func scanVisitor(row scanner, extra ...any) (db.Visitor, error) {
+	var visitor db.Visitor
+	err := row.Scan(append([]any{
+		&visitor.Ip,
+		&visitor.UrlID,
+		microTime{&visitor.TimeVisited},
+		&visitor.Referrer,
+		&visitor.UserAgent,
+		&visitor.AcceptLanguage,
+		&visitor.Host,
+		&visitor.QueryString,
+		&visitor.Country,
+		&visitor.Region,
+		&visitor.City,
+		&visitor.Asn,
+		&visitor.AsOrg,
+		&visitor.Browser,
+		&visitor.BrowserVersion,
+		&visitor.Os,
+		&visitor.Device,
+		&visitor.IsBot,
+	}, extra...)...)
+	return visitor, err
+}
+
+func (store *SQLiteStore) CreateVisitor(ctx context.Context, arg db.CreateVisitorParams) (db.Visitor, error) {
+	row := store.db.QueryRowContext(ctx, `
+INSERT INTO visitor(`+sqliteVisitorColumns+`)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING `+sqliteVisitorColumns,
+		visitorArgs(arg)...,
+	)
+	visitor, err := scanVisitor(row)
+	return visitor, sqliteError(err)
+}
+
+func (store *SQLiteStore) CreateVisitors(ctx context.Context, arg db.CreateVisitorsParams) (int64, error) {
+	// Insert the batch in one transaction, so that it is written to disk at once. Like the PostgreSQL
+	// query, visitors already recorded or whose URL no longer exists are skipped
+	count := int64(0)
+	err := store.ExecTx(ctx, func(tx Store) error {
+		for _, visitor := range splitVisitors(arg) {
+			result, err := tx.(*SQLiteStore).db.ExecContext(ctx, `
+INSERT OR IGNORE INTO visitor(`+sqliteVisitorColumns+`)
+SELECT ?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15, ?16, ?17, ?18
+WHERE EXISTS (SELECT 1 FROM url WHERE id = ?2)`,
+				visitorArgs(visitor)...,
+			)
+			if err != nil {
+				return err
+			}
+			inserted, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			count += inserted
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (store *SQLiteStore) CountVisitorForUpdate(ctx context.Context, id int64) (int64, error) {
+	// There is no row lock in SQLite, the transaction holds the only connection instead
+	var count int64
+	err := store.db.QueryRowContext(ctx, `
+SELECT (SELECT COUNT(*) FROM visitor v WHERE v.url_id = u.id AND NOT v.is_bot) AS total_visitors
+FROM url u
+WHERE u.id = ?`,
+		id,
+	).Scan(&count)
+	return count, err
+}
+
+func (store *SQLiteStore) ListVisitor(ctx context.Context, arg db.ListVisitorParams) ([]db.ListVisitorRow, error) {
+	rows, err := store.db.QueryContext(ctx, `
+SELECT v.ip, v.url_id, v.time_visited, v.referrer, v.user_agent, v.accept_language, v.host, v.query_string,
+    v.country, v.region, v.city, v.asn, v.as_org, v.browser, v.browser_version, v.os, v.device, v.is_bot,
+    u.original_url, u.alias, u.code
+FROM visitor v
+JOIN url u ON u.id = v.url_id
+WHERE v.url_id = ?1
+AND (?2 IS NULL OR v.referrer LIKE ?2 ESCAPE '\')
+AND (?3 IS NULL OR v.user_agent LIKE ?3 ESCAPE '\')
+AND (?4 IS NULL OR v.accept_language LIKE ?4 ESCAPE '\')
+AND (?5 IS NULL OR v.host LIKE ?5 ESCAPE '\')
+AND (?6 IS NULL OR v.query_string LIKE ?6 ESCAPE '\')
+AND (?7 IS NULL OR v.country = upper(?7))
+AND (?8 IS NULL OR lower(v.browser) = lower(?8))
+AND (?9 IS NULL OR lower(v.os) = lower(?9))
+AND (?10 IS NULL OR v.device = lower(?10))
+AND (?11 IS NULL OR v.is_bot = ?11)
+AND (?12 IS NULL OR v.time_visited >= ?12)
+AND (?13 IS NULL OR v.time_visited < ?13)
+ORDER BY v.time_visited, v.ip
+LIMIT ?15 OFFSET ?14`,
+		arg.UrlID, arg.Referrer, arg.UserAgent, arg.AcceptLanguage, arg.Host, arg.QueryString, arg.Country,
+		arg.Browser, arg.Os, arg.Device, arg.IsBot, nullMicros(arg.FromTime), nullMicros(arg.ToTime),
+		arg.Offset, arg.Limit,
+	)
+	return queryAll(rows, err, func(row scanner) (db.ListVisitorRow, error) {
+		var originalURL string
+		var alias, code sql.NullString
+		visitor, err := scanVisitor(row, &originalURL, &alias, &code)
+		return db.ListVisitorRow{
+			Ip:             visitor.Ip,
+			TimeVisited:    visitor.TimeVisited,
+			UrlID:          visitor.UrlID,
+			Referrer:       visitor.Referrer,
+			UserAgent:      visitor.UserAgent,
+			AcceptLanguage: visitor.AcceptLanguage,
+			Host:           visitor.Host,
+			QueryString:    visitor.QueryString,
+			Country:        visitor.Country,
+			Region:         visitor.Region,
+			City:           visitor.City,
+			Asn:            visitor.Asn,
+			AsOrg:          visitor.AsOrg,
+			Browser:        visitor.Browser,
+			BrowserVersion: visitor.BrowserVersion,
+			Os:             visitor.Os,
+			Device:         visitor.Device,
+			IsBot:          visitor.IsBot,
+			OriginalUrl:    originalURL,
+			Alias:          alias,
+			Code:           code,
+		}, err
+	})
+}
+
+func (store *SQLiteStore) ExportVisitor(ctx context.Context, arg db.ExportVisitorParams) ([]db.ExportVisitorRow, error) {
+	rows, err := store.db.QueryContext(ctx, `
+SELECT `+sqliteVisitorColumns+` FROM visitor
+WHERE url_id = ?
+AND (time_visited, ip) > (?, ?)
+ORDER BY time_visited, ip
+LIMIT ?`,
+		arg.UrlID, arg.AfterTime.UnixMicro(), arg.AfterIp, arg.Limit,
+	)
+	return queryAll(rows, err, func(row scanner) (db.ExportVisitorRow, error) {
+		visitor, err := scanVisitor(row)
+		return db.ExportVisitorRow{
+			Ip:             visitor.Ip,
+			TimeVisited:    visitor.TimeVisited,
+			Referrer:       visitor.Referrer,
+			UserAgent:      visitor.UserAgent,
+			AcceptLanguage: visitor.AcceptLanguage,
+			Host:           visitor.Host,
+			QueryString:    visitor.QueryString,
+			Country:        visitor.Country,
+			Region:         visitor.Region,
+			City:           visitor.City,
+			Asn:            visitor.Asn,
+			AsOrg:          visitor.AsOrg,
+			Browser:        visitor.Browser,
+			BrowserVersion: visitor.BrowserVersion,
+			Os:             visitor.Os,
+			Device:         visitor.Device,
+			IsBot:          visitor.IsBot,
+		}, err
+	})
+}
+
+// Helper method to list the visits of an URL in [from, to), to compute the statistics in Go
+func (store *SQLiteStore) listVisits(ctx context.Context, urlID int64, from, to time.Time) ([]visit, error) {
+	rows, err := store.db.QueryContext(ctx, `
+SELECT ip, time_visited, is_bot, country, browser, os, device FROM visitor
+WHERE url_id = ? AND time_visited >= ? AND time_visited < ?`,
+		urlID, from.UnixMicro(), to.UnixMicro(),
+	)
+	return queryAll(rows, err, func(row scanner) (visit, error) {
+		var v visit
+		err := row.Scan(&v.ip, microTime{&v.time}, &v.isBot, &v.country, &v.browser, &v.os, &v.device)
+		return v, err
+	})
+}
+
+func (store *SQLiteStore) ListVisitorStats(ctx context.Context, arg db.ListVisitorStatsParams) ([]db.ListVisitorStatsRow, error) {
+	visits, err := store.listVisits(ctx, arg.UrlID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	return visitStats(visits, arg.Bucket), nil
+}
+
+func (store *SQLiteStore) ListVisitorBreakdown(
+	ctx context.Context, arg db.ListVisitorBreakdownParams,
+) ([]db.ListVisitorBreakdownRow, error) {
+	visits, err := store.listVisits(ctx, arg.UrlID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	return visitBreakdown(visits, arg.Dimension), nil
+}
+
+func (store *SQLiteStore) GetVisitorSummary(ctx context.Context, arg db.GetVisitorSummaryParams) (db.GetVisitorSummaryRow, error) {
+	visits, err := store.listVisits(ctx, arg.UrlID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return db.GetVisitorSummaryRow{}, err
+	}
+	return visitSummary(visits), nil
+}
+
+func (store *SQLiteStore) DeleteVisitor(ctx context.Context, arg db.DeleteVisitorParams) error {
+	_, err := store.db.ExecContext(ctx, `DELETE FROM visitor WHERE ip = ? AND url_id = ? AND time_visited = ?`,
+		arg.Ip, arg.UrlID, arg.TimeVisited.UnixMicro())
+	return err
+}
+
+// User queries
+
+func scanUser(row scanner) (db.User, error) {
+	var user db.User
+	err := row.Scan(&user.ID, &user.Name, &user.IsAdmin, microTime{&user.TimeCreated})
+	return user, err
+}
+
+func (store *SQLiteStore) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	row := store.db.QueryRowContext(ctx, `
+INSERT INTO "user"(name, is_admin, time_created)
+VALUES (?, ?, ?)
+RETURNING id, name, is_admin, time_created`,
+		arg.Name, arg.IsAdmin, time.Now().UnixMicro(),
+	)
+	user, err := scanUser(row)
+	return user, sqliteError(err)
+}
+
+func (store *SQLiteStore) GetUser(ctx context.Context, id int64) (db.User, error) {
+	return scanUser(store.db.QueryRowContext(ctx, `SELECT id, name, is_admin, time_created FROM "user" WHERE id = ?`, id))
+}
+
+func (store *SQLiteStore) ListUser(ctx context.Context, arg db.ListUserParams) ([]db.User, error) {
+	rows, err := store.db.QueryContext(ctx, `
+SELECT id, name, is_admin, time_created FROM "user"
+ORDER BY id
+LIMIT ? OFFSET ?`,
+		arg.Limit, arg.Offset,
+	)
+	return queryAll(rows, err, scanUser)
+}
+
+func (store *SQLiteStore) DeleteUser(ctx context.Context, id int64) error {
+	_, err := store.db.ExecContext(ctx, `DELETE FROM "user" WHERE id = ?`, id)
+	return err
+}
+
+// API key queries
+
+const sqliteAPIKeyColumns = `id, name, key_hash, prefix, user_id, time_created, time_revoked`
+
+func scanAPIKey(row scanner, extra ...any) (db.ApiKey, error) {
+	var apiKey db.ApiKey
+	err := row.Scan(append([]any{
+		&apiKey.ID,
+		&apiKey.Name,
+		&apiKey.KeyHash,
+		&apiKey.Prefix,
+		&apiKey.UserID,
+		microTime{&apiKey.TimeCreated},
+		nullMicroTime{&apiKey.TimeRevoked},
+	}, extra...)...)
+	return apiKey, err
+}
+
+func (store *SQLiteStore) CreateAPIKey(ctx context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+	row := store.db.QueryRowContext(ctx, `
+INSERT INTO api_key(name, key_hash, prefix, user_id, time_created)
+VALUES (?, ?, ?, ?, ?)
+RETURNING `+sqliteAPIKeyColumns,
+		arg.Name, arg.KeyHash, arg.Prefix, arg.UserID, time.Now().UnixMicro(),
+	)
+	apiKey, err := scanAPIKey(row)
+	return apiKey, sqliteError(err)
+}
+
+func (store *SQLiteStore) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (db.GetActiveAPIKeyByHashRow, error) {
+	row := store.db.QueryRowContext(ctx, `
+SELECT k.id, k.name, k.key_hash, k.prefix, k.user_id, k.time_created, k.time_revoked, u.name, u.is_admin
+FROM api_key k
+JOIN "user" u ON u.id = k.user_id
+WHERE k.key_hash = ? AND k.time_revoked IS NULL`,
+		keyHash,
+	)
+	var result db.GetActiveAPIKeyByHashRow
+	apiKey, err := scanAPIKey(row, &result.UserName, &result.IsAdmin)
+	result.ID = apiKey.ID
+	result.Name = apiKey.Name
+	result.KeyHash = apiKey.KeyHash
+	result.Prefix = apiKey.Prefix
+	result.UserID = apiKey.UserID
+	result.TimeCreated = apiKey.TimeCreated
+	result.TimeRevoked = apiKey.TimeRevoked
+	return result, err
+}
+
+func (store *SQLiteStore) ListAPIKey(ctx context.Context, arg db.ListAPIKeyParams) ([]db.ApiKey, error) {
+	rows, err := store.db.QueryContext(ctx, `
+SELECT `+sqliteAPIKeyColumns+` FROM api_key
+ORDER BY id
+LIMIT ? OFFSET ?`,
+		arg.Limit, arg.Offset,
+	)
+	return queryAll(rows, err, func(row scanner) (db.ApiKey, error) { return scanAPIKey(row) })
+}
+
+func (store *SQLiteStore) RevokeAPIKey(ctx context.Context, id int64) (int64, error) {
+	result, err := store.db.ExecContext(ctx, `
+UPDATE api_key SET time_revoked = ?
+WHERE id = ? AND time_revoked IS NULL`,
+		time.Now().UnixMicro(), id,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (store *SQLiteStore) DeleteAPIKey(ctx context.Context, id int64) error {
+	_, err := store.db.ExecContext(ctx, `DELETE FROM api_key WHERE id = ?`, id)
+	return err
+}
+
+// Setting queries
+
+func (store *SQLiteStore) GetSetting(ctx context.Context, name string) (string, error) {
+	var value string
+	err := store.db.QueryRowContext(ctx, `SELECT value FROM setting WHERE name = ?`, name).Scan(&value)
+	return value, err
+}
+
+func (store *SQLiteStore) SetSetting(ctx context.Context, arg db.SetSettingParams) error {
+	_, err := store.db.ExecContext(ctx, `
+INSERT INTO setting(name, value)
+VALUES (?, ?)
+ON CONFLICT (name) DO UPDATE SET value = excluded.value`,
+		arg.Name, arg.Value,
+	)
+	return err
+}
@@ -0,0 +1,19 @@
+-- Initial schema of the SQLite store, the counterpart of db/migration/000001_init.up.sql. Times are
+-- stored as Unix microseconds (the precision of PostgreSQL) and booleans as 0 or 1.
+--
+-- SQLite cannot change the constraints of a table without rebuilding it, and no SQLite database predates
+-- the migrations, so the tables are created with the constraints of the later migrations: original_url
+-- is not unique (000006) and the visitors are deleted along with their URL (000005)
+
+CREATE TABLE IF NOT EXISTS url (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    original_url TEXT NOT NULL,
+    time_created INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS visitor (
+    ip TEXT NOT NULL,
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    time_visited INTEGER NOT NULL,
+    PRIMARY KEY (ip, url_id, time_visited)
+);
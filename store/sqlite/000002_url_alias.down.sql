@@ -0,0 +1,2 @@
+DROP INDEX url_alias_key;
+ALTER TABLE url DROP COLUMN alias;
@@ -0,0 +1,4 @@
+-- Custom alias of the URL. SQLite cannot add a column with a unique constraint, the unique index is named
+-- after the constraint of PostgreSQL
+ALTER TABLE url ADD COLUMN alias TEXT;
+CREATE UNIQUE INDEX url_alias_key ON url(alias);
@@ -0,0 +1,4 @@
+DROP INDEX visitor_url_id_idx;
+ALTER TABLE url DROP COLUMN fallback_url;
+ALTER TABLE url DROP COLUMN max_clicks;
+ALTER TABLE url DROP COLUMN expires_at;
@@ -0,0 +1,6 @@
+-- Expiration of the URL by time and by number of clicks, see db/migration/000003_url_expiration.up.sql
+ALTER TABLE url ADD COLUMN expires_at INTEGER;
+ALTER TABLE url ADD COLUMN max_clicks INTEGER;
+ALTER TABLE url ADD COLUMN fallback_url TEXT;
+
+CREATE INDEX visitor_url_id_idx ON visitor(url_id);
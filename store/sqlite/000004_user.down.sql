@@ -0,0 +1,4 @@
+DROP INDEX url_owner_id_idx;
+ALTER TABLE url DROP COLUMN owner_id;
+DROP TABLE IF EXISTS api_key;
+DROP TABLE IF EXISTS "user";
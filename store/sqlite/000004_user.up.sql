@@ -0,0 +1,21 @@
+-- Users, their API keys and the owner of the URLs, see db/migration/000004_user.up.sql
+CREATE TABLE IF NOT EXISTS "user" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    is_admin INTEGER NOT NULL DEFAULT 0,
+    time_created INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_key (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    prefix TEXT NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+    time_created INTEGER NOT NULL,
+    time_revoked INTEGER
+);
+
+ALTER TABLE url ADD COLUMN owner_id INTEGER REFERENCES "user"(id);
+
+CREATE INDEX url_owner_id_idx ON url(owner_id);
@@ -0,0 +1,2 @@
+-- Soft delete of the URL. The visitors are already deleted along with their URL, see 000001_init.up.sql
+ALTER TABLE url ADD COLUMN time_deleted INTEGER;
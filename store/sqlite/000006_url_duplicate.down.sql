@@ -0,0 +1 @@
+DROP INDEX url_original_url_idx;
@@ -0,0 +1,3 @@
+-- Index for looking up URLs by their original URL. original_url is already not unique, see
+-- 000001_init.up.sql
+CREATE INDEX url_original_url_idx ON url(original_url);
@@ -0,0 +1,5 @@
+ALTER TABLE visitor DROP COLUMN query_string;
+ALTER TABLE visitor DROP COLUMN host;
+ALTER TABLE visitor DROP COLUMN accept_language;
+ALTER TABLE visitor DROP COLUMN user_agent;
+ALTER TABLE visitor DROP COLUMN referrer;
@@ -0,0 +1,6 @@
+-- Metadata of the visit request, see db/migration/000007_visitor_metadata.up.sql
+ALTER TABLE visitor ADD COLUMN referrer TEXT NOT NULL DEFAULT '';
+ALTER TABLE visitor ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE visitor ADD COLUMN accept_language TEXT NOT NULL DEFAULT '';
+ALTER TABLE visitor ADD COLUMN host TEXT NOT NULL DEFAULT '';
+ALTER TABLE visitor ADD COLUMN query_string TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+ALTER TABLE visitor DROP COLUMN as_org;
+ALTER TABLE visitor DROP COLUMN asn;
+ALTER TABLE visitor DROP COLUMN city;
+ALTER TABLE visitor DROP COLUMN region;
+ALTER TABLE visitor DROP COLUMN country;
@@ -0,0 +1,6 @@
+-- Location of the visitor, see db/migration/000008_visitor_geoip.up.sql
+ALTER TABLE visitor ADD COLUMN country TEXT NOT NULL DEFAULT '';
+ALTER TABLE visitor ADD COLUMN region TEXT NOT NULL DEFAULT '';
+ALTER TABLE visitor ADD COLUMN city TEXT NOT NULL DEFAULT '';
+ALTER TABLE visitor ADD COLUMN asn INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE visitor ADD COLUMN as_org TEXT NOT NULL DEFAULT '';
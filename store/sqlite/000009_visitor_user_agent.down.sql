@@ -0,0 +1,4 @@
+ALTER TABLE visitor DROP COLUMN device;
+ALTER TABLE visitor DROP COLUMN os;
+ALTER TABLE visitor DROP COLUMN browser_version;
+ALTER TABLE visitor DROP COLUMN browser;
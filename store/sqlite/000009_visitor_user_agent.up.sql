@@ -0,0 +1,5 @@
+-- Parsed user agent of the visitor, see db/migration/000009_visitor_user_agent.up.sql
+ALTER TABLE visitor ADD COLUMN browser TEXT NOT NULL DEFAULT '';
+ALTER TABLE visitor ADD COLUMN browser_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE visitor ADD COLUMN os TEXT NOT NULL DEFAULT '';
+ALTER TABLE visitor ADD COLUMN device TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE visitor DROP COLUMN is_bot;
@@ -0,0 +1,2 @@
+-- Visit from a crawler, link unfurler, HEAD or prefetch request
+ALTER TABLE visitor ADD COLUMN is_bot INTEGER NOT NULL DEFAULT 0;
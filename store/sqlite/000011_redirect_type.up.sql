@@ -0,0 +1,3 @@
+-- HTTP status used to redirect the visitors of an URL, NULL to use the default of the server
+ALTER TABLE url ADD COLUMN redirect_type INTEGER
+    CONSTRAINT url_redirect_type_check CHECK (redirect_type IN (301, 302, 307, 308));
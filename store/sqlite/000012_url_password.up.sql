@@ -0,0 +1,2 @@
+-- bcrypt hash of the password visitors must enter before being redirected, NULL if the URL is public
+ALTER TABLE url ADD COLUMN password_hash TEXT;
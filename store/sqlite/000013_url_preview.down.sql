@@ -0,0 +1,3 @@
+ALTER TABLE url DROP COLUMN interstitial_seconds;
+ALTER TABLE url DROP COLUMN preview;
+ALTER TABLE url DROP COLUMN title;
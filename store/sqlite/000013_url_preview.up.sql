@@ -0,0 +1,4 @@
+-- Title, preview page and interstitial page of an URL, see db/migration/000013_url_preview.up.sql
+ALTER TABLE url ADD COLUMN title TEXT;
+ALTER TABLE url ADD COLUMN preview INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE url ADD COLUMN interstitial_seconds INTEGER;
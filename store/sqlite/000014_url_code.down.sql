@@ -0,0 +1,2 @@
+DROP INDEX url_code_key;
+ALTER TABLE url DROP COLUMN code;
@@ -0,0 +1,4 @@
+-- Random short code of the URL, see db/migration/000014_url_code.up.sql. SQLite cannot add a column with
+-- a unique constraint, the unique index is named after the constraint of PostgreSQL
+ALTER TABLE url ADD COLUMN code TEXT;
+CREATE UNIQUE INDEX url_code_key ON url(code);
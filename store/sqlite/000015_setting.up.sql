@@ -0,0 +1,7 @@
+-- Settings of the service, see db/migration/000015_setting.up.sql
+CREATE TABLE IF NOT EXISTS setting (
+    name TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+INSERT INTO setting(name, value) VALUES ('code_scheme', 'sequential:1') ON CONFLICT (name) DO NOTHING;
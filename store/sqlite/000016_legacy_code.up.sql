@@ -0,0 +1,3 @@
+-- Last ID with an unprefixed code, see db/migration/000016_legacy_code.up.sql
+INSERT INTO setting(name, value)
+SELECT 'legacy_code_max_id', CAST(COALESCE(MAX(id), 0) AS TEXT) FROM url;
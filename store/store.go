@@ -0,0 +1,248 @@
+// Package store provides the persistence layer of the service. The Store interface covers the URL,
+// visitor, user and API key operations, and is implemented by PostgreSQL (the queries generated by
+// sqlc), an embedded SQLite database and a pure in-memory store
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/danglnh07/URLShortener/service"
+)
+
+// Supported values of DbDriver
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// Store is the persistence layer of the service. The query methods follow the sqlc generated Querier:
+// a record that does not exist is reported with sql.ErrNoRows, and a unique constraint violation with
+// an error mentioning the PostgreSQL constraint name (e.g. "url_alias_key"), whatever the backend
+type Store interface {
+	db.Querier
+
+	// Run the function in a transaction, committed if the function returns nil and rolled back otherwise.
+	// Calling ExecTx on the transaction store runs a nested transaction (savepoint), so that only the
+	// changes of the nested function are rolled back if it fails
+	ExecTx(ctx context.Context, fn func(tx Store) error) error
+
+	// Release the resources of the store
+	Close() error
+}
+
+// Open the store of the given driver. The source is the connection string for PostgreSQL and the
+// database file for SQLite, and is ignored by the in-memory store
+func Open(driver, source string) (Store, error) {
+	switch driver {
+	case DriverPostgres:
+		conn, err := sql.Open("postgres", source)
+		if err != nil {
+			return nil, err
+		}
+		return NewPostgresStore(conn), nil
+	case DriverSQLite:
+		store, err := OpenSQLiteStore(source)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case DriverMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q, must be one of %s, %s or %s",
+			driver, DriverPostgres, DriverSQLite, DriverMemory)
+	}
+}
+
+// Error reported when a unique constraint is violated, with the same message as PostgreSQL
+func uniqueViolation(constraint string) error {
+	return fmt.Errorf("duplicate key value violates unique constraint %q", constraint)
+}
+
+// Error reported when a foreign key constraint is violated, with the same message as PostgreSQL
+func foreignKeyViolation(table, constraint string) error {
+	return fmt.Errorf("insert or update on table %q violates foreign key constraint %q", table, constraint)
+}
+
+// Error reported when a check constraint is violated, with the same message as PostgreSQL
+func checkViolation(table, constraint string) error {
+	return fmt.Errorf("new row for relation %q violates check constraint %q", table, constraint)
+}
+
+// Helper function to run a function in a transaction of the connection
+func execTx(ctx context.Context, conn *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx error: %w, rollback error: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Helper function to run a function inside a savepoint of the transaction. If the function returns an
+// error, only the changes made since the savepoint are rolled back, and the transaction can continue
+func execSavepoint(ctx context.Context, tx *sql.Tx, name string, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("savepoint error: %w, rollback error: %v", err, rbErr)
+		}
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// A visit used to compute the statistics of the stores that cannot compute them in SQL
+type visit struct {
+	ip      string
+	time    time.Time
+	isBot   bool
+	country string
+	browser string
+	os      string
+	device  string
+}
+
+// Helper to count the clicks and unique IPs of a group of visits, with and without bots
+type visitCounter struct {
+	clicks, humanClicks int64
+	ips, humanIPs       map[string]bool
+}
+
+func newVisitCounter() *visitCounter {
+	return &visitCounter{ips: map[string]bool{}, humanIPs: map[string]bool{}}
+}
+
+func (counter *visitCounter) add(v visit) {
+	counter.clicks++
+	counter.ips[v.ip] = true
+	if !v.isBot {
+		counter.humanClicks++
+		counter.humanIPs[v.ip] = true
+	}
+}
+
+// Compute the result of ListVisitorStats from the visits in the time range
+func visitStats(visits []visit, interval string) []db.ListVisitorStatsRow {
+	counters := map[time.Time]*visitCounter{}
+	for _, v := range visits {
+		start := service.TruncateTime(v.time, interval)
+		if counters[start] == nil {
+			counters[start] = newVisitCounter()
+		}
+		counters[start].add(v)
+	}
+
+	rows := []db.ListVisitorStatsRow{}
+	for start, counter := range counters {
+		rows = append(rows, db.ListVisitorStatsRow{
+			BucketStart:         start,
+			Clicks:              counter.clicks,
+			UniqueVisitors:      int64(len(counter.ips)),
+			HumanClicks:         counter.humanClicks,
+			HumanUniqueVisitors: int64(len(counter.humanIPs)),
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].BucketStart.Before(rows[j].BucketStart) })
+	return rows
+}
+
+// Compute the result of ListVisitorBreakdown from the visits in the time range
+func visitBreakdown(visits []visit, dimension string) []db.ListVisitorBreakdownRow {
+	counters := map[string]*visitCounter{}
+	for _, v := range visits {
+		value := ""
+		switch dimension {
+		case "country":
+			value = v.country
+		case "browser":
+			value = v.browser
+		case "os":
+			value = v.os
+		case "device":
+			value = v.device
+		}
+		if counters[value] == nil {
+			counters[value] = newVisitCounter()
+		}
+		counters[value].add(v)
+	}
+
+	rows := []db.ListVisitorBreakdownRow{}
+	for value, counter := range counters {
+		rows = append(rows, db.ListVisitorBreakdownRow{
+			Value:               value,
+			Clicks:              counter.clicks,
+			UniqueVisitors:      int64(len(counter.ips)),
+			HumanClicks:         counter.humanClicks,
+			HumanUniqueVisitors: int64(len(counter.humanIPs)),
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Clicks != rows[j].Clicks {
+			return rows[i].Clicks > rows[j].Clicks
+		}
+		return rows[i].Value < rows[j].Value
+	})
+	return rows
+}
+
+// Compute the result of GetVisitorSummary from the visits in the time range
+func visitSummary(visits []visit) db.GetVisitorSummaryRow {
+	counter := newVisitCounter()
+	for _, v := range visits {
+		counter.add(v)
+	}
+	return db.GetVisitorSummaryRow{
+		Clicks:              counter.clicks,
+		UniqueVisitors:      int64(len(counter.ips)),
+		HumanClicks:         counter.humanClicks,
+		HumanUniqueVisitors: int64(len(counter.humanIPs)),
+	}
+}
+
+// Split the arguments of CreateVisitors into the visitors to insert
+func splitVisitors(arg db.CreateVisitorsParams) []db.CreateVisitorParams {
+	visitors := make([]db.CreateVisitorParams, len(arg.Ips))
+	for i := range arg.Ips {
+		visitors[i] = db.CreateVisitorParams{
+			Ip:             arg.Ips[i],
+			UrlID:          arg.UrlIds[i],
+			TimeVisited:    arg.TimesVisited[i],
+			Referrer:       arg.Referrers[i],
+			UserAgent:      arg.UserAgents[i],
+			AcceptLanguage: arg.AcceptLanguages[i],
+			Host:           arg.Hosts[i],
+			QueryString:    arg.QueryStrings[i],
+			Country:        arg.Countries[i],
+			Region:         arg.Regions[i],
+			City:           arg.Cities[i],
+			Asn:            arg.Asns[i],
+			AsOrg:          arg.AsOrgs[i],
+			Browser:        arg.Browsers[i],
+			BrowserVersion: arg.BrowserVersions[i],
+			Os:             arg.Oses[i],
+			Device:         arg.Devices[i],
+			IsBot:          arg.IsBots[i],
+		}
+	}
+	return visitors
+}
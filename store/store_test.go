@@ -0,0 +1,373 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	db "github.com/danglnh07/URLShortener/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+// Run the same checks against the stores that do not need an external database, so that they behave
+// like PostgreSQL
+func TestStore(t *testing.T) {
+	sqliteStore, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer sqliteStore.Close()
+	_, err = MigrateUp(context.Background(), sqliteStore)
+	require.NoError(t, err)
+
+	for name, store := range map[string]Store{
+		DriverMemory: NewMemoryStore(),
+		DriverSQLite: sqliteStore,
+	} {
+		t.Run(name, func(t *testing.T) {
+			testURL(t, store)
+			testVisitor(t, store)
+			testUser(t, store)
+			testSetting(t, store)
+			testTx(t, store)
+		})
+	}
+}
+
+func testURL(t *testing.T, store Store) {
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+
+	url, err := store.CreateURL(ctx, db.CreateURLParams{
+		OriginalUrl:         "https://example.com",
+		Alias:               sql.NullString{String: "example", Valid: true},
+		ExpiresAt:           sql.NullTime{Time: expires, Valid: true},
+		MaxClicks:           sql.NullInt32{Int32: 10, Valid: true},
+		RedirectType:        sql.NullInt32{Int32: 302, Valid: true},
+		PasswordHash:        sql.NullString{String: "hash", Valid: true},
+		Title:               sql.NullString{String: "Example", Valid: true},
+		Preview:             true,
+		InterstitialSeconds: sql.NullInt32{Int32: 5, Valid: true},
+		Code:                sql.NullString{String: "Xy12abCD", Valid: true},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, url.ID)
+	require.Equal(t, int32(302), url.RedirectType.Int32)
+	require.Equal(t, "hash", url.PasswordHash.String)
+	require.Equal(t, "Example", url.Title.String)
+	require.True(t, url.Preview)
+	require.Equal(t, int32(5), url.InterstitialSeconds.Int32)
+	require.True(t, url.ExpiresAt.Time.Equal(expires.Truncate(time.Microsecond)))
+	require.WithinDuration(t, time.Now(), url.TimeCreated, time.Second)
+
+	// Unique alias
+	_, err = store.CreateURL(ctx, db.CreateURLParams{
+		OriginalUrl: "https://example.org",
+		Alias:       sql.NullString{String: "example", Valid: true},
+	})
+	require.ErrorContains(t, err, "url_alias_key")
+
+	// Unique random code
+	_, err = store.CreateURL(ctx, db.CreateURLParams{
+		OriginalUrl: "https://example.org",
+		Code:        sql.NullString{String: "Xy12abCD", Valid: true},
+	})
+	require.ErrorContains(t, err, "url_code_key")
+
+	// Only redirect statuses are allowed
+	_, err = store.CreateURL(ctx, db.CreateURLParams{
+		OriginalUrl:  "https://example.org",
+		RedirectType: sql.NullInt32{Int32: 200, Valid: true},
+	})
+	require.ErrorContains(t, err, "url_redirect_type_check")
+
+	fetched, err := store.GetURLByAlias(ctx, sql.NullString{String: "example", Valid: true})
+	require.NoError(t, err)
+	require.Equal(t, url.ID, fetched.ID)
+
+	fetched, err = store.GetURLByCode(ctx, sql.NullString{String: "Xy12abCD", Valid: true})
+	require.NoError(t, err)
+	require.Equal(t, url.ID, fetched.ID)
+
+	_, err = store.GetURL(ctx, url.ID+1000)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Update and soft delete
+	updated, err := store.UpdateURL(ctx, db.UpdateURLParams{ID: url.ID, OriginalUrl: "https://example.net"})
+	require.NoError(t, err)
+	require.Equal(t, "https://example.net", updated.OriginalUrl)
+	require.False(t, updated.Alias.Valid)
+	require.False(t, updated.RedirectType.Valid)
+	require.False(t, updated.PasswordHash.Valid)
+	require.False(t, updated.Title.Valid)
+	require.False(t, updated.Preview)
+	require.False(t, updated.InterstitialSeconds.Valid)
+
+	count, err := store.CountURL(ctx, sql.NullInt64{})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	// The only URL has a stored code
+	count, err = store.CountURLWithoutCode(ctx)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	rows, err := store.SoftDeleteURL(ctx, url.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	rows, err = store.SoftDeleteURL(ctx, url.ID)
+	require.NoError(t, err)
+	require.Zero(t, rows)
+
+	_, err = store.UpdateURL(ctx, db.UpdateURLParams{ID: url.ID, OriginalUrl: "https://example.com"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	list, err := store.ListURL(ctx, db.ListURLParams{Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	// Hard delete only removes the given URL, not the other URLs with the same original URL
+	other, err := store.CreateURL(ctx, db.CreateURLParams{OriginalUrl: "https://example.net"})
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteURL(ctx, url.ID))
+	_, err = store.GetURL(ctx, url.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = store.GetURL(ctx, other.ID)
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteURL(ctx, other.ID))
+}
+
+func testVisitor(t *testing.T, store Store) {
+	ctx := context.Background()
+	url, err := store.CreateURL(ctx, db.CreateURLParams{OriginalUrl: "https://visitor.example.com"})
+	require.NoError(t, err)
+	defer store.DeleteURL(ctx, url.ID)
+
+	now := time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC)
+	visitor := db.CreateVisitorParams{
+		Ip:          "10.0.0.1",
+		UrlID:       url.ID,
+		TimeVisited: now,
+		UserAgent:   "Mozilla/5.0 Firefox/131.0",
+		Country:     "VN",
+		Browser:     "Firefox",
+		Device:      "desktop",
+	}
+	_, err = store.CreateVisitor(ctx, visitor)
+	require.NoError(t, err)
+
+	// Same IP, URL and time is a duplicate
+	_, err = store.CreateVisitor(ctx, visitor)
+	require.ErrorContains(t, err, "visitor_pkey")
+
+	// Batch insert skips duplicates and unknown URLs
+	inserted, err := store.CreateVisitors(ctx, db.CreateVisitorsParams{
+		Ips:             []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+		UrlIds:          []int64{url.ID, url.ID, url.ID, url.ID + 1000},
+		TimesVisited:    []time.Time{now, now.Add(time.Hour), now.Add(2 * time.Hour), now},
+		Referrers:       []string{"", "", "", ""},
+		UserAgents:      []string{"", "Slackbot", "curl/8.0", ""},
+		AcceptLanguages: []string{"", "", "", ""},
+		Hosts:           []string{"", "", "", ""},
+		QueryStrings:    []string{"", "", "", ""},
+		Countries:       []string{"", "US", "VN", ""},
+		Regions:         []string{"", "", "", ""},
+		Cities:          []string{"", "", "", ""},
+		Asns:            []int64{0, 0, 0, 0},
+		AsOrgs:          []string{"", "", "", ""},
+		Browsers:        []string{"", "Slackbot", "curl", ""},
+		BrowserVersions: []string{"", "", "", ""},
+		Oses:            []string{"", "", "", ""},
+		Devices:         []string{"", "bot", "bot", ""},
+		IsBots:          []bool{false, true, true, false},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), inserted)
+
+	count, err := store.CountVisitorForUpdate(ctx, url.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	// Filters
+	visitors, err := store.ListVisitor(ctx, db.ListVisitorParams{
+		UrlID:     url.ID,
+		UserAgent: sql.NullString{String: "%FIREFOX%", Valid: true},
+		Country:   sql.NullString{String: "vn", Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, visitors, 1)
+	require.Equal(t, "10.0.0.1", visitors[0].Ip)
+	require.True(t, visitors[0].TimeVisited.Equal(now))
+	require.Equal(t, url.OriginalUrl, visitors[0].OriginalUrl)
+
+	visitors, err = store.ListVisitor(ctx, db.ListVisitorParams{
+		UrlID:  url.ID,
+		IsBot:  sql.NullBool{Bool: true, Valid: true},
+		Offset: 1,
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, visitors, 1)
+	require.Equal(t, "10.0.0.3", visitors[0].Ip)
+
+	exported, err := store.ExportVisitor(ctx, db.ExportVisitorParams{
+		UrlID:     url.ID,
+		AfterTime: now,
+		AfterIp:   "10.0.0.1",
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, exported, 2)
+	require.Equal(t, "10.0.0.2", exported[0].Ip)
+
+	// Statistics
+	stats, err := store.ListVisitorStats(ctx, db.ListVisitorStatsParams{
+		Bucket:   "hour",
+		UrlID:    url.ID,
+		FromTime: now.Add(-time.Hour),
+		ToTime:   now.Add(2 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	require.True(t, stats[0].BucketStart.Equal(now.Truncate(time.Hour)))
+	require.Equal(t, db.ListVisitorStatsRow{
+		BucketStart: stats[1].BucketStart, Clicks: 1, UniqueVisitors: 1,
+	}, stats[1])
+
+	breakdown, err := store.ListVisitorBreakdown(ctx, db.ListVisitorBreakdownParams{
+		Dimension: "country",
+		UrlID:     url.ID,
+		FromTime:  now,
+		ToTime:    now.Add(3 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []db.ListVisitorBreakdownRow{
+		{Value: "VN", Clicks: 2, UniqueVisitors: 2, HumanClicks: 1, HumanUniqueVisitors: 1},
+		{Value: "US", Clicks: 1, UniqueVisitors: 1},
+	}, breakdown)
+
+	summary, err := store.GetVisitorSummary(ctx, db.GetVisitorSummaryParams{
+		UrlID:    url.ID,
+		FromTime: now,
+		ToTime:   now.Add(3 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, db.GetVisitorSummaryRow{
+		Clicks: 3, UniqueVisitors: 3, HumanClicks: 1, HumanUniqueVisitors: 1,
+	}, summary)
+
+	urls, err := store.ListURL(ctx, db.ListURLParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, int64(3), urls[0].TotalVisitors)
+	require.Equal(t, int64(1), urls[0].HumanVisitors)
+
+	// Visitors are deleted with their URL
+	require.NoError(t, store.DeleteVisitor(ctx, db.DeleteVisitorParams{
+		Ip: "10.0.0.1", UrlID: url.ID, TimeVisited: now,
+	}))
+	require.NoError(t, store.DeleteURL(ctx, url.ID))
+	visitors, err = store.ListVisitor(ctx, db.ListVisitorParams{UrlID: url.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, visitors)
+}
+
+func testUser(t *testing.T, store Store) {
+	ctx := context.Background()
+	user, err := store.CreateUser(ctx, db.CreateUserParams{Name: "alice"})
+	require.NoError(t, err)
+
+	_, err = store.CreateUser(ctx, db.CreateUserParams{Name: "alice"})
+	require.ErrorContains(t, err, "user_name_key")
+
+	apiKey, err := store.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		Name: "ci", KeyHash: "hash", Prefix: "us_abc", UserID: user.ID,
+	})
+	require.NoError(t, err)
+
+	_, err = store.CreateAPIKey(ctx, db.CreateAPIKeyParams{Name: "ci", KeyHash: "hash", UserID: user.ID})
+	require.ErrorContains(t, err, "api_key_key_hash_key")
+
+	active, err := store.GetActiveAPIKeyByHash(ctx, "hash")
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, active.ID)
+	require.Equal(t, "alice", active.UserName)
+
+	rows, err := store.RevokeAPIKey(ctx, apiKey.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+	_, err = store.GetActiveAPIKeyByHash(ctx, "hash")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// API keys are deleted with their user
+	require.NoError(t, store.DeleteUser(ctx, user.ID))
+	keys, err := store.ListAPIKey(ctx, db.ListAPIKeyParams{Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, keys)
+}
+
+func testSetting(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	_, err := store.GetSetting(ctx, "test")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Setting a value again replaces it
+	for _, value := range []string{"first", "second"} {
+		err = store.SetSetting(ctx, db.SetSettingParams{Name: "test", Value: value})
+		require.NoError(t, err)
+
+		stored, err := store.GetSetting(ctx, "test")
+		require.NoError(t, err)
+		require.Equal(t, value, stored)
+	}
+
+	// A rolled back change is undone
+	failure := errors.New("failure")
+	err = store.ExecTx(ctx, func(tx Store) error {
+		require.NoError(t, tx.SetSetting(ctx, db.SetSettingParams{Name: "test", Value: "rolled back"}))
+		return failure
+	})
+	require.ErrorIs(t, err, failure)
+
+	stored, err := store.GetSetting(ctx, "test")
+	require.NoError(t, err)
+	require.Equal(t, "second", stored)
+}
+
+func testTx(t *testing.T, store Store) {
+	ctx := context.Background()
+	failure := errors.New("failure")
+
+	// A failed nested transaction only rolls back its own changes
+	err := store.ExecTx(ctx, func(tx Store) error {
+		_, err := tx.CreateUser(ctx, db.CreateUserParams{Name: "kept"})
+		require.NoError(t, err)
+
+		err = tx.ExecTx(ctx, func(nested Store) error {
+			_, err := nested.CreateUser(ctx, db.CreateUserParams{Name: "nested"})
+			require.NoError(t, err)
+			return failure
+		})
+		require.ErrorIs(t, err, failure)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A failed transaction rolls back everything
+	err = store.ExecTx(ctx, func(tx Store) error {
+		_, err := tx.CreateUser(ctx, db.CreateUserParams{Name: "rolled back"})
+		require.NoError(t, err)
+		return failure
+	})
+	require.ErrorIs(t, err, failure)
+
+	users, err := store.ListUser(ctx, db.ListUserParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, "kept", users[0].Name)
+	require.NoError(t, store.DeleteUser(ctx, users[0].ID))
+}